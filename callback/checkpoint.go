@@ -20,13 +20,42 @@ type Checkpoint struct {
 	Compare    CheckpointCompare
 	SaveDir    string
 
-	bestValue float64
+	bestValue    float64
+	hasBestValue bool
 }
 
 func (c *Checkpoint) GetSaveDir() string {
 	return c.SaveDir
 }
 
+func (c *Checkpoint) getMetricName() string {
+	if c.Loss {
+		return "loss"
+	}
+	return strings.ToLower(c.MetricName)
+}
+
+func (c *Checkpoint) GetBestMetrics() map[string]float64 {
+	if !c.hasBestValue {
+		return map[string]float64{}
+	}
+	return map[string]float64{
+		c.getMetricName(): c.bestValue,
+	}
+}
+
+func (c *Checkpoint) SetBestMetrics(bestMetrics map[string]float64) {
+	bestValue, ok := bestMetrics[c.getMetricName()]
+	if ok {
+		c.bestValue = bestValue
+		c.hasBestValue = true
+	}
+}
+
+func (c *Checkpoint) ActsPerBatch() bool {
+	return c.OnMode == ModeTrain && (c.OnEvent == EventStart || c.OnEvent == EventDuring)
+}
+
 func (c *Checkpoint) Init() error {
 	if c.OnEvent == "" {
 		return fmt.Errorf("no OnEvent set for callback")
@@ -76,6 +105,12 @@ func (c *Checkpoint) Call(event Event, mode Mode, epoch int, batch int, logs []L
 		}
 	}
 
+	if !c.hasBestValue {
+		c.bestValue = metricValue
+		c.hasBestValue = true
+		return []Action{ActionSave}, nil
+	}
+
 	if c.Compare == CheckpointCompareMin {
 		if metricValue < c.bestValue {
 			c.bestValue = metricValue
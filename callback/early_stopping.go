@@ -38,6 +38,10 @@ func (c *EarlyStoppingOnMetric) Init() error {
 	return nil
 }
 
+func (c *EarlyStoppingOnMetric) ActsPerBatch() bool {
+	return c.OnMode == ModeTrain && (c.OnEvent == EventStart || c.OnEvent == EventDuring)
+}
+
 func (c *EarlyStoppingOnMetric) Call(event Event, mode Mode, epoch int, batch int, logs []Log) ([]Action, error) {
 	if event != c.OnEvent || mode != c.OnMode {
 		return []Action{ActionNop}, nil
@@ -15,6 +15,8 @@ var (
 	ActionNop   Action = "nop"
 	ActionSave  Action = "save"
 	ActionHalt  Action = "halt"
+	// ActionSetLearningRate sets the learning rate of the model to the value returned by HasLearningRate
+	ActionSetLearningRate Action = "set_learning_rate"
 )
 
 type Log struct {
@@ -28,6 +30,24 @@ type Callback interface {
 	Call(event Event, mode Mode, epoch int, batch int, logs []Log) ([]Action, error)
 }
 
+// ActsPerBatch is implemented by callbacks which report whether they may save, set the learning rate or halt after a
+// training batch, so training waits for them before running the next batch. Training always waits for callbacks which
+// do not implement it
+type ActsPerBatch interface {
+	ActsPerBatch() bool
+}
+
 type HasSaveDir interface {
 	GetSaveDir() string
 }
+
+type HasLearningRate interface {
+	GetLearningRate() float32
+}
+
+// HasBestMetrics is implemented by callbacks which track the best value of a metric, so it can be restored when
+// training is resumed from a checkpoint
+type HasBestMetrics interface {
+	GetBestMetrics() map[string]float64
+	SetBestMetrics(bestMetrics map[string]float64)
+}
@@ -17,6 +17,10 @@ var (
 	LoggerVerbose      = "verbose"
 	LoggerTotalBatches = "totalBatches"
 	LoggerPrefetched   = "prefetched"
+	// LoggerDatasetStall and LoggerCallbackStall are the seconds the model spent waiting for the dataset and for the
+	// metrics and callbacks of the previous batch during the current epoch
+	LoggerDatasetStall  = "datasetStall"
+	LoggerCallbackStall = "callbackStall"
 )
 
 type Logger struct {
@@ -40,6 +44,10 @@ func (l *Logger) Init() error {
 	return nil
 }
 
+func (l *Logger) ActsPerBatch() bool {
+	return false
+}
+
 func (l *Logger) Call(event Event, mode Mode, epoch int, batch int, logs []Log) ([]Action, error) {
 	l.modeStartsLock.Lock()
 	defer l.modeStartsLock.Unlock()
@@ -66,12 +74,17 @@ func (l *Logger) Call(event Event, mode Mode, epoch int, batch int, logs []Log)
 	var metricValues []interface{}
 	prefetched := -1
 	verbose := false
+	datasetStall, callbackStall := float64(0), float64(0)
 
 	for _, log := range logs {
 		if log.Name == LoggerTotalBatches {
 			totalBatches = int(log.Value)
 		} else if log.Name == LoggerPrefetched {
 			prefetched = int(log.Value)
+		} else if log.Name == LoggerDatasetStall {
+			datasetStall = log.Value
+		} else if log.Name == LoggerCallbackStall {
+			callbackStall = log.Value
 		} else if log.Name == LoggerVerbose {
 			if log.Value != -1 {
 				verbose = true
@@ -125,7 +138,7 @@ func (l *Logger) Call(event Event, mode Mode, epoch int, batch int, logs []Log)
 		if now > l.lastPrint {
 			l.lastPrint = now
 			log := fmt.Sprintf(
-				"\r%s : logger.go : %s %d %d/%d (%ds/%ds) %s | Prefetched %d",
+				"\r%s : logger.go : %s %d %d/%d (%ds/%ds) %s | Prefetched %d | Stalled dataset %.1fs callbacks %.1fs",
 				time.Now().Format("2006-01-02 15:04:05.000"),
 				logType,
 				epoch,
@@ -138,6 +151,8 @@ func (l *Logger) Call(event Event, mode Mode, epoch int, batch int, logs []Log)
 					metricValues...,
 				),
 				prefetched,
+				datasetStall,
+				callbackStall,
 			)
 			fmt.Print(log)
 			if l.Progress {
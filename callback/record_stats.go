@@ -32,6 +32,10 @@ func (r *RecordStats) Init() error {
 	return nil
 }
 
+func (r *RecordStats) ActsPerBatch() bool {
+	return false
+}
+
 func (r *RecordStats) Call(event Event, mode Mode, epoch int, batch int, logs []Log) ([]Action, error) {
 	if event == r.OnEvent && mode == r.OnMode {
 		if epoch == 1 {
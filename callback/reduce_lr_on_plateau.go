@@ -0,0 +1,125 @@
+package callback
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ReduceLROnPlateau multiplies the learning rate by Factor when MetricName has not improved by at least MinDelta for
+// Patience calls. It reads the current learning rate from the "lr" log Fit records
+type ReduceLROnPlateau struct {
+	OnEvent         Event
+	OnMode          Mode
+	MetricName      string
+	Mode            EarlyStoppingOnMetricMode
+	Factor          float64
+	Patience        int
+	MinDelta        float64
+	Cooldown        int
+	MinLearningRate float64
+
+	best           float64
+	wait           int
+	cooldownWait   int
+	learningRate   float32
+	hasInitialised bool
+}
+
+func (c *ReduceLROnPlateau) Init() error {
+	if c.OnEvent == "" {
+		return fmt.Errorf("no OnEvent set for callback")
+	}
+	if c.OnMode == "" {
+		return fmt.Errorf("no OnMode set for callback")
+	}
+	if c.Mode == "" {
+		return fmt.Errorf("no Mode set for callback")
+	}
+	if c.MetricName == "" {
+		return fmt.Errorf("no MetricName set for callback")
+	}
+	if c.Factor <= 0 || c.Factor >= 1 {
+		return fmt.Errorf("factor must be between 0 and 1 for reduce lr on plateau, got: %f", c.Factor)
+	}
+
+	c.wait = 0
+	c.cooldownWait = 0
+	c.hasInitialised = false
+
+	return nil
+}
+
+func (c *ReduceLROnPlateau) ActsPerBatch() bool {
+	return c.OnMode == ModeTrain && (c.OnEvent == EventStart || c.OnEvent == EventDuring)
+}
+
+func (c *ReduceLROnPlateau) GetLearningRate() float32 {
+	return c.learningRate
+}
+
+func (c *ReduceLROnPlateau) Call(event Event, mode Mode, epoch int, batch int, logs []Log) ([]Action, error) {
+	if event != c.OnEvent || mode != c.OnMode {
+		return []Action{ActionNop}, nil
+	}
+
+	var metricValue, learningRate float64
+	foundMetric, foundLearningRate := false, false
+	for _, log := range logs {
+		if strings.ToLower(log.Name) == strings.ToLower(c.MetricName) {
+			metricValue = log.Value
+			foundMetric = true
+		}
+		if log.Name == "lr" {
+			learningRate = log.Value
+			foundLearningRate = true
+		}
+	}
+	if !foundMetric {
+		return []Action{ActionNop}, fmt.Errorf("metric %s does not exist for the model", c.MetricName)
+	}
+	if !foundLearningRate {
+		return []Action{ActionNop}, fmt.Errorf("the learning rate was not logged by the model")
+	}
+
+	improved := false
+	if !c.hasInitialised {
+		improved = true
+		c.hasInitialised = true
+	} else if c.Mode == Min {
+		improved = metricValue < c.best-c.MinDelta
+	} else if c.Mode == Max {
+		improved = metricValue > c.best+c.MinDelta
+	}
+
+	if c.cooldownWait > 0 {
+		c.cooldownWait--
+		c.wait = 0
+	}
+
+	if improved {
+		c.best = metricValue
+		c.wait = 0
+		return []Action{ActionNop}, nil
+	}
+
+	if c.cooldownWait > 0 {
+		return []Action{ActionNop}, nil
+	}
+
+	c.wait++
+	if c.wait < c.Patience {
+		return []Action{ActionNop}, nil
+	}
+
+	c.wait = 0
+	c.cooldownWait = c.Cooldown
+
+	newLearningRate := math.Max(learningRate*c.Factor, c.MinLearningRate)
+	if newLearningRate >= learningRate {
+		return []Action{ActionNop}, nil
+	}
+	c.learningRate = float32(newLearningRate)
+
+	return []Action{ActionSetLearningRate}, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cberrors/iowriterprovider"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/jobs"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "worker":
+		runWorker()
+	case "train":
+		runTrain()
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: tfkg worker [flags]")
+	fmt.Println("       tfkg train [flags]")
+	os.Exit(2)
+}
+
+func runWorker() {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	jobsDir := flags.String("jobs-dir", "/go/src/tfkg/job-queue", "dir of the job queue shared with the web server")
+	logDir := flags.String("log-dir", "/go/src/tfkg/logs", "dir the logs of each job are written to")
+	name := flags.String("name", "", "name of the worker, defaults to <hostname>-<pid>")
+	dir := flags.String("dir", "", "dir the jobs are trained in, relative paths in their configs are read from it")
+	pollInterval := flags.Duration("poll-interval", 5*time.Second, "how often to check the queue when it is empty")
+	_ = flags.Parse(os.Args[2:])
+
+	logger, e := cblog.NewLogger(cblog.LoggerConfig{
+		LogLevel:           cblog.DebugLevel,
+		Format:             "%{time:2006-01-02 15:04:05.000} : %{file}:%{line} : %{message}",
+		LogToStdOut:        true,
+		SetAsDefaultLogger: true,
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	errorHandler := cberrors.NewErrorContainer(iowriterprovider.New(logger))
+
+	queue, e := jobs.NewQueue(*jobsDir)
+	if e != nil {
+		errorHandler.Error(e)
+		os.Exit(1)
+	}
+
+	worker, e := jobs.NewWorker(errorHandler, logger, queue, jobs.WorkerConfig{
+		Name:         *name,
+		LogDir:       *logDir,
+		PollInterval: *pollInterval,
+		Dir:          *dir,
+	})
+	if e != nil {
+		os.Exit(1)
+	}
+
+	// Stop the running job and exit on ctrl+c or docker stop
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	e = worker.Run(ctx)
+	if e != nil {
+		errorHandler.Error(e)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cberrors/iowriterprovider"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/jobs"
+	"github.com/codingbeard/tfkg/model"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// runTrain trains the model of a config file. Run by a worker the model config is read from the job and everything is
+// saved to the log dir of the job
+func runTrain() {
+	flags := flag.NewFlagSet("train", flag.ExitOnError)
+	configPath := flags.String("config", "", "a .yaml, .yml or .json model config, defaults to the config of the job")
+	logDir := flags.String("log-dir", "/go/src/tfkg/logs", "dir the <name>-<timestamp> save dir is created in when not run by a worker")
+	_ = flags.Parse(os.Args[2:])
+
+	environment, isJob, e := jobs.CurrentEnvironment()
+	if e != nil {
+		panic(e)
+	}
+
+	var config model.Config
+	if *configPath != "" {
+		config, e = model.LoadConfig(*configPath)
+	} else if isJob && len(environment.Config) > 0 {
+		config, e = model.ParseJsonConfig(environment.Config)
+	} else {
+		e = fmt.Errorf("no -config set and not run by a worker with a job config")
+	}
+	if e != nil {
+		fmt.Println(e.Error())
+		os.Exit(1)
+	}
+	if config.Name == "" {
+		config.Name = "model"
+	}
+
+	saveDir := environment.LogDir
+	if !isJob {
+		saveDir = filepath.Join(*logDir, fmt.Sprintf("%s-%d", config.Name, time.Now().Unix()))
+	}
+	e = os.MkdirAll(saveDir, os.ModePerm)
+	if e != nil {
+		panic(e)
+	}
+
+	// The worker already writes stdout to training.log in the save dir
+	logger, e := cblog.NewLogger(cblog.LoggerConfig{
+		LogLevel:           cblog.DebugLevel,
+		Format:             "%{time:2006-01-02 15:04:05.000} : %{file}:%{line} : %{message}",
+		LogToFile:          !isJob,
+		FilePath:           filepath.Join(saveDir, "training.log"),
+		FilePerm:           os.ModePerm,
+		LogToStdOut:        true,
+		SetAsDefaultLogger: true,
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	errorHandler := cberrors.NewErrorContainer(iowriterprovider.New(logger))
+
+	dataset, e := config.GetDataset(logger, errorHandler)
+	if e != nil {
+		os.Exit(1)
+	}
+	e = dataset.SaveProcessors(saveDir)
+	if e != nil {
+		os.Exit(1)
+	}
+
+	m, e := model.FromConfig(errorHandler, logger, config)
+	if e != nil {
+		os.Exit(1)
+	}
+
+	compileConfig, e := config.GetCompileConfig(saveDir)
+	if e != nil {
+		errorHandler.Error(e)
+		os.Exit(1)
+	}
+	e = m.CompileAndLoad(compileConfig)
+	if e != nil {
+		os.Exit(1)
+	}
+
+	fitConfig, e := config.GetFitConfig(logger, saveDir)
+	if e != nil {
+		errorHandler.Error(e)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logger.InfoF("main", "Training model: %s", saveDir)
+	_, e = m.FitContext(ctx, dataset, fitConfig)
+	if e != nil {
+		os.Exit(1)
+	}
+	logger.InfoF("main", "Finished training")
+}
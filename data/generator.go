@@ -0,0 +1,68 @@
+package data
+
+import (
+	"errors"
+	"github.com/codingbeard/cberrors"
+)
+
+// generateOrdered reads the rows of each batch in turn on a single goroutine, then builds up to concurrency batches at
+// once. The batches are sent in the order their rows were read, so a dataset shuffled with the same seed always
+// generates the same batches in the same order. Closing done stops generating before the dataset is exhausted
+func generateOrdered(
+	errorHandler *cberrors.ErrorsContainer,
+	numBatches int,
+	concurrency int,
+	preFetch int,
+	done <-chan struct{},
+	readBatch func() (func() (Batch, error), error),
+) chan Batch {
+	generatorChan := make(chan Batch, preFetch)
+	building := make(chan chan Batch, concurrency)
+
+	go func() {
+		defer close(building)
+		for i := 0; i < numBatches; i++ {
+			build, e := readBatch()
+			if errors.Is(e, ErrGeneratorEnd) {
+				return
+			}
+			if e != nil {
+				errorHandler.Error(e)
+				continue
+			}
+
+			batchChan := make(chan Batch, 1)
+			select {
+			case building <- batchChan:
+			case <-done:
+				return
+			}
+			go func() {
+				defer close(batchChan)
+				batch, e := build()
+				if e != nil {
+					errorHandler.Error(e)
+					return
+				}
+				batchChan <- batch
+			}()
+		}
+	}()
+
+	go func() {
+		defer close(generatorChan)
+		for batchChan := range building {
+			batch, ok := <-batchChan
+			if !ok {
+				continue
+			}
+			select {
+			case generatorChan <- batch:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return generatorChan
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"github.com/codingbeard/cberrors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGenerateOrderedDone(t *testing.T) {
+	numBatches := 100
+	var reads int32
+	done := make(chan struct{})
+	generatorChan := generateOrdered(
+		cberrors.NewErrorContainer(),
+		numBatches,
+		2,
+		1,
+		done,
+		func() (func() (Batch, error), error) {
+			atomic.AddInt32(&reads, 1)
+			return func() (Batch, error) {
+				return Batch{}, nil
+			}, nil
+		},
+	)
+
+	<-generatorChan
+	close(done)
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-generatorChan:
+			if ok {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("expected the generator to close after done was closed")
+		}
+		break
+	}
+
+	// The reader stops once the builders waiting to be sent are full
+	time.Sleep(50 * time.Millisecond)
+	stoppedReads := atomic.LoadInt32(&reads)
+	time.Sleep(50 * time.Millisecond)
+	if reads := atomic.LoadInt32(&reads); reads != stoppedReads || int(reads) == numBatches {
+		t.Errorf("expected the reader to stop after done was closed, read %d of %d batches", reads, numBatches)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/codingbeard/cblog"
 	"github.com/codingbeard/tfkg/preprocessor"
 	tf "github.com/galeone/tensorflow/tensorflow/go"
-	"github.com/remeh/sizedwaitgroup"
 	"io/ioutil"
 	"math"
 	"math/rand"
@@ -39,6 +38,7 @@ type ImgFolderDataset struct {
 	ignoreParseErrors   bool
 	shuffled            bool
 	generatorOffset     int
+	generatorOffsetLock *sync.Mutex
 	processor           *preprocessor.Processor
 	images              []imgMetadata
 	trainPercent        float32
@@ -110,6 +110,7 @@ func NewImgFolderDataset(
 		concurrentFileLimit: config.ConcurrentFileLimit,
 		openFileCount:       &openFileCount,
 		generatorOffset:     0,
+		generatorOffsetLock: &sync.Mutex{},
 	}
 
 	d.filePool = &sync.Pool{
@@ -327,11 +328,14 @@ func (d *ImgFolderDataset) SetMode(mode GeneratorMode) Dataset {
 
 func (d *ImgFolderDataset) getRow() (string, int, error) {
 	if d.shuffled {
-		if len(d.images) <= d.generatorOffset {
+		d.generatorOffsetLock.Lock()
+		generatorOffset := d.generatorOffset
+		d.generatorOffset++
+		d.generatorOffsetLock.Unlock()
+		if len(d.images) <= generatorOffset || d.offset+d.limit <= generatorOffset {
 			return "", 0, ErrGeneratorEnd
 		}
-		img := d.images[d.generatorOffset]
-		d.generatorOffset++
+		img := d.images[generatorOffset]
 		return img.Filepath, img.Category, nil
 	} else {
 		panic("Non shuffled mode not implemented")
@@ -358,49 +362,46 @@ func (d *ImgFolderDataset) GetColumnNames() []string {
 	return []string{d.processor.Name}
 }
 
-func (d *ImgFolderDataset) GeneratorChan(batchSize int, preFetch int) chan Batch {
-	generatorChan := make(chan Batch, preFetch)
-
-	go func() {
-		swg := sizedwaitgroup.New(int(d.concurrentFileLimit))
-		for i := 0; i < int(d.limit)/batchSize; i++ {
-			swg.Add()
-			go func() {
-				defer swg.Done()
-				x, y, classWeights, e := d.Generate(batchSize)
-
-				if errors.Is(e, ErrGeneratorEnd) {
-					return
-				}
-				if e != nil && !errors.Is(e, ErrGeneratorEnd) {
-					d.errorHandler.Error(e)
-					return
-				}
-
-				generatorChan <- Batch{
-					X:            x,
-					Y:            y,
-					ClassWeights: classWeights,
-				}
-			}()
-		}
-		swg.Wait()
-		close(generatorChan)
-	}()
-
-	return generatorChan
+func (d *ImgFolderDataset) GeneratorChan(batchSize int, preFetch int, done <-chan struct{}) chan Batch {
+	return generateOrdered(
+		d.errorHandler,
+		int(math.Ceil(float64(d.limit)/float64(batchSize))),
+		int(d.concurrentFileLimit),
+		preFetch,
+		done,
+		func() (func() (Batch, error), error) {
+			return d.readBatch(batchSize)
+		},
+	)
 }
 
 func (d *ImgFolderDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error) {
-	var x []*tf.Tensor
+	build, e := d.readBatch(batchSize)
+	if e != nil {
+		return nil, nil, nil, e
+	}
+	batch, e := build()
+	if e != nil {
+		return nil, nil, nil, e
+	}
+
+	return batch.X, batch.Y, batch.ClassWeights, nil
+}
 
+// readBatch reads the rows of the next batch. The returned function processes the images into the batch and can run
+// concurrently with the next readBatch
+func (d *ImgFolderDataset) readBatch(batchSize int) (func() (Batch, error), error) {
 	var xPaths []string
 	var yInts [][]int32
 
 	for true {
 		filePath, category, e := d.getRow()
 		if errors.Is(e, ErrGeneratorEnd) {
-			return nil, nil, nil, e
+			// The final batch of the mode may be smaller than batchSize
+			if len(yInts) > 0 {
+				break
+			}
+			return nil, e
 		}
 
 		if len(filePath) == 0 {
@@ -416,31 +417,39 @@ func (d *ImgFolderDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *t
 		}
 	}
 
-	process, e := d.processor.ProcessString(xPaths)
-	if e != nil {
-		return nil, nil, nil, e
-	}
+	return func() (Batch, error) {
+		var x []*tf.Tensor
 
-	x = append(x, process)
+		process, e := d.processor.ProcessString(xPaths)
+		if e != nil {
+			return Batch{}, e
+		}
 
-	var classWeights []float32
-	for _, yInt32 := range yInts {
-		classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])])
-	}
+		x = append(x, process)
 
-	classWeightsTensor, e := tf.NewTensor(classWeights)
-	if e != nil {
-		d.errorHandler.Error(e)
-		return nil, nil, nil, e
-	}
+		var classWeights []float32
+		for _, yInt32 := range yInts {
+			classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])])
+		}
 
-	y, e := tf.NewTensor(yInts)
-	if e != nil {
-		d.errorHandler.Error(e)
-		return nil, nil, nil, e
-	}
+		classWeightsTensor, e := tf.NewTensor(classWeights)
+		if e != nil {
+			d.errorHandler.Error(e)
+			return Batch{}, e
+		}
+
+		y, e := tf.NewTensor(yInts)
+		if e != nil {
+			d.errorHandler.Error(e)
+			return Batch{}, e
+		}
 
-	return x, y, classWeightsTensor, nil
+		return Batch{
+			X:            x,
+			Y:            y,
+			ClassWeights: classWeightsTensor,
+		}, nil
+	}, nil
 }
 
 func (d *ImgFolderDataset) Reset() error {
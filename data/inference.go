@@ -11,6 +11,7 @@ import (
 type Inference struct {
 	processorsSaveDir string
 	columnProcessors  []*preprocessor.Processor
+	yProcessor        *preprocessor.Processor
 	categoryTokenizer *preprocessor.Tokenizer
 
 	logger       *cblog.Logger
@@ -77,3 +78,35 @@ func (d *Inference) GenerateInputs(rawInputValues ...interface{}) ([]*tf.Tensor,
 
 	return x, nil
 }
+
+// LoadYProcessor loads the saved state of the y processor used to train the model, E.G. a
+// preprocessor.NewScalingYProcessor, so InverseTransformY can map predictions back to the original units
+func (d *Inference) LoadYProcessor(yProcessor *preprocessor.Processor) error {
+	yProcessor.SetLoadDir(d.processorsSaveDir)
+	e := yProcessor.Load()
+	if e != nil {
+		d.errorHandler.Error(e)
+		return e
+	}
+
+	d.yProcessor = yProcessor
+
+	return nil
+}
+
+// InverseTransformY maps the float32 outputs of TfkgModel.Predict back to the original units of the y processor.
+// The outputs are returned unchanged if no y processor has been loaded or it does not scale its values
+func (d *Inference) InverseTransformY(outputs *tf.Tensor) ([][]float32, error) {
+	rows, ok := outputs.Value().([][]float32)
+	if !ok {
+		e := fmt.Errorf("could not convert outputs to [][]float32 to inverse transform, got shape: %v", outputs.Shape())
+		d.errorHandler.Error(e)
+		return nil, e
+	}
+
+	if d.yProcessor == nil {
+		return rows, nil
+	}
+
+	return d.yProcessor.InverseTransform(rows)
+}
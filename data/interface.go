@@ -21,14 +21,19 @@ type Dataset interface {
 	Shuffle(seed int64)
 	Unshuffle() error
 	GetColumnNames() []string
-	GeneratorChan(batchSize int, preFetch int) chan Batch
+	// GeneratorChan generates batches until the dataset is exhausted or done is closed, a nil done is never closed so
+	// every batch must be read
+	GeneratorChan(batchSize int, preFetch int, done <-chan struct{}) chan Batch
 	Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error)
 	Reset() error
 	SaveProcessors(saveDir string) error
 }
 
 type Batch struct {
-	X            []*tf.Tensor
-	Y            *tf.Tensor
+	X []*tf.Tensor
+	Y *tf.Tensor
+	// ExtraY holds the labels for the second and subsequent outputs of a multiple output model
+	ExtraY []*tf.Tensor
+	// ClassWeights is the class weight of each row multiplied by its sample weight, it scales the loss of the row
 	ClassWeights *tf.Tensor
 }
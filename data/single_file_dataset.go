@@ -19,6 +19,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -42,6 +43,7 @@ type SingleFileDataset struct {
 	generatorOffsetLock *sync.Mutex
 	cacheDir            string
 	yProcessor          *preprocessor.Processor
+	extraYProcessors    []*preprocessor.Processor
 	columnProcessors    []*preprocessor.Processor
 	lineOffsets         []int64
 	trainPercent        float32
@@ -54,6 +56,7 @@ type SingleFileDataset struct {
 	offset              int32
 	limit               int32
 	filter              func(line []string) bool
+	sampleWeight        func(row []string) (float32, error)
 	maxRowsForFit       int
 
 	logger       *cblog.Logger
@@ -72,6 +75,25 @@ type SingleFileDatasetConfig struct {
 	ConcurrentFileLimit    int32
 	MaxRowsForProcessorFit int
 	ClassWeights           map[int]float32
+	// ExtraYProcessors produce the labels for the second and subsequent outputs of a multiple output model
+	ExtraYProcessors []*preprocessor.Processor
+	// SampleWeight returns a weight for a row which is multiplied with the class weight of the row. Use
+	// SampleWeightFromColumn to read the weight from a column of the file
+	SampleWeight func(row []string) (float32, error)
+}
+
+// SampleWeightFromColumn parses the sample weight of each row from the column at offset
+func SampleWeightFromColumn(offset int) func(row []string) (float32, error) {
+	return func(row []string) (float32, error) {
+		if len(row) <= offset {
+			return 0, fmt.Errorf("row did not contain enough columns for the sample weight at offset %d", offset)
+		}
+		weight, e := strconv.ParseFloat(row[offset], 32)
+		if e != nil {
+			return 0, e
+		}
+		return float32(weight), nil
+	}
 }
 
 func NewSingleFileDataset(
@@ -112,6 +134,7 @@ func NewSingleFileDataset(
 		ignoreParseErrors:   config.IgnoreParseErrors,
 		cacheDir:            config.CacheDir,
 		yProcessor:          yProcessor,
+		extraYProcessors:    config.ExtraYProcessors,
 		columnProcessors:    columnProcessors,
 		trainPercent:        config.TrainPercent,
 		valPercent:          config.ValPercent,
@@ -119,6 +142,7 @@ func NewSingleFileDataset(
 		ClassCounts:         make(map[int]int),
 		ClassWeights:        config.ClassWeights,
 		filter:              config.RowFilter,
+		sampleWeight:        config.SampleWeight,
 		concurrentFileLimit: config.ConcurrentFileLimit,
 		openFileCount:       &openFileCount,
 		generatorOffset:     &generatorOffset,
@@ -280,6 +304,21 @@ func (d *SingleFileDataset) readLineOffsets() error {
 				}
 			}
 
+			for _, extraYProcessor := range d.extraYProcessors {
+				if !extraYProcessor.RequiresFit || len(line) <= extraYProcessor.LineOffset {
+					continue
+				}
+				e = extraYProcessor.FitString([]string{line[extraYProcessor.LineOffset]})
+				if e != nil {
+					if d.ignoreParseErrors {
+						return
+					}
+					d.errorHandler.Error(e)
+					errs = append(errs, e)
+					return
+				}
+			}
+
 			category, e := d.yProcessor.ProcessString([]string{line[d.yProcessor.LineOffset]})
 			if e != nil {
 				if d.ignoreParseErrors {
@@ -290,8 +329,9 @@ func (d *SingleFileDataset) readLineOffsets() error {
 				return
 			}
 
+			// Sequence labels have a label per position so they are not counted as classes
 			categoryInt, isInt := category.Value().([][]int32)
-			if isInt {
+			if isInt && len(categoryInt[0]) == 1 {
 				d.classCountsLock.Lock()
 				count := d.ClassCounts[int(categoryInt[0][0])]
 				count++
@@ -340,6 +380,14 @@ func (d *SingleFileDataset) readLineOffsets() error {
 			return e
 		}
 	}
+	for _, extraYProcessor := range d.extraYProcessors {
+		if extraYProcessor.RequiresFit {
+			e := extraYProcessor.FinishFit()
+			if e != nil {
+				return e
+			}
+		}
+	}
 
 	e = ioutil.WriteFile(filepath.Join(d.cacheDir, cacheFileName), cacheBytes, os.ModePerm)
 	if e != nil {
@@ -537,7 +585,7 @@ func (d *SingleFileDataset) getRow() ([]string, error) {
 		generatorOffset := atomic.LoadInt32(d.generatorOffset)
 		atomic.AddInt32(d.generatorOffset, 1)
 		d.generatorOffsetLock.Unlock()
-		if len(d.lineOffsets) <= int(generatorOffset) {
+		if len(d.lineOffsets) <= int(generatorOffset) || d.offset+d.limit <= generatorOffset {
 			return nil, ErrGeneratorEnd
 		}
 		offset := d.lineOffsets[int(generatorOffset)]
@@ -596,55 +644,71 @@ func (d *SingleFileDataset) GetColumnNames() []string {
 	return columnNames
 }
 
-func (d *SingleFileDataset) GeneratorChan(batchSize int, preFetch int) chan Batch {
-	generatorChan := make(chan Batch, preFetch)
-
-	go func() {
-		swg := sizedwaitgroup.New(int(d.concurrentFileLimit))
-		for i := 0; i < int(d.limit)/batchSize; i++ {
-			swg.Add()
-			go func() {
-				defer swg.Done()
-				x, y, classWeights, e := d.Generate(batchSize)
-
-				if errors.Is(e, ErrGeneratorEnd) {
-					return
-				}
-				if e != nil && !errors.Is(e, ErrGeneratorEnd) {
-					d.errorHandler.Error(e)
-					return
-				}
+func (d *SingleFileDataset) GeneratorChan(batchSize int, preFetch int, done <-chan struct{}) chan Batch {
+	return generateOrdered(
+		d.errorHandler,
+		int(math.Ceil(float64(d.limit)/float64(batchSize))),
+		int(d.concurrentFileLimit),
+		preFetch,
+		done,
+		func() (func() (Batch, error), error) {
+			return d.readBatch(batchSize)
+		},
+	)
+}
 
-				generatorChan <- Batch{
-					X:            x,
-					Y:            y,
-					ClassWeights: classWeights,
-				}
-			}()
-		}
-		swg.Wait()
-		close(generatorChan)
-	}()
+func (d *SingleFileDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error) {
+	batch, e := d.generateBatch(batchSize)
+	if e != nil {
+		return nil, nil, nil, e
+	}
 
-	return generatorChan
+	return batch.X, batch.Y, batch.ClassWeights, nil
 }
 
-func (d *SingleFileDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error) {
-	var x []*tf.Tensor
+func (d *SingleFileDataset) generateBatch(batchSize int) (Batch, error) {
+	build, e := d.readBatch(batchSize)
+	if e != nil {
+		return Batch{}, e
+	}
+	return build()
+}
 
+// readBatch reads the rows of the next batch. The returned function processes the rows into the batch and can run
+// concurrently with the next readBatch
+func (d *SingleFileDataset) readBatch(batchSize int) (func() (Batch, error), error) {
 	xStrings := make([][]string, len(d.columnProcessors))
 	var yRaw []string
+	extraYRaw := make([][]string, len(d.extraYProcessors))
+	var sampleWeights []float32
 
 	for true {
 		row, e := d.getRow()
 		if errors.Is(e, ErrGeneratorEnd) {
-			return nil, nil, nil, e
+			// The final batch of the mode may be smaller than batchSize
+			if len(yRaw) > 0 {
+				break
+			}
+			return nil, e
 		}
 
 		if len(row) == 0 {
 			continue
 		}
 
+		// The sample weight is parsed first so a row which fails is skipped before any of its columns are added
+		sampleWeight := float32(1)
+		if d.sampleWeight != nil {
+			sampleWeight, e = d.sampleWeight(row)
+			if e != nil {
+				if d.ignoreParseErrors {
+					continue
+				}
+				d.errorHandler.Error(e)
+				return nil, e
+			}
+		}
+
 		var lineError error
 		for offset, processor := range d.columnProcessors {
 			if processor.DataLength > 1 {
@@ -671,12 +735,21 @@ func (d *SingleFileDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *
 				}
 			}
 		}
+		for _, extraYProcessor := range d.extraYProcessors {
+			if len(row) <= extraYProcessor.LineOffset {
+				lineError = fmt.Errorf(
+					"row did not contain enough columns for y processor %s at offset %d",
+					extraYProcessor.Name,
+					extraYProcessor.LineOffset,
+				)
+			}
+		}
 		if lineError != nil {
 			if d.ignoreParseErrors {
 				continue
 			}
 			d.errorHandler.Error(e)
-			return nil, nil, nil, e
+			return nil, e
 		}
 
 		if len(row) <= d.yProcessor.LineOffset {
@@ -685,49 +758,69 @@ func (d *SingleFileDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *
 			}
 			e = fmt.Errorf("row did not contain enough columns for categoryOffset at %d", d.yProcessor.LineOffset)
 			d.errorHandler.Error(e)
-			return nil, nil, nil, e
+			return nil, e
 		}
 
 		yRaw = append(yRaw, row[d.yProcessor.LineOffset])
+		sampleWeights = append(sampleWeights, sampleWeight)
+		for offset, extraYProcessor := range d.extraYProcessors {
+			extraYRaw[offset] = append(extraYRaw[offset], row[extraYProcessor.LineOffset])
+		}
 
 		if len(yRaw) >= batchSize {
 			break
 		}
 	}
 
-	for offset, processor := range d.columnProcessors {
-		process, e := processor.ProcessString(xStrings[offset])
-		if e != nil {
-			return nil, nil, nil, e
-		}
+	return func() (Batch, error) {
+		var x []*tf.Tensor
 
-		x = append(x, process)
-	}
+		for offset, processor := range d.columnProcessors {
+			process, e := processor.ProcessString(xStrings[offset])
+			if e != nil {
+				return Batch{}, e
+			}
 
-	y, e := d.yProcessor.ProcessString(yRaw)
-	if e != nil {
-		return nil, nil, nil, e
-	}
+			x = append(x, process)
+		}
 
-	var classWeights []float32
-	yInts, isInt := y.Value().([][]int32)
-	if isInt {
-		for _, yInt32 := range yInts {
-			classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])])
+		y, e := d.yProcessor.ProcessString(yRaw)
+		if e != nil {
+			return Batch{}, e
 		}
-	} else {
-		for range yRaw {
-			classWeights = append(classWeights, 1)
+
+		var extraY []*tf.Tensor
+		for offset, extraYProcessor := range d.extraYProcessors {
+			processed, e := extraYProcessor.ProcessString(extraYRaw[offset])
+			if e != nil {
+				return Batch{}, e
+			}
+			extraY = append(extraY, processed)
 		}
-	}
 
-	classWeightsTensor, e := tf.NewTensor(classWeights)
-	if e != nil {
-		d.errorHandler.Error(e)
-		return nil, nil, nil, e
-	}
+		var classWeights []float32
+		yInts, isInt := y.Value().([][]int32)
+		if isInt && len(yInts[0]) == 1 {
+			for i, yInt32 := range yInts {
+				classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])]*sampleWeights[i])
+			}
+		} else {
+			classWeights = sampleWeights
+		}
 
-	return x, y, classWeightsTensor, nil
+		classWeightsTensor, e := tf.NewTensor(classWeights)
+		if e != nil {
+			d.errorHandler.Error(e)
+			return Batch{}, e
+		}
+
+		return Batch{
+			X:            x,
+			Y:            y,
+			ExtraY:       extraY,
+			ClassWeights: classWeightsTensor,
+		}, nil
+	}, nil
 }
 
 func (d *SingleFileDataset) Reset() error {
@@ -755,5 +848,11 @@ func (d *SingleFileDataset) SaveProcessors(saveDir string) error {
 	if e != nil {
 		return e
 	}
+	for _, extraYProcessor := range d.extraYProcessors {
+		e = extraYProcessor.Save(saveDir)
+		if e != nil {
+			return e
+		}
+	}
 	return nil
 }
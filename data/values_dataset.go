@@ -10,7 +10,9 @@ import (
 	"github.com/remeh/sizedwaitgroup"
 	"math"
 	"math/rand"
+	"os"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -19,24 +21,28 @@ type ValuesDataset struct {
 	ClassWeights map[int]float32
 	Count        int
 
-	shuffled         bool
-	generatorOffset  int
-	cacheDir         string
-	yProcessor       *preprocessor.Processor
-	isCategorical    bool
-	columnProcessors []*preprocessor.Processor
-	trainPercent     float32
-	valPercent       float32
-	testPercent      float32
-	trainCount       int
-	valCount         int
-	testCount        int
-	mode             GeneratorMode
-	offsets          []int
-	offset           int
-	limit            int
-	xValues          [][]interface{}
-	yValues          []interface{}
+	shuffled            bool
+	generatorOffset     int
+	generatorOffsetLock *sync.Mutex
+	cacheDir            string
+	yProcessor          *preprocessor.Processor
+	extraYProcessors    []*preprocessor.Processor
+	isCategorical       bool
+	columnProcessors    []*preprocessor.Processor
+	trainPercent        float32
+	valPercent          float32
+	testPercent         float32
+	trainCount          int
+	valCount            int
+	testCount           int
+	mode                GeneratorMode
+	offsets             []int
+	offset              int
+	limit               int
+	xValues             [][]interface{}
+	yValues             []interface{}
+	extraYValues        [][]interface{}
+	sampleWeights       []float32
 
 	logger       *cblog.Logger
 	errorHandler *cberrors.ErrorsContainer
@@ -47,6 +53,8 @@ type ValuesDatasetConfig struct {
 	TrainPercent float32
 	ValPercent   float32
 	TestPercent  float32
+	// ExtraYProcessors produce the labels for the second and subsequent outputs of a multiple output model
+	ExtraYProcessors []*preprocessor.Processor
 }
 
 func NewValuesDataset(
@@ -63,12 +71,15 @@ func NewValuesDataset(
 		logger:           logger,
 		errorHandler:     errorHandler,
 		yProcessor:       yProcessor,
+		extraYProcessors: config.ExtraYProcessors,
 		columnProcessors: columnProcessors,
 		trainPercent:     config.TrainPercent,
 		valPercent:       config.ValPercent,
 		testPercent:      config.TestPercent,
 		ClassCounts:      make(map[int]int),
 		ClassWeights:     make(map[int]float32),
+
+		generatorOffsetLock: &sync.Mutex{},
 	}
 
 	return d, nil
@@ -128,6 +139,53 @@ func (d *ValuesDataset) SetValues(yValues []interface{}, xValues ...[]interface{
 	return d.fitPreProcessors()
 }
 
+// SetExtraYValues sets the labels for the second and subsequent outputs of a multiple output model, one slice per
+// ExtraYProcessors entry. It must be called after SetValues
+func (d *ValuesDataset) SetExtraYValues(extraYValues ...[]interface{}) error {
+	if len(extraYValues) != len(d.extraYProcessors) {
+		e := fmt.Errorf(
+			"the number of extra y processors defined on ValuesDataset (%d) did not match the number of extra y values (%d)",
+			len(d.extraYProcessors),
+			len(extraYValues),
+		)
+		d.errorHandler.Error(e)
+		return e
+	}
+	for _, values := range extraYValues {
+		if len(values) != len(d.yValues) {
+			e := fmt.Errorf(
+				"the number of extra y values (%d) did not match the number of y values (%d)",
+				len(values),
+				len(d.yValues),
+			)
+			d.errorHandler.Error(e)
+			return e
+		}
+	}
+
+	d.extraYValues = extraYValues
+
+	return nil
+}
+
+// SetSampleWeights sets a weight for each row which is multiplied with the class weight of the row. It must be called
+// after SetValues
+func (d *ValuesDataset) SetSampleWeights(sampleWeights []float32) error {
+	if len(sampleWeights) != len(d.yValues) {
+		e := fmt.Errorf(
+			"the number of sample weights (%d) did not match the number of y values (%d)",
+			len(sampleWeights),
+			len(d.yValues),
+		)
+		d.errorHandler.Error(e)
+		return e
+	}
+
+	d.sampleWeights = sampleWeights
+
+	return nil
+}
+
 func (d *ValuesDataset) NumCategoricalClasses() int {
 	return len(d.ClassCounts)
 }
@@ -148,6 +206,14 @@ func (d *ValuesDataset) fitPreProcessors() error {
 			}
 		}
 	}
+	if d.yProcessor.RequiresFit {
+		e := d.yProcessor.Load()
+		if e == nil {
+			d.logger.InfoF("data", "Loaded Pre-Processor: %s", d.yProcessor.Name)
+		} else {
+			anyNeedingFit = true
+		}
+	}
 
 	if !anyNeedingFit {
 		d.logger.InfoF("data", "Loaded All Pre-Processors")
@@ -166,10 +232,17 @@ func (d *ValuesDataset) fitPreProcessors() error {
 	swg := sizedwaitgroup.New(64)
 
 	for i := 0; i < 1000000; i++ {
-		xInterface, _, e := d.getRow()
+		xInterface, yInterface, _, _, e := d.getRow()
 		if errors.Is(e, ErrGeneratorEnd) {
 			break
 		}
+		if d.yProcessor.RequiresFit {
+			e = d.yProcessor.FitInterface(yInterface)
+			if e != nil {
+				d.errorHandler.Error(e)
+				return e
+			}
+		}
 		for _, processor := range d.columnProcessors {
 			if processor.RequiresFit {
 				swg.Add()
@@ -211,6 +284,12 @@ func (d *ValuesDataset) fitPreProcessors() error {
 			}
 		}
 	}
+	if d.yProcessor.RequiresFit {
+		e := d.yProcessor.FinishFit()
+		if e != nil {
+			return e
+		}
+	}
 
 	e := d.Reset()
 	if e != nil {
@@ -251,12 +330,16 @@ func (d *ValuesDataset) SetMode(mode GeneratorMode) Dataset {
 	return d
 }
 
-func (d *ValuesDataset) getRow() ([]interface{}, interface{}, error) {
-	if len(d.yValues) <= d.generatorOffset {
-		return nil, nil, ErrGeneratorEnd
+func (d *ValuesDataset) getRow() ([]interface{}, interface{}, []interface{}, float32, error) {
+	d.generatorOffsetLock.Lock()
+	generatorOffset := d.generatorOffset
+	d.generatorOffset++
+	d.generatorOffsetLock.Unlock()
+	if len(d.yValues) <= generatorOffset || d.offset+d.limit <= generatorOffset {
+		return nil, nil, nil, 0, ErrGeneratorEnd
 	}
 
-	offset := d.offsets[d.generatorOffset]
+	offset := d.offsets[generatorOffset]
 
 	var x []interface{}
 	for i := range d.columnProcessors {
@@ -264,12 +347,17 @@ func (d *ValuesDataset) getRow() ([]interface{}, interface{}, error) {
 	}
 	y := d.yValues[offset]
 
-	d.generatorOffset++
+	var extraY []interface{}
+	for i := range d.extraYValues {
+		extraY = append(extraY, d.extraYValues[i][offset])
+	}
 
-	if d.generatorOffset >= d.offset+d.limit {
-		return x, y, ErrGeneratorEnd
+	sampleWeight := float32(1)
+	if d.sampleWeights != nil {
+		sampleWeight = d.sampleWeights[offset]
 	}
-	return x, y, nil
+
+	return x, y, extraY, sampleWeight, nil
 }
 
 func (d *ValuesDataset) Shuffle(seed int64) {
@@ -298,49 +386,52 @@ func (d *ValuesDataset) GetColumnNames() []string {
 	return columnNames
 }
 
-func (d *ValuesDataset) GeneratorChan(batchSize int, preFetch int) chan Batch {
-	generatorChan := make(chan Batch, preFetch)
-
-	go func() {
-		swg := sizedwaitgroup.New(runtime.NumCPU())
-		for i := 0; i < d.limit/batchSize; i++ {
-			swg.Add()
-			go func() {
-				defer swg.Done()
-				x, y, classWeights, e := d.Generate(batchSize)
-
-				if errors.Is(e, ErrGeneratorEnd) {
-					return
-				}
-				if e != nil && !errors.Is(e, ErrGeneratorEnd) {
-					d.errorHandler.Error(e)
-					return
-				}
+func (d *ValuesDataset) GeneratorChan(batchSize int, preFetch int, done <-chan struct{}) chan Batch {
+	return generateOrdered(
+		d.errorHandler,
+		int(math.Ceil(float64(d.limit)/float64(batchSize))),
+		runtime.NumCPU(),
+		preFetch,
+		done,
+		func() (func() (Batch, error), error) {
+			return d.readBatch(batchSize)
+		},
+	)
+}
 
-				generatorChan <- Batch{
-					X:            x,
-					Y:            y,
-					ClassWeights: classWeights,
-				}
-			}()
-		}
-		swg.Wait()
-		close(generatorChan)
-	}()
+func (d *ValuesDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error) {
+	batch, e := d.generateBatch(batchSize)
+	if e != nil {
+		return nil, nil, nil, e
+	}
 
-	return generatorChan
+	return batch.X, batch.Y, batch.ClassWeights, nil
 }
 
-func (d *ValuesDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.Tensor, error) {
-	var x []*tf.Tensor
+func (d *ValuesDataset) generateBatch(batchSize int) (Batch, error) {
+	build, e := d.readBatch(batchSize)
+	if e != nil {
+		return Batch{}, e
+	}
+	return build()
+}
 
+// readBatch reads the rows of the next batch. The returned function processes the rows into the batch and can run
+// concurrently with the next readBatch
+func (d *ValuesDataset) readBatch(batchSize int) (func() (Batch, error), error) {
 	xRaw := make([][]interface{}, len(d.columnProcessors))
 	var yRaw []interface{}
+	extraYRaw := make([][]interface{}, len(d.extraYValues))
+	var sampleWeights []float32
 
 	for true {
-		xInterfaces, yInterface, e := d.getRow()
+		xInterfaces, yInterface, extraYInterfaces, sampleWeight, e := d.getRow()
 		if errors.Is(e, ErrGeneratorEnd) {
-			return nil, nil, nil, e
+			// The final batch of the mode may be smaller than batchSize
+			if len(yRaw) > 0 {
+				break
+			}
+			return nil, e
 		}
 
 		if len(xInterfaces) == 0 {
@@ -352,46 +443,67 @@ func (d *ValuesDataset) Generate(batchSize int) ([]*tf.Tensor, *tf.Tensor, *tf.T
 		}
 
 		yRaw = append(yRaw, yInterface)
+		sampleWeights = append(sampleWeights, sampleWeight)
+		for i := range extraYInterfaces {
+			extraYRaw[i] = append(extraYRaw[i], extraYInterfaces[i])
+		}
 
 		if len(yRaw) >= batchSize {
 			break
 		}
 	}
 
-	for offset, processor := range d.columnProcessors {
-		process, e := processor.ProcessInterface(xRaw[offset])
-		if e != nil {
-			return nil, nil, nil, e
-		}
+	return func() (Batch, error) {
+		var x []*tf.Tensor
 
-		x = append(x, process)
-	}
+		for offset, processor := range d.columnProcessors {
+			process, e := processor.ProcessInterface(xRaw[offset])
+			if e != nil {
+				return Batch{}, e
+			}
 
-	y, e := d.yProcessor.ProcessInterface(yRaw)
-	if e != nil {
-		d.errorHandler.Error(e)
-		return nil, nil, nil, e
-	}
+			x = append(x, process)
+		}
 
-	var classWeights []float32
-	categoricalY, ok := y.Value().([][]int32)
-	if ok {
-		for _, yInt32 := range categoricalY {
-			classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])])
+		y, e := d.yProcessor.ProcessInterface(yRaw)
+		if e != nil {
+			d.errorHandler.Error(e)
+			return Batch{}, e
 		}
-	} else {
-		for range yRaw {
-			classWeights = append(classWeights, 1)
+
+		var extraY []*tf.Tensor
+		for offset := range extraYRaw {
+			processed, e := d.extraYProcessors[offset].ProcessInterface(extraYRaw[offset])
+			if e != nil {
+				d.errorHandler.Error(e)
+				return Batch{}, e
+			}
+			extraY = append(extraY, processed)
 		}
-	}
 
-	classWeightsTensor, e := tf.NewTensor(classWeights)
-	if e != nil {
-		d.errorHandler.Error(e)
-		return nil, nil, nil, e
-	}
+		var classWeights []float32
+		categoricalY, ok := y.Value().([][]int32)
+		if ok && len(categoricalY[0]) == 1 {
+			for i, yInt32 := range categoricalY {
+				classWeights = append(classWeights, d.ClassWeights[int(yInt32[0])]*sampleWeights[i])
+			}
+		} else {
+			classWeights = sampleWeights
+		}
+
+		classWeightsTensor, e := tf.NewTensor(classWeights)
+		if e != nil {
+			d.errorHandler.Error(e)
+			return Batch{}, e
+		}
 
-	return x, y, classWeightsTensor, nil
+		return Batch{
+			X:            x,
+			Y:            y,
+			ExtraY:       extraY,
+			ClassWeights: classWeightsTensor,
+		}, nil
+	}, nil
 }
 
 func (d *ValuesDataset) Reset() error {
@@ -401,6 +513,26 @@ func (d *ValuesDataset) Reset() error {
 }
 
 func (d *ValuesDataset) SaveProcessors(saveDir string) error {
-
+	e := os.MkdirAll(saveDir, os.ModePerm)
+	if e != nil {
+		d.errorHandler.Error(e)
+		return e
+	}
+	for _, processor := range d.columnProcessors {
+		e := processor.Save(saveDir)
+		if e != nil {
+			return e
+		}
+	}
+	e = d.yProcessor.Save(saveDir)
+	if e != nil {
+		return e
+	}
+	for _, extraYProcessor := range d.extraYProcessors {
+		e = extraYProcessor.Save(saveDir)
+		if e != nil {
+			return e
+		}
+	}
 	return nil
 }
@@ -0,0 +1,304 @@
+package explain
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/data"
+	"github.com/codingbeard/tfkg/metric"
+	"github.com/codingbeard/tfkg/model"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type PermutationImportanceConfig struct {
+	Mode      data.GeneratorMode
+	BatchSize int
+	PreFetch  int
+	// Metric scores the predictions of the output at OutputIndex
+	Metric      metric.Metric
+	OutputIndex int
+	// LowerIsBetter should be set for metrics such as metric.HammingLoss so the importance is still the drop in quality
+	LowerIsBetter bool
+	// Repeats is the number of times each column is shuffled, defaults to 5
+	Repeats int
+	// PerFeature shuffles each element of float32 columns on its own, E.G. each float of a multi-column processor. Other
+	// columns, such as tokenized strings, are always shuffled whole
+	PerFeature bool
+	// Confidence is the level of the confidence interval of the importance, defaults to 0.95
+	Confidence float64
+	Seed       int64
+}
+
+type FeatureImportance struct {
+	Column string
+	// Feature is the offset of the element within the column when PerFeature is set, otherwise -1
+	Feature           int
+	Importance        float64
+	StandardDeviation float64
+	LowerBound        float64
+	UpperBound        float64
+	Drops             []float64
+}
+
+type permutableColumn struct {
+	dataType tf.DataType
+	rowShape []int64
+	rowBytes int
+	rows     [][]byte
+}
+
+type permutationSplit struct {
+	columns   []*permutableColumn
+	batchRows []int
+	labels    []*tf.Tensor
+}
+
+// PermutationImportance measures how much the Metric drops when the values of each input column are shuffled between
+// the rows of a dataset split, breaking their relationship with the labels. It only needs Predict so it works on any
+// model, including those loaded with model.LoadVanillaModel. The confidence interval uses a normal approximation over
+// the Repeats, sorted from the most to the least important
+func PermutationImportance(
+	errorHandler *cberrors.ErrorsContainer,
+	logger *cblog.Logger,
+	m *model.TfkgModel,
+	dataset data.Dataset,
+	config PermutationImportanceConfig,
+) ([]FeatureImportance, error) {
+	if config.Metric == nil {
+		e := fmt.Errorf("no Metric set for permutation importance")
+		errorHandler.Error(e)
+		return nil, e
+	}
+	if config.Mode == "" {
+		config.Mode = data.GeneratorModeTest
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+	if config.PreFetch == 0 {
+		config.PreFetch = 10
+	}
+	if config.Repeats == 0 {
+		config.Repeats = 5
+	}
+	if config.Confidence == 0 {
+		config.Confidence = 0.95
+	}
+	config.Metric.Init()
+
+	split, e := readPermutationSplit(dataset, config)
+	if e != nil {
+		errorHandler.Error(e)
+		return nil, e
+	}
+	columnNames := dataset.GetColumnNames()
+	if len(columnNames) != len(split.columns) {
+		e = fmt.Errorf("the number of column names (%d) did not match the number of inputs (%d)", len(columnNames), len(split.columns))
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	baseline, e := scorePermutationSplit(errorHandler, m, split, config)
+	if e != nil {
+		return nil, e
+	}
+	logger.InfoF("explain", "Baseline %s: %f", config.Metric.GetName(), baseline)
+
+	random := rand.New(rand.NewSource(config.Seed))
+	var importances []FeatureImportance
+	for columnOffset, column := range split.columns {
+		features := []int{-1}
+		if config.PerFeature && column.dataType == tf.Float {
+			features = nil
+			for feature := 0; feature < column.rowBytes/4; feature++ {
+				features = append(features, feature)
+			}
+		}
+
+		for _, feature := range features {
+			importance := FeatureImportance{
+				Column:  columnNames[columnOffset],
+				Feature: feature,
+			}
+			for repeat := 0; repeat < config.Repeats; repeat++ {
+				split.columns[columnOffset] = column.permute(random, feature)
+				score, e := scorePermutationSplit(errorHandler, m, split, config)
+				split.columns[columnOffset] = column
+				if e != nil {
+					return nil, e
+				}
+
+				drop := baseline - score
+				if config.LowerIsBetter {
+					drop = score - baseline
+				}
+				importance.Drops = append(importance.Drops, drop)
+			}
+			importance.setStatistics(config.Confidence)
+			importances = append(importances, importance)
+
+			logger.InfoF(
+				"explain",
+				"Permutation importance of %s feature %d: %f (%f - %f)",
+				importance.Column,
+				importance.Feature,
+				importance.Importance,
+				importance.LowerBound,
+				importance.UpperBound,
+			)
+		}
+	}
+
+	sort.SliceStable(importances, func(i, j int) bool {
+		return importances[i].Importance > importances[j].Importance
+	})
+
+	return importances, nil
+}
+
+func readPermutationSplit(dataset data.Dataset, config PermutationImportanceConfig) (*permutationSplit, error) {
+	split := &permutationSplit{}
+	var readError error
+	for batch := range dataset.SetMode(config.Mode).GeneratorChan(config.BatchSize, config.PreFetch, nil) {
+		// The generator is drained after an error so it is not left blocked
+		if readError != nil {
+			continue
+		}
+		labels := batch.Y
+		if config.OutputIndex > 0 {
+			if len(batch.ExtraY) < config.OutputIndex {
+				readError = fmt.Errorf("the dataset has no labels for output %d", config.OutputIndex)
+				continue
+			}
+			labels = batch.ExtraY[config.OutputIndex-1]
+		}
+		if len(batch.X) == 0 || batch.X[0].Shape()[0] == 0 {
+			continue
+		}
+		rows := int(batch.X[0].Shape()[0])
+
+		for offset, x := range batch.X {
+			if len(split.columns) <= offset {
+				split.columns = append(split.columns, &permutableColumn{
+					dataType: x.DataType(),
+					rowShape: x.Shape()[1:],
+				})
+			}
+			column := split.columns[offset]
+			if x.DataType() == tf.String {
+				readError = fmt.Errorf("string inputs can not be permuted, input %d", offset)
+				break
+			}
+
+			var buffer bytes.Buffer
+			_, e := x.WriteContentsTo(&buffer)
+			if e != nil {
+				readError = e
+				break
+			}
+			contents := buffer.Bytes()
+			column.rowBytes = len(contents) / rows
+			for row := 0; row < rows; row++ {
+				column.rows = append(column.rows, contents[row*column.rowBytes:(row+1)*column.rowBytes])
+			}
+		}
+		split.batchRows = append(split.batchRows, rows)
+		split.labels = append(split.labels, labels)
+	}
+	if readError != nil {
+		return nil, readError
+	}
+
+	if len(split.batchRows) == 0 {
+		return nil, fmt.Errorf("no rows in the %s split of the dataset", config.Mode)
+	}
+
+	return split, nil
+}
+
+func scorePermutationSplit(
+	errorHandler *cberrors.ErrorsContainer,
+	m *model.TfkgModel,
+	split *permutationSplit,
+	config PermutationImportanceConfig,
+) (float64, error) {
+	config.Metric.Reset()
+	rowOffset := 0
+	for batch, rows := range split.batchRows {
+		var inputs []*tf.Tensor
+		for _, column := range split.columns {
+			var buffer bytes.Buffer
+			for row := rowOffset; row < rowOffset+rows; row++ {
+				buffer.Write(column.rows[row])
+			}
+			input, e := tf.ReadTensor(column.dataType, append([]int64{int64(rows)}, column.rowShape...), &buffer)
+			if e != nil {
+				errorHandler.Error(e)
+				return 0, e
+			}
+			inputs = append(inputs, input)
+		}
+		rowOffset += rows
+
+		outputs, e := m.PredictOutputs(inputs...)
+		if e != nil {
+			return 0, e
+		}
+		if len(outputs) <= config.OutputIndex {
+			e = fmt.Errorf("the model has no output %d", config.OutputIndex)
+			errorHandler.Error(e)
+			return 0, e
+		}
+
+		config.Metric.Compute(split.labels[batch].Value(), outputs[config.OutputIndex].Value())
+	}
+
+	return config.Metric.ComputeFinal().Value, nil
+}
+
+// permute shuffles the rows of the column, or only the element at feature of each row when feature is not -1
+func (c *permutableColumn) permute(random *rand.Rand, feature int) *permutableColumn {
+	permutation := random.Perm(len(c.rows))
+	permuted := &permutableColumn{
+		dataType: c.dataType,
+		rowShape: c.rowShape,
+		rowBytes: c.rowBytes,
+		rows:     make([][]byte, len(c.rows)),
+	}
+	for row, from := range permutation {
+		if feature == -1 {
+			permuted.rows[row] = c.rows[from]
+			continue
+		}
+		permutedRow := make([]byte, c.rowBytes)
+		copy(permutedRow, c.rows[row])
+		copy(permutedRow[feature*4:(feature+1)*4], c.rows[from][feature*4:(feature+1)*4])
+		permuted.rows[row] = permutedRow
+	}
+
+	return permuted
+}
+
+func (f *FeatureImportance) setStatistics(confidence float64) {
+	total := float64(0)
+	for _, drop := range f.Drops {
+		total += drop
+	}
+	f.Importance = total / float64(len(f.Drops))
+
+	if len(f.Drops) > 1 {
+		squares := float64(0)
+		for _, drop := range f.Drops {
+			squares += (drop - f.Importance) * (drop - f.Importance)
+		}
+		f.StandardDeviation = math.Sqrt(squares / float64(len(f.Drops)-1))
+	}
+
+	margin := math.Sqrt2 * math.Erfinv(confidence) * f.StandardDeviation / math.Sqrt(float64(len(f.Drops)))
+	f.LowerBound = f.Importance - margin
+	f.UpperBound = f.Importance + margin
+}
@@ -7,6 +7,10 @@ import (
 )
 
 func main() {
+	commonContent, e := ioutil.ReadFile("../model/common_model.py")
+	if e != nil {
+		panic(e)
+	}
 	tfkgContent, e := ioutil.ReadFile("../model/tfkg_model.py")
 	if e != nil {
 		panic(e)
@@ -21,15 +25,18 @@ import (
 	"strings"
 )
 
-// This code is generated automatically using "go generate ./..." from model/tfkg_model.py. DO NOT EDIT manually.
+// This code is generated automatically using "go generate ./..." from model/common_model.py, model/tfkg_model.py and
+// model/vanilla_model.py. DO NOT EDIT manually.
+const pythonCommonCode = %s%s%s
+
 func GetTfkgPythonCode(customDefinitions []string) string {
-	return strings.ReplaceAll(%s%s%s, "# tfkg-custom-definitions", strings.Join(customDefinitions, "\n"))
+	return strings.ReplaceAll(pythonCommonCode+"\n\n"+%s%s%s, "# tfkg-custom-definitions", strings.Join(customDefinitions, "\n"))
 }
 
-func GetVanillaPythonCode() string {
-	return %s%s%s
+func GetVanillaPythonCode(customDefinitions []string) string {
+	return strings.ReplaceAll(pythonCommonCode+"\n\n"+%s%s%s, "# tfkg-custom-definitions", strings.Join(customDefinitions, "\n"))
 }
-`, "`", string(tfkgContent), "`", "`", string(vanillaContent), "`")), os.ModePerm)
+`, "`", string(commonContent), "`", "`", string(tfkgContent), "`", "`", string(vanillaContent), "`")), os.ModePerm)
 	if e != nil {
 		panic(e)
 	}
@@ -34,46 +34,53 @@ func main() {
 	if e != nil {
 		panic(e)
 	}
+	registries := make(map[string][]*fileGenerator)
+	var registryDirs []string
 	for _, object := range objects {
 		fmt.Println(object.Type, object.Name)
+		var f *fileGenerator
 		if object.Type == "optimizer" {
-			f := newFileGenerator(
+			f = newFileGenerator(
 				object,
 				"../../optimizer",
 				&parameter{
 					Name: "name",
 				},
 			)
-			f.generate()
+		} else if object.Type == "loss" {
+			f = newFileGenerator(
+				object,
+				"../../loss",
+				&parameter{
+					Name: "name",
+				},
+			)
 		} else if object.Type == "initializer" {
-			f := newFileGenerator(
+			f = newFileGenerator(
 				object,
 				"../../layer/initializer",
 				&parameter{
 					Name: "name",
 				},
 			)
-			f.generate()
 		} else if object.Type == "regularizer" {
-			f := newFileGenerator(
+			f = newFileGenerator(
 				object,
 				"../../layer/regularizer",
 				&parameter{
 					Name: "name",
 				},
 			)
-			f.generate()
 		} else if object.Type == "constraint" {
-			f := newFileGenerator(
+			f = newFileGenerator(
 				object,
 				"../../layer/constraint",
 				&parameter{
 					Name: "name",
 				},
 			)
-			f.generate()
 		} else if object.Type == "layer" {
-			f := newFileGenerator(
+			f = newFileGenerator(
 				object,
 				"../../layer",
 				&parameter{
@@ -88,14 +95,30 @@ func main() {
 					Name: "name",
 				},
 			)
-			f.generate()
 		}
+		if f == nil {
+			continue
+		}
+		f.generate()
+		if _, ok := registries[f.Dir]; !ok {
+			registryDirs = append(registryDirs, f.Dir)
+		}
+		registries[f.Dir] = append(registries[f.Dir], f)
+	}
+
+	// The registries make every generated object addressable by its keras class name, E.G. for model.FromConfig
+	for _, dir := range registryDirs {
+		generateRegistry(dir, registries[dir])
 	}
 
 	_, e = exec.Command("go", "fmt", "github.com/codingbeard/tfkg/optimizer").Output()
 	if e != nil {
 		panic(e)
 	}
+	_, e = exec.Command("go", "fmt", "github.com/codingbeard/tfkg/loss").Output()
+	if e != nil {
+		panic(e)
+	}
 	_, e = exec.Command("go", "fmt", "github.com/codingbeard/tfkg/layer/initializer").Output()
 	if e != nil {
 		panic(e)
@@ -349,6 +372,10 @@ func (f *fileGenerator) generate() {
 				strings.HasSuffix(param.Name, "regularizer") {
 				getter += ".GetKerasLayerConfig()"
 			}
+			if f.object.Type == "optimizer" && param.Name == "learning_rate" {
+				configLines = append(configLines, "\t\"learning_rate\": learningRate,")
+				continue
+			}
 			configLines = append(configLines, fmt.Sprintf(
 				"\t\"%s\": %s.%s,",
 				param.Name,
@@ -358,6 +385,25 @@ func (f *fileGenerator) generate() {
 		}
 	}
 	configLines = append(configLines, "}")
+	if f.object.Type == "optimizer" {
+		objectProperties = append(objectProperties, "learningRateSchedule LearningRateSchedule")
+		options = append(options, f.getOptionString(&parameter{
+			ObjectName: structName,
+			Name:       "learningRateSchedule",
+			StringType: "LearningRateSchedule",
+		}))
+		for _, clippingOption := range []string{"clipnorm", "clipvalue", "global_clipnorm"} {
+			clippingParameter := &parameter{
+				ObjectName: structName,
+				Name:       clippingOption,
+				StringType: "float64",
+			}
+			objectProperties = append(objectProperties, fmt.Sprintf("%s float64", clippingParameter.getCamelCaseName()))
+			options = append(options, f.getOptionString(clippingParameter))
+		}
+		configLines[len(configLines)-1] = fmt.Sprintf("}, %s.clipnorm, %s.clipvalue, %s.globalClipnorm)", reciever, reciever, reciever)
+		configLines[0] = "addClippingConfig(" + configLines[0]
+	}
 	layerDefaultGetters := ""
 	if f.object.Type == "layer" {
 		objectProperties = append(objectProperties, "layerWeights []*tf.Tensor")
@@ -428,6 +474,13 @@ func (%s *%s) GetLayerWeights() []*tf.Tensor {
 			map[string]bool{},
 		})
 	}`, reciever)
+	}
+	if f.object.Type == "optimizer" {
+		inboundNodes = fmt.Sprintf(`	var learningRate interface{} = %s.learningRate
+	if %s.learningRateSchedule != nil {
+		learningRate = %s.learningRateSchedule.GetKerasLayerConfig()
+	}
+`, reciever, reciever, reciever)
 	}
 	inboundNodesSetter := ""
 	configInboundNodesDef := ""
@@ -527,6 +580,64 @@ func (%s *%s) GetLayerWeights() []*tf.Tensor {
 	}
 }
 
+type registryEntry struct {
+	className    string
+	functionName string
+	required     []string
+}
+
+// generateRegistry writes registry.go with the constructor of each object keyed by keras class name. Required are the
+// keras names of the constructor params, in the order of the constructor
+func generateRegistry(dir string, generators []*fileGenerator) {
+	var entries []registryEntry
+	packageName := ""
+	for _, f := range generators {
+		packageName = f.object.Type
+		entry := registryEntry{
+			className:    fmt.Sprint(f.object.Config["class_name"]),
+			functionName: f.object.Name,
+		}
+		for _, param := range f.Params {
+			if param.IsRequired {
+				entry.required = append(entry.required, param.Name)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	writeRegistry(dir, packageName, entries)
+}
+
+func writeRegistry(dir string, packageName string, entries []registryEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].className < entries[j].className
+	})
+
+	lines := []string{
+		"package " + packageName,
+		"",
+		"// Constructors are the generated objects keyed by keras class name",
+		"var Constructors = map[string]Constructor{",
+	}
+	for _, entry := range entries {
+		var required []string
+		for _, name := range entry.required {
+			required = append(required, fmt.Sprintf("%q", name))
+		}
+		lines = append(lines, fmt.Sprintf("\t%q: {", entry.className))
+		lines = append(lines, fmt.Sprintf("\t\tFunction: %s,", entry.functionName))
+		if len(required) > 0 {
+			lines = append(lines, fmt.Sprintf("\t\tRequired: []string{%s},", strings.Join(required, ", ")))
+		}
+		lines = append(lines, "\t},")
+	}
+	lines = append(lines, "}", "")
+
+	e := ioutil.WriteFile(filepath.Join(dir, "registry.go"), []byte(strings.Join(lines, "\n")), os.ModePerm)
+	if e != nil {
+		panic(e)
+	}
+}
+
 func snakeCaseToCamelCase(str string) string {
 	str = strings.ReplaceAll(str, "_", " ")
 	str = strings.Title(str)
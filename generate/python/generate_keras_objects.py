@@ -121,6 +121,18 @@ objects = [
     {"type": "optimizer", "class": k.optimizers.Nadam, "args": []},
     {"type": "optimizer", "class": k.optimizers.RMSprop, "args": []},
     {"type": "optimizer", "class": k.optimizers.SGD, "args": []},
+    {"type": "loss", "class": k.losses.BinaryCrossentropy, "args": []},
+    {"type": "loss", "class": k.losses.CategoricalCrossentropy, "args": []},
+    {"type": "loss", "class": k.losses.SparseCategoricalCrossentropy, "args": []},
+    {"type": "loss", "class": k.losses.MeanSquaredError, "args": []},
+    {"type": "loss", "class": k.losses.MeanAbsoluteError, "args": []},
+    {"type": "loss", "class": k.losses.Huber, "args": []},
+    {"type": "loss", "class": k.losses.LogCosh, "args": []},
+    {"type": "loss", "class": k.losses.Hinge, "args": []},
+    {"type": "loss", "class": k.losses.SquaredHinge, "args": []},
+    {"type": "loss", "class": k.losses.KLDivergence, "args": []},
+    {"type": "loss", "class": k.losses.CosineSimilarity, "args": []},
+    {"type": "loss", "class": k.losses.Poisson, "args": []},
 ]
 defaults = {
     "activation": "linear",
@@ -14,9 +14,12 @@ require (
 	github.com/codingbeard/cbweb v0.10.9
 	github.com/fasthttp/router v1.4.4
 	github.com/galeone/tensorflow/tensorflow/go v0.0.0-20210519172502-4018d721b591
+	github.com/golang/protobuf v1.5.2
 	github.com/karrick/godirwalk v1.16.1
+	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/remeh/sizedwaitgroup v1.0.0
 	github.com/valyala/fasthttp v1.31.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
 
 require (
@@ -26,14 +29,15 @@ require (
 	github.com/didip/tollbooth v1.0.0 // indirect
 	github.com/didip/tollbooth_fasthttp v0.0.0-20170910065828-cfa276ddefe2 // indirect
 	github.com/fatih/camelcase v1.0.0 // indirect
-	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/klauspost/compress v1.13.4 // indirect
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
 	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
 	github.com/savsgio/gotils v0.0.0-20210921075833-21a6215cb0e4 // indirect
 	github.com/twpayne/go-jsonstruct v0.0.0-20200905114252-a1027bf3a425 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a // indirect
+	golang.org/x/net v0.0.0-20210510120150-4163338589ed // indirect
+	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015 // indirect
+	golang.org/x/text v0.3.6 // indirect
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
 	google.golang.org/protobuf v1.26.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
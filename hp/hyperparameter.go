@@ -1 +1,261 @@
 package hp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Param is a hyperparameter in a search space. The value sampled for it is stored in Params under its name
+type Param interface {
+	GetName() string
+	sample(random *rand.Rand) interface{}
+	gridValues() []interface{}
+}
+
+// Int samples ints between Min and Max inclusive, in multiples of Step from Min. Step defaults to 1
+type Int struct {
+	Name string
+	Min  int
+	Max  int
+	Step int
+}
+
+func (p *Int) GetName() string {
+	return p.Name
+}
+
+func (p *Int) step() int {
+	if p.Step <= 0 {
+		return 1
+	}
+	return p.Step
+}
+
+func (p *Int) sample(random *rand.Rand) interface{} {
+	return p.Min + random.Intn((p.Max-p.Min)/p.step()+1)*p.step()
+}
+
+func (p *Int) gridValues() []interface{} {
+	var values []interface{}
+	for value := p.Min; value <= p.Max; value += p.step() {
+		values = append(values, value)
+	}
+	return values
+}
+
+// fromFloat rounds a value to the nearest step between Min and Max
+func (p *Int) fromFloat(value float64) int {
+	steps := math.Round((value - float64(p.Min)) / float64(p.step()))
+	rounded := p.Min + int(steps)*p.step()
+	if rounded < p.Min {
+		return p.Min
+	}
+	if rounded > p.Max {
+		return p.Min + (p.Max-p.Min)/p.step()*p.step()
+	}
+	return rounded
+}
+
+// Float samples float64s uniformly between Min and Max. GridPoints is the number of evenly spaced values used by the
+// GridSampler, defaults to 5
+type Float struct {
+	Name       string
+	Min        float64
+	Max        float64
+	GridPoints int
+}
+
+func (p *Float) GetName() string {
+	return p.Name
+}
+
+func (p *Float) sample(random *rand.Rand) interface{} {
+	return p.Min + random.Float64()*(p.Max-p.Min)
+}
+
+func (p *Float) gridValues() []interface{} {
+	var values []interface{}
+	for _, value := range linearSpace(p.Min, p.Max, p.GridPoints) {
+		values = append(values, value)
+	}
+	return values
+}
+
+// LogUniform samples float64s between Min and Max uniformly in log space, E.G. learning rates. Min must be above 0.
+// GridPoints is the number of log spaced values used by the GridSampler, defaults to 5
+type LogUniform struct {
+	Name       string
+	Min        float64
+	Max        float64
+	GridPoints int
+}
+
+func (p *LogUniform) GetName() string {
+	return p.Name
+}
+
+func (p *LogUniform) sample(random *rand.Rand) interface{} {
+	return math.Exp(math.Log(p.Min) + random.Float64()*(math.Log(p.Max)-math.Log(p.Min)))
+}
+
+func (p *LogUniform) gridValues() []interface{} {
+	var values []interface{}
+	for _, value := range linearSpace(math.Log(p.Min), math.Log(p.Max), p.GridPoints) {
+		values = append(values, math.Exp(value))
+	}
+	return values
+}
+
+// Choice samples one of Values, which should be ints, float64s, strings or bools so they survive the JSON study state
+type Choice struct {
+	Name   string
+	Values []interface{}
+}
+
+func (p *Choice) GetName() string {
+	return p.Name
+}
+
+func (p *Choice) sample(random *rand.Rand) interface{} {
+	return p.Values[random.Intn(len(p.Values))]
+}
+
+func (p *Choice) gridValues() []interface{} {
+	return p.Values
+}
+
+// Conditional only samples Params when the value sampled for Parent is one of Values, E.G. the number of LSTM units
+// only when the layer type is "lstm". Parent must come before the Conditional in the search space
+type Conditional struct {
+	Parent string
+	Values []interface{}
+	Params []Param
+}
+
+func (p *Conditional) GetName() string {
+	return p.Parent
+}
+
+func (p *Conditional) sample(random *rand.Rand) interface{} {
+	return nil
+}
+
+func (p *Conditional) gridValues() []interface{} {
+	return nil
+}
+
+func (p *Conditional) isActive(params Params) bool {
+	parentValue, ok := params[p.Parent]
+	if !ok {
+		return false
+	}
+	for _, value := range p.Values {
+		if sameValue(parentValue, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Params are the values sampled for a trial, keyed by the name of each Param. Params inside an inactive Conditional
+// are missing
+type Params map[string]interface{}
+
+func (p Params) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+func (p Params) GetInt(name string) int {
+	value, _ := toFloat(p[name])
+	return int(math.Round(value))
+}
+
+func (p Params) GetFloat(name string) float64 {
+	value, _ := toFloat(p[name])
+	return value
+}
+
+func (p Params) GetString(name string) string {
+	value, ok := p[name].(string)
+	if !ok {
+		return fmt.Sprint(p[name])
+	}
+	return value
+}
+
+func (p Params) GetBool(name string) bool {
+	value, _ := p[name].(bool)
+	return value
+}
+
+func (p Params) copy() Params {
+	params := make(Params)
+	for name, value := range p {
+		params[name] = value
+	}
+	return params
+}
+
+// walkSpace calls choose for each Param which is active given the values chosen before it
+func walkSpace(space []Param, params Params, choose func(param Param) (interface{}, error)) error {
+	for _, param := range space {
+		if conditional, ok := param.(*Conditional); ok {
+			if !conditional.isActive(params) {
+				continue
+			}
+			e := walkSpace(conditional.Params, params, choose)
+			if e != nil {
+				return e
+			}
+			continue
+		}
+		value, e := choose(param)
+		if e != nil {
+			return e
+		}
+		params[param.GetName()] = value
+	}
+	return nil
+}
+
+func linearSpace(min float64, max float64, points int) []float64 {
+	if points <= 0 {
+		points = 5
+	}
+	if points == 1 {
+		return []float64{min}
+	}
+	var values []float64
+	for point := 0; point < points; point++ {
+		values = append(values, min+(max-min)*float64(point)/float64(points-1))
+	}
+	return values
+}
+
+// toFloat reads numbers sampled in this process as well as the float64s they become in the JSON study state
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case float32:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
+
+func sameValue(a interface{}, b interface{}) bool {
+	aFloat, aIsNumber := toFloat(a)
+	bFloat, bIsNumber := toFloat(b)
+	if aIsNumber && bIsNumber {
+		return aFloat == bFloat
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
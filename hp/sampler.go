@@ -0,0 +1,83 @@
+package hp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// ErrSearchComplete is returned by a Sampler when there are no parameters left to try, E.G. every combination of the
+// GridSampler
+var ErrSearchComplete = errors.New("the search space has been exhausted")
+
+type Sampler interface {
+	// Sample returns the params of the next trial. Trials are every trial of the study so far, in order
+	Sample(space []Param, direction Direction, trials []Trial, random *rand.Rand) (Params, error)
+}
+
+// RandomSampler samples each Param independently
+type RandomSampler struct{}
+
+func (s *RandomSampler) Sample(space []Param, direction Direction, trials []Trial, random *rand.Rand) (Params, error) {
+	return sampleRandom(space, random)
+}
+
+func sampleRandom(space []Param, random *rand.Rand) (Params, error) {
+	params := make(Params)
+	e := walkSpace(space, params, func(param Param) (interface{}, error) {
+		return sampleParam(param, random)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	return params, nil
+}
+
+func sampleParam(param Param, random *rand.Rand) (interface{}, error) {
+	if choice, ok := param.(*Choice); ok && len(choice.Values) == 0 {
+		return nil, fmt.Errorf("no values for choice param: %s", choice.Name)
+	}
+	return param.sample(random), nil
+}
+
+// GridSampler tries every combination of the search space in order, then returns ErrSearchComplete. Float and
+// LogUniform params use their GridPoints
+type GridSampler struct {
+	combinations []Params
+}
+
+func (s *GridSampler) Sample(space []Param, direction Direction, trials []Trial, random *rand.Rand) (Params, error) {
+	if s.combinations == nil {
+		s.combinations = gridCombinations(space, make(Params))
+	}
+	if len(trials) >= len(s.combinations) {
+		return nil, ErrSearchComplete
+	}
+
+	return s.combinations[len(trials)].copy(), nil
+}
+
+func gridCombinations(space []Param, params Params) []Params {
+	if len(space) == 0 {
+		return []Params{params}
+	}
+	param := space[0]
+	rest := space[1:]
+
+	if conditional, ok := param.(*Conditional); ok {
+		if !conditional.isActive(params) {
+			return gridCombinations(rest, params)
+		}
+		return gridCombinations(append(append([]Param{}, conditional.Params...), rest...), params)
+	}
+
+	var combinations []Params
+	for _, value := range param.gridValues() {
+		combination := params.copy()
+		combination[param.GetName()] = value
+		combinations = append(combinations, gridCombinations(rest, combination)...)
+	}
+
+	return combinations
+}
@@ -0,0 +1,189 @@
+package hp
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestGridSamplerConditional(t *testing.T) {
+	space := []Param{
+		&Choice{Name: "layer", Values: []interface{}{"dense", "lstm"}},
+		&Conditional{
+			Parent: "layer",
+			Values: []interface{}{"lstm"},
+			Params: []Param{
+				&Int{Name: "units", Min: 32, Max: 64, Step: 32},
+			},
+		},
+		&Choice{Name: "dropout", Values: []interface{}{false, true}},
+	}
+
+	expected := []Params{
+		{"layer": "dense", "dropout": false},
+		{"layer": "dense", "dropout": true},
+		{"layer": "lstm", "units": 32, "dropout": false},
+		{"layer": "lstm", "units": 32, "dropout": true},
+		{"layer": "lstm", "units": 64, "dropout": false},
+		{"layer": "lstm", "units": 64, "dropout": true},
+	}
+
+	sampler := &GridSampler{}
+	random := rand.New(rand.NewSource(1))
+	var trials []Trial
+	for _, want := range expected {
+		params, e := sampler.Sample(space, DirectionMinimize, trials, random)
+		if e != nil {
+			t.Fatalf("unexpected error after %d trials: %s", len(trials), e)
+		}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("trial %d: expected %v, got %v", len(trials), want, params)
+		}
+		trials = append(trials, Trial{Number: len(trials), Params: params, State: TrialStateComplete})
+	}
+
+	_, e := sampler.Sample(space, DirectionMinimize, trials, random)
+	if !errors.Is(e, ErrSearchComplete) {
+		t.Errorf("expected ErrSearchComplete once every combination was tried, got: %v", e)
+	}
+}
+
+func TestGridCombinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		space    []Param
+		expected int
+	}{
+		{
+			name:     "empty space",
+			space:    nil,
+			expected: 1,
+		},
+		{
+			name: "int steps",
+			space: []Param{
+				&Int{Name: "a", Min: 1, Max: 10, Step: 3},
+			},
+			expected: 4,
+		},
+		{
+			name: "float default grid points",
+			space: []Param{
+				&Float{Name: "a", Min: 0, Max: 1},
+			},
+			expected: 5,
+		},
+		{
+			name: "log uniform grid points",
+			space: []Param{
+				&LogUniform{Name: "a", Min: 0.001, Max: 0.1, GridPoints: 3},
+			},
+			expected: 3,
+		},
+		{
+			name: "inactive conditional",
+			space: []Param{
+				&Choice{Name: "a", Values: []interface{}{1, 2}},
+				&Conditional{
+					Parent: "a",
+					Values: []interface{}{3},
+					Params: []Param{&Choice{Name: "b", Values: []interface{}{1, 2, 3}}},
+				},
+			},
+			expected: 2,
+		},
+		{
+			name: "nested conditionals",
+			space: []Param{
+				&Choice{Name: "a", Values: []interface{}{1, 2}},
+				&Conditional{
+					Parent: "a",
+					Values: []interface{}{2},
+					Params: []Param{
+						&Choice{Name: "b", Values: []interface{}{"x", "y"}},
+						&Conditional{
+							Parent: "b",
+							Values: []interface{}{"y"},
+							Params: []Param{&Choice{Name: "c", Values: []interface{}{1, 2, 3}}},
+						},
+					},
+				},
+			},
+			expected: 1 + 1 + 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			combinations := gridCombinations(test.space, make(Params))
+			if len(combinations) != test.expected {
+				t.Errorf("expected %d combinations, got %d: %v", test.expected, len(combinations), combinations)
+			}
+		})
+	}
+}
+
+func TestRandomSamplerConditional(t *testing.T) {
+	space := []Param{
+		&Choice{Name: "layer", Values: []interface{}{"dense", "lstm"}},
+		&Conditional{
+			Parent: "layer",
+			Values: []interface{}{"lstm"},
+			Params: []Param{
+				&Int{Name: "units", Min: 8, Max: 64, Step: 8},
+			},
+		},
+	}
+
+	sampler := &RandomSampler{}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		params, e := sampler.Sample(space, DirectionMinimize, nil, random)
+		if e != nil {
+			t.Fatal(e)
+		}
+		isLstm := params.GetString("layer") == "lstm"
+		if params.Has("units") != isLstm {
+			t.Fatalf("units should only be sampled for the lstm layer, got: %v", params)
+		}
+		if isLstm {
+			units := params.GetInt("units")
+			if units < 8 || units > 64 || units%8 != 0 {
+				t.Fatalf("units out of range: %d", units)
+			}
+		}
+	}
+}
+
+func TestSampleParamEmptyChoice(t *testing.T) {
+	_, e := sampleRandom([]Param{&Choice{Name: "a"}}, rand.New(rand.NewSource(1)))
+	if e == nil {
+		t.Error("expected an error for a choice with no values")
+	}
+}
+
+func TestIntFromFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    *Int
+		value    float64
+		expected int
+	}{
+		{name: "rounds down", param: &Int{Min: 0, Max: 10}, value: 4.4, expected: 4},
+		{name: "rounds up", param: &Int{Min: 0, Max: 10}, value: 4.6, expected: 5},
+		{name: "nearest step", param: &Int{Min: 1, Max: 10, Step: 3}, value: 5.4, expected: 4},
+		{name: "below min", param: &Int{Min: 2, Max: 10}, value: -5, expected: 2},
+		{name: "above max", param: &Int{Min: 2, Max: 10}, value: 20, expected: 10},
+		{name: "above the last step", param: &Int{Min: 1, Max: 10, Step: 4}, value: 10, expected: 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := test.param.fromFloat(test.value)
+			if value != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package hp
+
+import (
+	"fmt"
+	"github.com/codingbeard/tfkg/callback"
+	"github.com/codingbeard/tfkg/model"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type SchedulerMode string
+
+var (
+	// SchedulerModeASHA runs asynchronous successive halving: every trial starts at the first rung
+	SchedulerModeASHA SchedulerMode = "asha"
+	// SchedulerModeHyperband spreads the trials over brackets of successive halving which start at later rungs, from
+	// the most aggressive bracket to one which trains every trial for MaxEpochs
+	SchedulerModeHyperband SchedulerMode = "hyperband"
+)
+
+// SchedulerConfig stops weak trials early. Trials report MetricName to their PruningCallback at the end of each epoch,
+// at each rung (MinEpochs * ReductionFactor^n epochs) a trial continues if it is in the best 1/ReductionFactor of the
+// trials which reached the rung, otherwise it is checkpointed and paused. A paused trial is promoted once enough worse
+// trials reach its rung and continues from its checkpoint. Paused trials which are never promoted were pruned
+type SchedulerConfig struct {
+	Mode SchedulerMode
+	// MinEpochs is the number of epochs before the first rung, defaults to 1
+	MinEpochs int
+	// MaxEpochs is the number of epochs of a trial which passes every rung, use it as FitConfig.Epochs
+	MaxEpochs       int
+	ReductionFactor int
+	// MetricName is the log compared at each rung, E.G. "val_loss", using the Direction of the study
+	MetricName string
+	// OnMode is the mode of the end event the metric is read from, defaults to callback.ModeVal
+	OnMode callback.Mode
+}
+
+// PruningCallback reports the metric of a trial to the study, returning ActionSave and ActionHalt when the trial is
+// paused at a rung so it can be resumed from Trial.CheckpointDir
+type PruningCallback struct {
+	study *Study
+	trial *Trial
+}
+
+func (s *SchedulerConfig) setDefaults() error {
+	if s.Mode == "" {
+		s.Mode = SchedulerModeASHA
+	}
+	if s.Mode != SchedulerModeASHA && s.Mode != SchedulerModeHyperband {
+		return fmt.Errorf("unknown scheduler mode: %s", s.Mode)
+	}
+	if s.MinEpochs == 0 {
+		s.MinEpochs = 1
+	}
+	if s.ReductionFactor == 0 {
+		s.ReductionFactor = 3
+	}
+	if s.ReductionFactor < 2 {
+		return fmt.Errorf("the scheduler ReductionFactor must be at least 2, got: %d", s.ReductionFactor)
+	}
+	if s.MaxEpochs < s.MinEpochs {
+		return fmt.Errorf("the scheduler MaxEpochs (%d) must be at least MinEpochs (%d)", s.MaxEpochs, s.MinEpochs)
+	}
+	if s.MetricName == "" {
+		return fmt.Errorf("no MetricName set for the scheduler")
+	}
+	if s.OnMode == "" {
+		s.OnMode = callback.ModeVal
+	}
+	return nil
+}
+
+func (s *SchedulerConfig) brackets() int {
+	if s.Mode == SchedulerModeASHA {
+		return 1
+	}
+	brackets := 1
+	for epochs := s.MinEpochs * s.ReductionFactor; epochs <= s.MaxEpochs; epochs *= s.ReductionFactor {
+		brackets++
+	}
+	return brackets
+}
+
+// rungEpochs returns the epochs at which trials in the bracket are compared, a trial which passes the last rung
+// trains until MaxEpochs
+func (s *SchedulerConfig) rungEpochs(bracket int) []int {
+	epochs := s.MinEpochs
+	for b := 0; b < bracket; b++ {
+		epochs *= s.ReductionFactor
+	}
+	var rungs []int
+	for ; epochs < s.MaxEpochs; epochs *= s.ReductionFactor {
+		rungs = append(rungs, epochs)
+	}
+	return rungs
+}
+
+// promotable is true when the trial is in the best 1/ReductionFactor of the trials of its bracket which reached rung
+func (s *SchedulerConfig) promotable(direction Direction, trials []Trial, trial Trial, rung int) bool {
+	rungEpoch := s.rungEpochs(trial.Bracket)[rung]
+	type rungValue struct {
+		number int
+		value  float64
+	}
+	var values []rungValue
+	for _, other := range trials {
+		if other.Bracket != trial.Bracket {
+			continue
+		}
+		value, ok := other.Intermediate[rungEpoch]
+		if ok {
+			values = append(values, rungValue{number: other.Number, value: value})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return direction.better(values[i].value, values[j].value)
+	})
+
+	for offset := 0; offset < len(values)/s.ReductionFactor; offset++ {
+		if values[offset].number == trial.Number {
+			return true
+		}
+	}
+	return false
+}
+
+// nextPromotion returns the paused trial at the highest rung which can be promoted
+func (s *SchedulerConfig) nextPromotion(direction Direction, trials []Trial) (Trial, bool) {
+	var paused []Trial
+	for _, trial := range trials {
+		if trial.State == TrialStatePaused {
+			paused = append(paused, trial)
+		}
+	}
+	sort.SliceStable(paused, func(i, j int) bool {
+		return paused[i].Rung > paused[j].Rung
+	})
+
+	for _, trial := range paused {
+		if s.promotable(direction, trials, trial, trial.Rung) {
+			return trial, true
+		}
+	}
+	return Trial{}, false
+}
+
+// CheckpointDir is where the PruningCallback saves the model when the trial is paused, resume it with
+// model.ResumeTraining when Resume is set
+func (t *Trial) CheckpointDir() string {
+	return filepath.Join(t.Dir, "checkpoint")
+}
+
+func (t *Trial) hasCheckpoint() bool {
+	_, e := model.LoadTrainingState(t.CheckpointDir())
+	return e == nil
+}
+
+// LastValue returns the last value reported to the PruningCallback, which can be returned from the Objective of a
+// resumed trial
+func (t *Trial) LastValue() float64 {
+	lastEpoch := -1
+	value := float64(0)
+	for epoch, epochValue := range t.Intermediate {
+		if epoch > lastEpoch {
+			lastEpoch = epoch
+			value = epochValue
+		}
+	}
+	return value
+}
+
+// PruningCallback returns the callback which reports the metric of the trial to the study, add it to the FitConfig
+// callbacks of the trial. Without a scheduler it does nothing
+func (t *Trial) PruningCallback() *PruningCallback {
+	return &PruningCallback{
+		study: t.study,
+		trial: t,
+	}
+}
+
+func (c *PruningCallback) GetSaveDir() string {
+	return c.trial.CheckpointDir()
+}
+
+// ActsPerBatch is false as the trial is only reported at the end of an epoch
+func (c *PruningCallback) ActsPerBatch() bool {
+	return false
+}
+
+func (c *PruningCallback) Init() error {
+	if c.study == nil {
+		return fmt.Errorf("the pruning callback was not created by a study trial")
+	}
+	return nil
+}
+
+func (c *PruningCallback) Call(
+	event callback.Event,
+	mode callback.Mode,
+	epoch int,
+	batch int,
+	logs []callback.Log,
+) ([]callback.Action, error) {
+	scheduler := c.study.config.Scheduler
+	onMode := callback.ModeVal
+	metricName := ""
+	if scheduler != nil {
+		onMode = scheduler.OnMode
+		metricName = scheduler.MetricName
+	}
+	if event != callback.EventEnd || mode != onMode || metricName == "" {
+		return []callback.Action{callback.ActionNop}, nil
+	}
+
+	found := false
+	var metricValue float64
+	for _, log := range logs {
+		if strings.ToLower(log.Name) == strings.ToLower(metricName) {
+			metricValue = log.Value
+			found = true
+		}
+	}
+	if !found {
+		return []callback.Action{callback.ActionNop}, fmt.Errorf("metric %s does not exist for the model", metricName)
+	}
+	if math.IsNaN(metricValue) {
+		return []callback.Action{callback.ActionNop}, fmt.Errorf("metric %s of trial %d is NaN", metricName, c.trial.Number)
+	}
+
+	return c.study.report(c.trial, epoch, metricValue)
+}
+
+// report records the value of the trial at the epoch and pauses it if it is not promotable at its next rung
+func (s *Study) report(trial *Trial, epoch int, value float64) ([]callback.Action, error) {
+	if trial.Intermediate == nil {
+		trial.Intermediate = make(map[int]float64)
+	}
+	trial.Intermediate[epoch] = value
+	trial.Value = value
+	s.state.Trials[trial.Number] = *trial
+
+	actions := []callback.Action{callback.ActionNop}
+	scheduler := s.config.Scheduler
+	rungs := scheduler.rungEpochs(trial.Bracket)
+	if trial.Rung < len(rungs) && epoch >= rungs[trial.Rung] {
+		if scheduler.promotable(s.config.Direction, s.state.Trials, *trial, trial.Rung) {
+			trial.Rung++
+			s.logger.InfoF("hp", "Trial %d of study %s promoted at epoch %d with value: %f", trial.Number, s.config.Name, epoch, value)
+		} else {
+			trial.State = TrialStatePaused
+			actions = []callback.Action{callback.ActionSave, callback.ActionHalt}
+			s.logger.InfoF("hp", "Trial %d of study %s paused at epoch %d with value: %f", trial.Number, s.config.Name, epoch, value)
+		}
+		s.state.Trials[trial.Number] = *trial
+	}
+
+	e := s.saveState()
+	if e != nil {
+		return []callback.Action{callback.ActionNop}, e
+	}
+
+	return actions, nil
+}
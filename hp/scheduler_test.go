@@ -0,0 +1,242 @@
+package hp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchedulerRungEpochs(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler SchedulerConfig
+		bracket   int
+		expected  []int
+	}{
+		{
+			name:      "asha",
+			scheduler: SchedulerConfig{MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3},
+			expected:  []int{1, 3, 9},
+		},
+		{
+			name:      "max epochs between rungs",
+			scheduler: SchedulerConfig{MinEpochs: 2, MaxEpochs: 20, ReductionFactor: 2},
+			expected:  []int{2, 4, 8, 16},
+		},
+		{
+			name:      "later bracket",
+			scheduler: SchedulerConfig{MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3},
+			bracket:   2,
+			expected:  []int{9},
+		},
+		{
+			name:      "last bracket has no rungs",
+			scheduler: SchedulerConfig{MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3},
+			bracket:   3,
+			expected:  nil,
+		},
+		{
+			name:      "min epochs equal to max epochs",
+			scheduler: SchedulerConfig{MinEpochs: 5, MaxEpochs: 5, ReductionFactor: 3},
+			expected:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rungs := test.scheduler.rungEpochs(test.bracket)
+			if !reflect.DeepEqual(rungs, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, rungs)
+			}
+		})
+	}
+}
+
+func TestSchedulerBrackets(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler SchedulerConfig
+		expected  int
+	}{
+		{
+			name:      "asha",
+			scheduler: SchedulerConfig{Mode: SchedulerModeASHA, MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3},
+			expected:  1,
+		},
+		{
+			name:      "hyperband",
+			scheduler: SchedulerConfig{Mode: SchedulerModeHyperband, MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3},
+			expected:  4,
+		},
+		{
+			name:      "hyperband between rungs",
+			scheduler: SchedulerConfig{Mode: SchedulerModeHyperband, MinEpochs: 1, MaxEpochs: 20, ReductionFactor: 3},
+			expected:  3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			brackets := test.scheduler.brackets()
+			if brackets != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, brackets)
+			}
+		})
+	}
+}
+
+func TestSchedulerPromotable(t *testing.T) {
+	scheduler := SchedulerConfig{MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3}
+	trialsWithValues := func(bracket int, values ...float64) []Trial {
+		var trials []Trial
+		for number, value := range values {
+			trials = append(trials, Trial{
+				Number:       number,
+				Bracket:      bracket,
+				Intermediate: map[int]float64{1: value},
+			})
+		}
+		return trials
+	}
+
+	tests := []struct {
+		name      string
+		direction Direction
+		trials    []Trial
+		trial     int
+		expected  bool
+	}{
+		{
+			name:      "alone at the rung",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.1),
+			trial:     0,
+			expected:  false,
+		},
+		{
+			name:      "best of two",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.1, 0.2),
+			trial:     0,
+			expected:  false,
+		},
+		{
+			name:      "best of three",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.3, 0.1, 0.2),
+			trial:     1,
+			expected:  true,
+		},
+		{
+			name:      "second of three",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.3, 0.1, 0.2),
+			trial:     2,
+			expected:  false,
+		},
+		{
+			name:      "best of three maximizing",
+			direction: DirectionMaximize,
+			trials:    trialsWithValues(0, 0.3, 0.1, 0.2),
+			trial:     0,
+			expected:  true,
+		},
+		{
+			name:      "second of six",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.6, 0.5, 0.4, 0.3, 0.2, 0.1),
+			trial:     4,
+			expected:  true,
+		},
+		{
+			name:      "third of six",
+			direction: DirectionMinimize,
+			trials:    trialsWithValues(0, 0.6, 0.5, 0.4, 0.3, 0.2, 0.1),
+			trial:     3,
+			expected:  false,
+		},
+		{
+			name:      "other brackets are ignored",
+			direction: DirectionMinimize,
+			trials: append(
+				trialsWithValues(0, 0.1, 0.2),
+				Trial{Number: 2, Bracket: 1, Intermediate: map[int]float64{1: 0.3}},
+			),
+			trial:    0,
+			expected: false,
+		},
+		{
+			name:      "trials which did not reach the rung are ignored",
+			direction: DirectionMinimize,
+			trials: append(
+				trialsWithValues(0, 0.1, 0.2),
+				Trial{Number: 2, Intermediate: map[int]float64{0: 0.3}},
+			),
+			trial:    0,
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			promotable := scheduler.promotable(test.direction, test.trials, test.trials[test.trial], 0)
+			if promotable != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, promotable)
+			}
+		})
+	}
+}
+
+func TestSchedulerNextPromotion(t *testing.T) {
+	scheduler := SchedulerConfig{MinEpochs: 1, MaxEpochs: 27, ReductionFactor: 3}
+	trials := []Trial{
+		{Number: 0, State: TrialStatePaused, Rung: 0, Intermediate: map[int]float64{1: 0.1}},
+		{Number: 1, State: TrialStateComplete, Rung: 1, Intermediate: map[int]float64{1: 0.2, 3: 0.4}},
+		{Number: 2, State: TrialStatePaused, Rung: 1, Intermediate: map[int]float64{1: 0.3, 3: 0.1}},
+		{Number: 3, State: TrialStateRunning, Rung: 1, Intermediate: map[int]float64{1: 0.4, 3: 0.5}},
+		{Number: 4, State: TrialStateComplete, Rung: 0, Intermediate: map[int]float64{1: 0.5}},
+		{Number: 5, State: TrialStateComplete, Rung: 0, Intermediate: map[int]float64{1: 0.6}},
+	}
+
+	trial, ok := scheduler.nextPromotion(DirectionMinimize, trials)
+	if !ok {
+		t.Fatal("expected a trial to be promoted")
+	}
+	if trial.Number != 2 {
+		t.Errorf("expected the paused trial at the highest rung to be promoted, got trial %d", trial.Number)
+	}
+
+	trials[2].State = TrialStateComplete
+	trial, ok = scheduler.nextPromotion(DirectionMinimize, trials)
+	if !ok || trial.Number != 0 {
+		t.Errorf("expected trial 0 to be promoted, got trial %d (%t)", trial.Number, ok)
+	}
+
+	trials[0].State = TrialStateComplete
+	_, ok = scheduler.nextPromotion(DirectionMinimize, trials)
+	if ok {
+		t.Error("expected no promotion without paused trials")
+	}
+}
+
+func TestSchedulerSetDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheduler SchedulerConfig
+		valid     bool
+	}{
+		{name: "defaults", scheduler: SchedulerConfig{MaxEpochs: 9, MetricName: "val_loss"}, valid: true},
+		{name: "unknown mode", scheduler: SchedulerConfig{Mode: "median", MaxEpochs: 9, MetricName: "val_loss"}},
+		{name: "reduction factor of one", scheduler: SchedulerConfig{ReductionFactor: 1, MaxEpochs: 9, MetricName: "val_loss"}},
+		{name: "max epochs below min epochs", scheduler: SchedulerConfig{MinEpochs: 3, MaxEpochs: 2, MetricName: "val_loss"}},
+		{name: "no metric", scheduler: SchedulerConfig{MaxEpochs: 9}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := test.scheduler.setDefaults()
+			if (e == nil) != test.valid {
+				t.Errorf("expected valid %t, got error: %v", test.valid, e)
+			}
+		})
+	}
+}
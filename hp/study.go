@@ -0,0 +1,394 @@
+package hp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const trialParamsFileName = "trial-params.json"
+
+type Direction string
+
+var (
+	DirectionMinimize Direction = "minimize"
+	DirectionMaximize Direction = "maximize"
+)
+
+func (d Direction) better(value float64, than float64) bool {
+	if d == DirectionMaximize {
+		return value > than
+	}
+	return value < than
+}
+
+type TrialState string
+
+var (
+	// TrialStateRunning trials are re-run with the same params when a study is resumed
+	TrialStateRunning  TrialState = "running"
+	TrialStateComplete TrialState = "complete"
+	TrialStateFailed   TrialState = "failed"
+	// TrialStatePaused trials were stopped at a rung of the scheduler and are resumed if they are promoted
+	TrialStatePaused TrialState = "paused"
+)
+
+type Trial struct {
+	Number int        `json:"number"`
+	Params Params     `json:"params"`
+	State  TrialState `json:"state"`
+	Value  float64    `json:"value"`
+	Error  string     `json:"error,omitempty"`
+	// Dir is the log dir of the trial, use it for the training.log, callback.RecordStats and ModelInfoSaveDir of the
+	// model so the web metrics module shows each trial as a model
+	Dir      string    `json:"dir"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// MaxEpochs is the number of epochs to train the trial for when the study has a scheduler
+	MaxEpochs int `json:"max_epochs,omitempty"`
+	Bracket   int `json:"bracket,omitempty"`
+	// Rung is the number of rungs of the scheduler the trial has passed
+	Rung int `json:"rung,omitempty"`
+	// Resume is set when the trial should continue from the model saved in CheckpointDir with model.ResumeTraining
+	// instead of building a new model
+	Resume bool `json:"resume,omitempty"`
+	// Intermediate are the values reported to the PruningCallback keyed by epoch
+	Intermediate map[int]float64 `json:"intermediate,omitempty"`
+
+	study *Study
+}
+
+// Objective builds a model from the params of the trial, fits it and returns the value the study optimizes, E.G. the
+// last val_loss of the History. Pass ctx to FitContext so the study can be stopped
+type Objective func(ctx context.Context, trial *Trial) (float64, error)
+
+type StudyConfig struct {
+	Name string
+	// LogDir is where the study state and the log dir of each trial are created, E.G. "../../logs" for the web metrics
+	// module. Each trial gets a dir named <Name>-trial-<Number>
+	LogDir    string
+	Direction Direction
+	// Sampler defaults to a RandomSampler
+	Sampler Sampler
+	// Trials is the total number of trials of the study including those run before it was resumed, defaults to 20
+	Trials int
+	// Seed is combined with the number of each trial so a resumed study samples the same way
+	Seed int64
+	// Scheduler stops weak trials early with ASHA or Hyperband, the Objective must add the Trial.PruningCallback
+	Scheduler *SchedulerConfig
+}
+
+type Study struct {
+	errorHandler *cberrors.ErrorsContainer
+	logger       *cblog.Logger
+	config       StudyConfig
+	space        []Param
+	state        studyState
+}
+
+type studyState struct {
+	Name      string    `json:"name"`
+	Direction Direction `json:"direction"`
+	Trials    []Trial   `json:"trials"`
+}
+
+// NewStudy creates a study over the search space, or resumes the study with the same Name in LogDir
+func NewStudy(
+	errorHandler *cberrors.ErrorsContainer,
+	logger *cblog.Logger,
+	config StudyConfig,
+	space ...Param,
+) (*Study, error) {
+	if config.Name == "" {
+		e := fmt.Errorf("no Name set for the study")
+		errorHandler.Error(e)
+		return nil, e
+	}
+	if config.LogDir == "" {
+		e := fmt.Errorf("no LogDir set for the study")
+		errorHandler.Error(e)
+		return nil, e
+	}
+	if config.Direction == "" {
+		config.Direction = DirectionMinimize
+	}
+	if config.Direction != DirectionMinimize && config.Direction != DirectionMaximize {
+		e := fmt.Errorf("unknown study direction: %s", config.Direction)
+		errorHandler.Error(e)
+		return nil, e
+	}
+	if config.Sampler == nil {
+		config.Sampler = &RandomSampler{}
+	}
+	if config.Trials == 0 {
+		config.Trials = 20
+	}
+	if config.Scheduler != nil {
+		scheduler := *config.Scheduler
+		e := scheduler.setDefaults()
+		if e != nil {
+			errorHandler.Error(e)
+			return nil, e
+		}
+		config.Scheduler = &scheduler
+	}
+	if len(space) == 0 {
+		e := fmt.Errorf("no params in the search space of the study")
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	e := os.MkdirAll(config.LogDir, os.ModePerm)
+	if e != nil {
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	s := &Study{
+		errorHandler: errorHandler,
+		logger:       logger,
+		config:       config,
+		space:        space,
+		state: studyState{
+			Name:      config.Name,
+			Direction: config.Direction,
+		},
+	}
+
+	jsonBytes, e := ioutil.ReadFile(s.statePath())
+	if e == nil {
+		var state studyState
+		e = json.Unmarshal(jsonBytes, &state)
+		if e != nil {
+			errorHandler.Error(e)
+			return nil, e
+		}
+		if state.Direction != config.Direction {
+			e = fmt.Errorf("the study %s was started with the direction %s, got: %s", config.Name, state.Direction, config.Direction)
+			errorHandler.Error(e)
+			return nil, e
+		}
+		s.state = state
+		logger.InfoF("hp", "Resuming study %s with %d trials", config.Name, len(state.Trials))
+	} else if !os.IsNotExist(e) {
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	return s, nil
+}
+
+// Optimize runs trials until the study has Trials trials, errors are sent to the error handler
+func (s *Study) Optimize(objective Objective) {
+	_ = s.OptimizeContext(context.Background(), objective)
+}
+
+// OptimizeContext runs trials until the study has Trials trials or the sampler runs out of params. Trials which were
+// running when a previous run was killed are run again first. A failed trial does not stop the study, but cancelling
+// ctx does: the trial it interrupted is left running so it is run again when the study is resumed
+func (s *Study) OptimizeContext(ctx context.Context, objective Objective) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		trial, e := s.nextTrial()
+		if errors.Is(e, ErrSearchComplete) {
+			s.logger.InfoF("hp", "Study %s has run every trial in the search space", s.config.Name)
+			break
+		}
+		if e != nil {
+			return e
+		}
+		if trial == nil {
+			break
+		}
+
+		s.logger.InfoF("hp", "Starting trial %d of study %s with params: %v", trial.Number, s.config.Name, trial.Params)
+		trial.study = s
+		value, e := objective(ctx, trial)
+		if e != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if trial.State == TrialStatePaused {
+			// The value at the rung was recorded by the PruningCallback
+			trial.Resume = false
+			s.state.Trials[trial.Number] = *trial
+			e = s.saveState()
+			if e != nil {
+				return e
+			}
+			continue
+		}
+		if e == nil && (math.IsNaN(value) || math.IsInf(value, 0)) {
+			e = fmt.Errorf("the objective of trial %d returned: %f", trial.Number, value)
+		}
+
+		trial.Finished = time.Now()
+		if e != nil {
+			s.errorHandler.Error(e)
+			trial.State = TrialStateFailed
+			trial.Error = e.Error()
+			s.logger.InfoF("hp", "Trial %d of study %s failed: %s", trial.Number, s.config.Name, e.Error())
+		} else {
+			trial.State = TrialStateComplete
+			trial.Value = value
+			s.logger.InfoF("hp", "Trial %d of study %s finished with value: %f", trial.Number, s.config.Name, value)
+		}
+		s.state.Trials[trial.Number] = *trial
+
+		e = s.saveState()
+		if e != nil {
+			return e
+		}
+
+		best, ok := s.BestTrial()
+		if ok {
+			s.logger.InfoF("hp", "Best trial of study %s is %d with value: %f", s.config.Name, best.Number, best.Value)
+		}
+	}
+
+	return nil
+}
+
+// nextTrial returns an interrupted trial, a promoted trial, a new trial from the sampler, or nil when the study has
+// enough trials
+func (s *Study) nextTrial() (*Trial, error) {
+	for _, trial := range s.state.Trials {
+		if trial.State == TrialStateRunning {
+			s.logger.InfoF("hp", "Running interrupted trial %d of study %s again", trial.Number, s.config.Name)
+			return s.startTrial(trial)
+		}
+	}
+	if s.config.Scheduler != nil {
+		trial, ok := s.config.Scheduler.nextPromotion(s.config.Direction, s.state.Trials)
+		if ok {
+			trial.Rung++
+			s.logger.InfoF("hp", "Resuming promoted trial %d of study %s at rung %d", trial.Number, s.config.Name, trial.Rung)
+			return s.startTrial(trial)
+		}
+	}
+	if len(s.state.Trials) >= s.config.Trials {
+		return nil, nil
+	}
+
+	number := len(s.state.Trials)
+	random := rand.New(rand.NewSource(s.config.Seed + int64(number)))
+	params, e := s.config.Sampler.Sample(s.space, s.config.Direction, s.Trials(), random)
+	if errors.Is(e, ErrSearchComplete) {
+		return nil, e
+	}
+	if e != nil {
+		s.errorHandler.Error(e)
+		return nil, e
+	}
+
+	trial := Trial{
+		Number:  number,
+		Params:  params,
+		State:   TrialStateRunning,
+		Dir:     filepath.Join(s.config.LogDir, fmt.Sprintf("%s-trial-%d", s.config.Name, number)),
+		Started: time.Now(),
+	}
+	if s.config.Scheduler != nil {
+		trial.MaxEpochs = s.config.Scheduler.MaxEpochs
+		trial.Bracket = number % s.config.Scheduler.brackets()
+	}
+	e = os.MkdirAll(trial.Dir, os.ModePerm)
+	if e != nil {
+		s.errorHandler.Error(e)
+		return nil, e
+	}
+	jsonBytes, e := json.MarshalIndent(trial.Params, "", "  ")
+	if e != nil {
+		s.errorHandler.Error(e)
+		return nil, e
+	}
+	e = ioutil.WriteFile(filepath.Join(trial.Dir, trialParamsFileName), jsonBytes, os.ModePerm)
+	if e != nil {
+		s.errorHandler.Error(e)
+		return nil, e
+	}
+
+	s.state.Trials = append(s.state.Trials, trial)
+	e = s.saveState()
+	if e != nil {
+		return nil, e
+	}
+
+	return &trial, nil
+}
+
+// startTrial runs an interrupted or promoted trial from its checkpoint if it has one, otherwise from the start
+func (s *Study) startTrial(trial Trial) (*Trial, error) {
+	trial.State = TrialStateRunning
+	trial.Resume = trial.hasCheckpoint()
+	if !trial.Resume {
+		trial.Intermediate = nil
+	}
+	s.state.Trials[trial.Number] = trial
+
+	e := s.saveState()
+	if e != nil {
+		return nil, e
+	}
+
+	return &trial, nil
+}
+
+// Trials returns a copy of every trial of the study in order
+func (s *Study) Trials() []Trial {
+	return append([]Trial{}, s.state.Trials...)
+}
+
+// BestTrial returns the complete trial with the best value, false if no trial has completed
+func (s *Study) BestTrial() (Trial, bool) {
+	var best Trial
+	found := false
+	for _, trial := range s.state.Trials {
+		if trial.State != TrialStateComplete {
+			continue
+		}
+		if !found || s.config.Direction.better(trial.Value, best.Value) {
+			best = trial
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (s *Study) statePath() string {
+	return filepath.Join(s.config.LogDir, fmt.Sprintf("%s-study.json", s.config.Name))
+}
+
+// saveState writes to a temporary file first so a study killed while saving still has its previous state
+func (s *Study) saveState() error {
+	jsonBytes, e := json.MarshalIndent(s.state, "", "  ")
+	if e != nil {
+		s.errorHandler.Error(e)
+		return e
+	}
+
+	tempPath := s.statePath() + ".tmp"
+	e = ioutil.WriteFile(tempPath, jsonBytes, os.ModePerm)
+	if e != nil {
+		s.errorHandler.Error(e)
+		return e
+	}
+	e = os.Rename(tempPath, s.statePath())
+	if e != nil {
+		s.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
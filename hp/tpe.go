@@ -0,0 +1,264 @@
+package hp
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// TPESampler is a Tree-structured Parzen Estimator. The complete trials are split into the best Gamma fraction and the
+// rest, each param is modelled by a kernel density over both groups and the candidate most likely to be in the best
+// group is sampled. Params are modelled independently, Conditional params only use the trials they were active in
+type TPESampler struct {
+	// StartupTrials are sampled randomly before the densities are used, defaults to 10
+	StartupTrials int
+	// Gamma is the fraction of complete trials treated as good, defaults to 0.25
+	Gamma float64
+	// Candidates is the number of values drawn from the good density for each param, defaults to 24
+	Candidates int
+}
+
+type parzenEstimator struct {
+	means      []float64
+	sigma      float64
+	priorMean  float64
+	priorSigma float64
+	low        float64
+	high       float64
+}
+
+func (s *TPESampler) Sample(space []Param, direction Direction, trials []Trial, random *rand.Rand) (Params, error) {
+	if s.StartupTrials == 0 {
+		s.StartupTrials = 10
+	}
+	if s.Gamma == 0 {
+		s.Gamma = 0.25
+	}
+	if s.Candidates == 0 {
+		s.Candidates = 24
+	}
+
+	var complete []Trial
+	for _, trial := range trials {
+		if trial.State == TrialStateComplete {
+			complete = append(complete, trial)
+		}
+	}
+	if len(complete) < s.StartupTrials {
+		return sampleRandom(space, random)
+	}
+
+	sort.SliceStable(complete, func(i, j int) bool {
+		return direction.better(complete[i].Value, complete[j].Value)
+	})
+	goodCount := int(math.Ceil(s.Gamma * float64(len(complete))))
+	if goodCount < 1 {
+		goodCount = 1
+	}
+	good := complete[:goodCount]
+	bad := complete[goodCount:]
+
+	params := make(Params)
+	e := walkSpace(space, params, func(param Param) (interface{}, error) {
+		goodValues := paramValues(good, param.GetName())
+		badValues := paramValues(bad, param.GetName())
+		if len(goodValues) == 0 || len(badValues) == 0 {
+			return sampleParam(param, random)
+		}
+
+		switch typed := param.(type) {
+		case *Int:
+			value := s.sampleNumeric(goodValues, badValues, float64(typed.Min), float64(typed.Max), false, random)
+			return typed.fromFloat(value), nil
+		case *Float:
+			return s.sampleNumeric(goodValues, badValues, typed.Min, typed.Max, false, random), nil
+		case *LogUniform:
+			return s.sampleNumeric(goodValues, badValues, typed.Min, typed.Max, true, random), nil
+		case *Choice:
+			return s.sampleChoice(typed, goodValues, badValues, random)
+		}
+		return sampleParam(param, random)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	return params, nil
+}
+
+func paramValues(trials []Trial, name string) []interface{} {
+	var values []interface{}
+	for _, trial := range trials {
+		if value, ok := trial.Params[name]; ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (s *TPESampler) sampleNumeric(
+	goodValues []interface{},
+	badValues []interface{},
+	low float64,
+	high float64,
+	logScale bool,
+	random *rand.Rand,
+) float64 {
+	transform := func(value float64) float64 {
+		if logScale {
+			return math.Log(value)
+		}
+		return value
+	}
+	low = transform(low)
+	high = transform(high)
+
+	toObservations := func(values []interface{}) []float64 {
+		var observations []float64
+		for _, value := range values {
+			number, ok := toFloat(value)
+			if ok {
+				observations = append(observations, transform(number))
+			}
+		}
+		return observations
+	}
+	goodDensity := newParzenEstimator(toObservations(goodValues), low, high)
+	badDensity := newParzenEstimator(toObservations(badValues), low, high)
+
+	best := goodDensity.sample(random)
+	bestScore := goodDensity.logDensity(best) - badDensity.logDensity(best)
+	for candidate := 1; candidate < s.Candidates; candidate++ {
+		value := goodDensity.sample(random)
+		score := goodDensity.logDensity(value) - badDensity.logDensity(value)
+		if score > bestScore {
+			best = value
+			bestScore = score
+		}
+	}
+
+	if logScale {
+		return math.Exp(best)
+	}
+	return best
+}
+
+func (s *TPESampler) sampleChoice(
+	choice *Choice,
+	goodValues []interface{},
+	badValues []interface{},
+	random *rand.Rand,
+) (interface{}, error) {
+	if len(choice.Values) == 0 {
+		return sampleParam(choice, random)
+	}
+	// Each value starts with a count of 1 so values which have not been tried can still be sampled
+	weights := func(values []interface{}) []float64 {
+		counts := make([]float64, len(choice.Values))
+		total := float64(len(choice.Values))
+		for offset := range counts {
+			counts[offset] = 1
+		}
+		for _, value := range values {
+			for offset, choiceValue := range choice.Values {
+				if sameValue(value, choiceValue) {
+					counts[offset]++
+					total++
+					break
+				}
+			}
+		}
+		for offset := range counts {
+			counts[offset] /= total
+		}
+		return counts
+	}
+	goodWeights := weights(goodValues)
+	badWeights := weights(badValues)
+
+	best := -1
+	bestScore := math.Inf(-1)
+	for candidate := 0; candidate < s.Candidates; candidate++ {
+		offset := sampleWeighted(goodWeights, random)
+		score := math.Log(goodWeights[offset]) - math.Log(badWeights[offset])
+		if score > bestScore {
+			best = offset
+			bestScore = score
+		}
+	}
+
+	return choice.Values[best], nil
+}
+
+func sampleWeighted(weights []float64, random *rand.Rand) int {
+	target := random.Float64()
+	cumulative := float64(0)
+	for offset, weight := range weights {
+		cumulative += weight
+		if target < cumulative {
+			return offset
+		}
+	}
+	return len(weights) - 1
+}
+
+// newParzenEstimator places a gaussian kernel on each observation with a bandwidth from Scott's rule, plus a wide prior
+// kernel over the whole range
+func newParzenEstimator(observations []float64, low float64, high float64) *parzenEstimator {
+	spread := high - low
+	if spread <= 0 {
+		spread = 1
+	}
+
+	mean := float64(0)
+	for _, observation := range observations {
+		mean += observation
+	}
+	mean /= float64(len(observations))
+	variance := float64(0)
+	for _, observation := range observations {
+		variance += (observation - mean) * (observation - mean)
+	}
+	variance /= float64(len(observations))
+
+	sigma := 1.06 * math.Sqrt(variance) * math.Pow(float64(len(observations)), -0.2)
+	sigma = math.Max(sigma, spread/100)
+	sigma = math.Min(sigma, spread)
+
+	return &parzenEstimator{
+		means:      observations,
+		sigma:      sigma,
+		priorMean:  low + spread/2,
+		priorSigma: spread,
+		low:        low,
+		high:       high,
+	}
+}
+
+func (p *parzenEstimator) sample(random *rand.Rand) float64 {
+	component := random.Intn(len(p.means) + 1)
+	mean := p.priorMean
+	sigma := p.priorSigma
+	if component < len(p.means) {
+		mean = p.means[component]
+		sigma = p.sigma
+	}
+
+	value := mean + random.NormFloat64()*sigma
+	return math.Max(p.low, math.Min(p.high, value))
+}
+
+func (p *parzenEstimator) logDensity(value float64) float64 {
+	density := gaussianDensity(value, p.priorMean, p.priorSigma)
+	for _, mean := range p.means {
+		density += gaussianDensity(value, mean, p.sigma)
+	}
+	density /= float64(len(p.means) + 1)
+
+	return math.Log(math.Max(density, math.SmallestNonzeroFloat64))
+}
+
+func gaussianDensity(value float64, mean float64, sigma float64) float64 {
+	z := (value - mean) / sigma
+	return math.Exp(-0.5*z*z) / (sigma * math.Sqrt(2*math.Pi))
+}
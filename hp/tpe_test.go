@@ -0,0 +1,164 @@
+package hp
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func tpeTrials(count int, state TrialState, params func(number int) Params) []Trial {
+	var trials []Trial
+	for number := 0; number < count; number++ {
+		trials = append(trials, Trial{
+			Number: number,
+			Params: params(number),
+			State:  state,
+			Value:  float64(number),
+		})
+	}
+	return trials
+}
+
+func TestTPESamplerFallbacks(t *testing.T) {
+	space := []Param{
+		&Float{Name: "rate", Min: 0, Max: 1},
+		&Choice{Name: "layer", Values: []interface{}{"dense", "lstm"}},
+		&Conditional{
+			Parent: "layer",
+			Values: []interface{}{"lstm"},
+			Params: []Param{
+				&Int{Name: "units", Min: 8, Max: 64, Step: 8},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		trials []Trial
+	}{
+		{
+			name:   "no trials",
+			trials: nil,
+		},
+		{
+			name: "fewer complete trials than startup trials",
+			trials: tpeTrials(9, TrialStateComplete, func(number int) Params {
+				return Params{"rate": 0.5, "layer": "dense"}
+			}),
+		},
+		{
+			name: "only failed trials",
+			trials: tpeTrials(20, TrialStateFailed, func(number int) Params {
+				return Params{"rate": 0.5, "layer": "dense"}
+			}),
+		},
+		{
+			name: "conditional never active",
+			trials: tpeTrials(20, TrialStateComplete, func(number int) Params {
+				return Params{"rate": float64(number) / 20, "layer": "dense"}
+			}),
+		},
+		{
+			name: "values from the JSON study state",
+			trials: tpeTrials(20, TrialStateComplete, func(number int) Params {
+				return Params{"rate": float64(number) / 20, "layer": "lstm", "units": float64(8 * (number%8 + 1))}
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sampler := &TPESampler{}
+			random := rand.New(rand.NewSource(1))
+			for i := 0; i < 20; i++ {
+				params, e := sampler.Sample(space, DirectionMinimize, test.trials, random)
+				if e != nil {
+					t.Fatal(e)
+				}
+				rate := params.GetFloat("rate")
+				if rate < 0 || rate > 1 {
+					t.Fatalf("rate out of range: %f", rate)
+				}
+				layer := params.GetString("layer")
+				if layer != "dense" && layer != "lstm" {
+					t.Fatalf("unknown layer: %s", layer)
+				}
+				if params.Has("units") != (layer == "lstm") {
+					t.Fatalf("units should only be sampled for the lstm layer, got: %v", params)
+				}
+				if units, ok := params["units"]; ok {
+					if _, isInt := units.(int); !isInt {
+						t.Fatalf("units should be sampled as an int, got: %#v", units)
+					}
+					if params.GetInt("units") < 8 || params.GetInt("units") > 64 || params.GetInt("units")%8 != 0 {
+						t.Fatalf("units out of range: %d", params.GetInt("units"))
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("empty choice", func(t *testing.T) {
+		sampler := &TPESampler{StartupTrials: 1}
+		trials := tpeTrials(5, TrialStateComplete, func(number int) Params {
+			return Params{"a": "x"}
+		})
+		_, e := sampler.Sample([]Param{&Choice{Name: "a"}}, DirectionMinimize, trials, rand.New(rand.NewSource(1)))
+		if e == nil {
+			t.Error("expected an error for a choice with no values")
+		}
+	})
+}
+
+func TestTPESamplerPrefersGoodValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction Direction
+		low       bool
+	}{
+		{name: "minimize", direction: DirectionMinimize, low: true},
+		{name: "maximize", direction: DirectionMaximize, low: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := []Param{
+				&Float{Name: "x", Min: 0, Max: 10},
+				&Choice{Name: "c", Values: []interface{}{"low", "high"}},
+			}
+			// The value of each trial is x, so low values are better when minimizing
+			var trials []Trial
+			for number := 0; number < 40; number++ {
+				x := float64(number) / 4
+				c := "high"
+				if x < 5 {
+					c = "low"
+				}
+				trials = append(trials, Trial{
+					Number: number,
+					Params: Params{"x": x, "c": c},
+					State:  TrialStateComplete,
+					Value:  x,
+				})
+			}
+
+			sampler := &TPESampler{}
+			random := rand.New(rand.NewSource(1))
+			lowCount := 0
+			for i := 0; i < 50; i++ {
+				params, e := sampler.Sample(space, test.direction, trials, random)
+				if e != nil {
+					t.Fatal(e)
+				}
+				if params.GetFloat("x") < 5 && params.GetString("c") == "low" {
+					lowCount++
+				}
+			}
+			if test.low && lowCount < 40 {
+				t.Errorf("expected mostly low samples, got %d of 50", lowCount)
+			}
+			if !test.low && lowCount > 10 {
+				t.Errorf("expected mostly high samples, got %d low of 50", lowCount)
+			}
+		})
+	}
+}
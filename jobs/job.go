@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Status string
+
+var (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	// StatusCancelling jobs are running but a cancel was requested, the worker stops them at its next heartbeat
+	StatusCancelling Status = "cancelling"
+	StatusSucceeded  Status = "succeeded"
+	StatusFailed     Status = "failed"
+	StatusCancelled  Status = "cancelled"
+)
+
+const (
+	EnvJobId     = "TFKG_JOB_ID"
+	EnvJobLogDir = "TFKG_JOB_LOG_DIR"
+	EnvJobConfig = "TFKG_JOB_CONFIG"
+)
+
+type SubmitRequest struct {
+	// Name is the model name of the job, its logs are written to the <Name>-<Id> dir of the worker log dir
+	Name string `json:"name"`
+	// Config is the model.Config of the job as JSON, trained by the train command of the worker
+	Config json.RawMessage `json:"config"`
+	// MaxRetries is the number of times a failed job is queued again
+	MaxRetries int `json:"max_retries"`
+}
+
+type Job struct {
+	Id         string          `json:"id"`
+	Name       string          `json:"name"`
+	Config     json.RawMessage `json:"config"`
+	Status     Status          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	MaxRetries int             `json:"max_retries"`
+	Error      string          `json:"error,omitempty"`
+	Worker     string          `json:"worker,omitempty"`
+	LogDir     string          `json:"log_dir,omitempty"`
+	Created    time.Time       `json:"created"`
+	Started    time.Time       `json:"started"`
+	Finished   time.Time       `json:"finished"`
+	// Heartbeat is updated by the worker while the job runs, running jobs with an old heartbeat are queued again
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// Environment is how a command run by a worker finds its job
+type Environment struct {
+	Id string
+	// LogDir is the dir to save the model, logs and stats to, E.G. the RecordDir of callback.RecordStats. Output to
+	// stdout and stderr is written to training.log in it, so log to stdout rather than to that file
+	LogDir string
+	Config json.RawMessage
+}
+
+func (r SubmitRequest) validate() error {
+	var config map[string]interface{}
+	e := json.Unmarshal(r.Config, &config)
+	if e != nil || config == nil {
+		return fmt.Errorf("the config of the job must be a model config object")
+	}
+	if r.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative, got: %d", r.MaxRetries)
+	}
+	return nil
+}
+
+func newJobId() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// Done is true when the job will not be run again
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// CurrentEnvironment reads the job of a command run by a worker, false when it was not run by a worker
+func CurrentEnvironment() (Environment, bool, error) {
+	environment := Environment{
+		Id:     os.Getenv(EnvJobId),
+		LogDir: os.Getenv(EnvJobLogDir),
+	}
+	if environment.Id == "" {
+		return environment, false, nil
+	}
+
+	configPath := os.Getenv(EnvJobConfig)
+	if configPath != "" {
+		configBytes, e := ioutil.ReadFile(configPath)
+		if e != nil {
+			return environment, true, e
+		}
+		environment.Config = configBytes
+	}
+
+	return environment, true, nil
+}
+
+// UnmarshalConfig reads the Config of the job into v
+func (e Environment) UnmarshalConfig(v interface{}) error {
+	if len(e.Config) == 0 {
+		return fmt.Errorf("no config set for job %s", e.Id)
+	}
+	return json.Unmarshal(e.Config, v)
+}
@@ -0,0 +1,349 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	queueLockFileName = "queue.lock"
+	// A lock older than this was left by a killed process
+	queueLockStaleAfter = 30 * time.Second
+	queueLockTimeout    = 10 * time.Second
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobDone     = errors.New("job is done")
+	// ErrJobNotOwned is returned when a worker changes a job which is no longer running on it, E.G. because it was
+	// claimed by another worker after missing its heartbeats
+	ErrJobNotOwned = errors.New("job is not owned by the worker")
+)
+
+// Queue stores each job as a JSON file in a dir. Changes are made under a lock file so the web server and several
+// workers on one machine can share the dir
+type Queue struct {
+	dir string
+}
+
+func NewQueue(dir string) (*Queue, error) {
+	e := os.MkdirAll(dir, os.ModePerm)
+	if e != nil {
+		return nil, e
+	}
+	return &Queue{
+		dir: dir,
+	}, nil
+}
+
+func (q *Queue) Submit(request SubmitRequest) (Job, error) {
+	e := request.validate()
+	if e != nil {
+		return Job{}, e
+	}
+
+	job := Job{
+		Id:         newJobId(),
+		Name:       request.Name,
+		Config:     request.Config,
+		Status:     StatusQueued,
+		MaxRetries: request.MaxRetries,
+		Created:    time.Now(),
+	}
+	if job.Name == "" {
+		job.Name = "job"
+	}
+
+	e = q.withLock(func() error {
+		return q.write(job)
+	})
+	if e != nil {
+		return Job{}, e
+	}
+
+	return job, nil
+}
+
+func (q *Queue) Get(id string) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		return e
+	})
+	return job, e
+}
+
+// List returns every job in the order they were submitted
+func (q *Queue) List() ([]Job, error) {
+	var jobs []Job
+	e := q.withLock(func() error {
+		var e error
+		jobs, e = q.readAll()
+		return e
+	})
+	return jobs, e
+}
+
+// Cancel cancels a queued job, or asks the worker of a running job to stop it
+func (q *Queue) Cancel(id string) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		if e != nil {
+			return e
+		}
+		if job.Done() {
+			return fmt.Errorf("%w: job %s has already %s", ErrJobDone, id, job.Status)
+		}
+
+		if job.Status == StatusQueued {
+			job.Status = StatusCancelled
+			job.Finished = time.Now()
+		} else {
+			job.Status = StatusCancelling
+		}
+		return q.write(job)
+	})
+	return job, e
+}
+
+// Claim starts the oldest queued job on the worker, false if there are none. Running jobs without a heartbeat for
+// staleAfter are queued again first as their worker was killed
+func (q *Queue) Claim(worker string, staleAfter time.Duration) (Job, bool, error) {
+	var claimed Job
+	found := false
+	e := q.withLock(func() error {
+		jobs, e := q.readAll()
+		if e != nil {
+			return e
+		}
+
+		for offset, job := range jobs {
+			if (job.Status != StatusRunning && job.Status != StatusCancelling) || time.Since(job.Heartbeat) < staleAfter {
+				continue
+			}
+			job = q.finish(job, fmt.Errorf("worker %s stopped sending heartbeats", job.Worker))
+			e = q.write(job)
+			if e != nil {
+				return e
+			}
+			jobs[offset] = job
+		}
+
+		for _, job := range jobs {
+			if job.Status != StatusQueued {
+				continue
+			}
+			job.Status = StatusRunning
+			job.Attempts++
+			job.Worker = worker
+			job.Started = time.Now()
+			job.Heartbeat = job.Started
+			job.Finished = time.Time{}
+			e = q.write(job)
+			if e != nil {
+				return e
+			}
+			claimed = job
+			found = true
+			return nil
+		}
+		return nil
+	})
+	return claimed, found, e
+}
+
+// Heartbeat marks the job as alive and returns it, so the worker can see if it was cancelled
+func (q *Queue) Heartbeat(id string, worker string) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		if e != nil {
+			return e
+		}
+		if job.Worker != worker || (job.Status != StatusRunning && job.Status != StatusCancelling) {
+			return fmt.Errorf("%w: job %s is no longer running on worker %s", ErrJobNotOwned, id, worker)
+		}
+		job.Heartbeat = time.Now()
+		return q.write(job)
+	})
+	return job, e
+}
+
+// SetLogDir records where the worker writes the logs of the job
+func (q *Queue) SetLogDir(id string, logDir string) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		if e != nil {
+			return e
+		}
+		job.LogDir = logDir
+		return q.write(job)
+	})
+	return job, e
+}
+
+// Finish ends an attempt of a job. A failed job is queued again until it has been retried MaxRetries times
+func (q *Queue) Finish(id string, worker string, runError error) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		if e != nil {
+			return e
+		}
+		if job.Worker != worker || job.Done() {
+			return fmt.Errorf("%w: job %s is no longer running on worker %s", ErrJobNotOwned, id, worker)
+		}
+		job = q.finish(job, runError)
+		return q.write(job)
+	})
+	return job, e
+}
+
+// Requeue queues a job again when its worker was stopped while running it, without counting the attempt towards its
+// retries. A job which was being cancelled is cancelled
+func (q *Queue) Requeue(id string, worker string) (Job, error) {
+	var job Job
+	e := q.withLock(func() error {
+		var e error
+		job, e = q.read(id)
+		if e != nil {
+			return e
+		}
+		if job.Worker != worker || job.Done() {
+			return fmt.Errorf("%w: job %s is no longer running on worker %s", ErrJobNotOwned, id, worker)
+		}
+		if job.Status == StatusCancelling {
+			job.Status = StatusCancelled
+			job.Finished = time.Now()
+		} else {
+			job.Status = StatusQueued
+			job.Attempts--
+		}
+		return q.write(job)
+	})
+	return job, e
+}
+
+func (q *Queue) finish(job Job, runError error) Job {
+	job.Finished = time.Now()
+	job.Error = ""
+	if runError != nil {
+		job.Error = runError.Error()
+	}
+
+	if job.Status == StatusCancelling {
+		job.Status = StatusCancelled
+	} else if runError == nil {
+		job.Status = StatusSucceeded
+	} else if job.Attempts <= job.MaxRetries {
+		job.Status = StatusQueued
+	} else {
+		job.Status = StatusFailed
+	}
+
+	return job
+}
+
+func (q *Queue) path(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("%w: invalid job id: %s", ErrJobNotFound, id)
+	}
+	return filepath.Join(q.dir, id+".json"), nil
+}
+
+func (q *Queue) read(id string) (Job, error) {
+	var job Job
+	path, e := q.path(id)
+	if e != nil {
+		return job, e
+	}
+	jsonBytes, e := ioutil.ReadFile(path)
+	if os.IsNotExist(e) {
+		return job, ErrJobNotFound
+	}
+	if e != nil {
+		return job, e
+	}
+	e = json.Unmarshal(jsonBytes, &job)
+	return job, e
+}
+
+func (q *Queue) readAll() ([]Job, error) {
+	paths, e := filepath.Glob(filepath.Join(q.dir, "*.json"))
+	if e != nil {
+		return nil, e
+	}
+
+	var jobs []Job
+	for _, path := range paths {
+		job, e := q.read(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if e != nil {
+			return nil, e
+		}
+		jobs = append(jobs, job)
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Created.Before(jobs[j].Created)
+	})
+
+	return jobs, nil
+}
+
+// write saves to a temporary file first so a job is never read half written
+func (q *Queue) write(job Job) error {
+	path, e := q.path(job.Id)
+	if e != nil {
+		return e
+	}
+	jsonBytes, e := json.MarshalIndent(job, "", "  ")
+	if e != nil {
+		return e
+	}
+	tempPath := path + ".tmp"
+	e = ioutil.WriteFile(tempPath, jsonBytes, os.ModePerm)
+	if e != nil {
+		return e
+	}
+	return os.Rename(tempPath, path)
+}
+
+func (q *Queue) withLock(f func() error) error {
+	lockPath := filepath.Join(q.dir, queueLockFileName)
+	started := time.Now()
+	for {
+		lockFile, e := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.ModePerm)
+		if e == nil {
+			_ = lockFile.Close()
+			break
+		}
+		if !os.IsExist(e) {
+			return e
+		}
+		stat, statError := os.Stat(lockPath)
+		if statError == nil && time.Since(stat.ModTime()) > queueLockStaleAfter {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Since(started) > queueLockTimeout {
+			return fmt.Errorf("timed out waiting for the queue lock: %s", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer os.Remove(lockPath)
+
+	return f()
+}
@@ -0,0 +1,334 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	queue, e := NewQueue(t.TempDir())
+	if e != nil {
+		t.Fatal(e)
+	}
+	return queue
+}
+
+func submitTestJob(t *testing.T, queue *Queue, maxRetries int) Job {
+	t.Helper()
+	job, e := queue.Submit(SubmitRequest{
+		Name:       "test",
+		Config:     json.RawMessage(`{"layers": []}`),
+		MaxRetries: maxRetries,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	return job
+}
+
+func TestQueueSubmit(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SubmitRequest
+		valid   bool
+	}{
+		{name: "config object", request: SubmitRequest{Name: "a", Config: json.RawMessage(`{"layers": []}`)}, valid: true},
+		{name: "empty config object", request: SubmitRequest{Config: json.RawMessage(`{}`)}, valid: true},
+		{name: "no config", request: SubmitRequest{Name: "a"}},
+		{name: "null config", request: SubmitRequest{Config: json.RawMessage(`null`)}},
+		{name: "config list", request: SubmitRequest{Config: json.RawMessage(`[]`)}},
+		{name: "config string", request: SubmitRequest{Config: json.RawMessage(`"config.json"`)}},
+		{name: "negative retries", request: SubmitRequest{Config: json.RawMessage(`{}`), MaxRetries: -1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := newTestQueue(t)
+			job, e := queue.Submit(test.request)
+			if !test.valid {
+				if e == nil {
+					t.Errorf("expected an error, got %+v", job)
+				}
+				jobs, e := queue.List()
+				if e != nil {
+					t.Fatal(e)
+				}
+				if len(jobs) != 0 {
+					t.Errorf("expected no jobs to be queued, got %d", len(jobs))
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if job.Status != StatusQueued {
+				t.Errorf("expected the status %s, got %s", StatusQueued, job.Status)
+			}
+			if test.request.Name == "" && job.Name != "job" {
+				t.Errorf("expected the default name job, got %s", job.Name)
+			}
+			stored, e := queue.Get(job.Id)
+			if e != nil {
+				t.Fatal(e)
+			}
+			var config, storedConfig interface{}
+			_ = json.Unmarshal(test.request.Config, &config)
+			_ = json.Unmarshal(stored.Config, &storedConfig)
+			if stored.Id != job.Id || !reflect.DeepEqual(storedConfig, config) {
+				t.Errorf("expected the stored job %+v, got %+v", job, stored)
+			}
+		})
+	}
+}
+
+func TestQueueTransitions(t *testing.T) {
+	type step struct {
+		action string
+		// worker defaults to worker-1
+		worker  string
+		invalid bool
+		// err is the error invalid steps are expected to wrap, if set
+		err      error
+		status   Status
+		attempts int
+	}
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		steps      []step
+	}{
+		{
+			name: "succeeds",
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "heartbeat", status: StatusRunning, attempts: 1},
+				{action: "succeed", status: StatusSucceeded, attempts: 1},
+				{action: "cancel", invalid: true, status: StatusSucceeded, attempts: 1},
+				{action: "fail", invalid: true, status: StatusSucceeded, attempts: 1},
+				{action: "requeue", invalid: true, status: StatusSucceeded, attempts: 1},
+			},
+		},
+		{
+			name: "fails without retries",
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "fail", status: StatusFailed, attempts: 1},
+				{action: "claim", invalid: true, status: StatusFailed, attempts: 1},
+			},
+		},
+		{
+			name:       "retries then fails",
+			maxRetries: 1,
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "fail", status: StatusQueued, attempts: 1},
+				{action: "claim", status: StatusRunning, attempts: 2},
+				{action: "fail", status: StatusFailed, attempts: 2},
+			},
+		},
+		{
+			name:       "retries then succeeds",
+			maxRetries: 2,
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "fail", status: StatusQueued, attempts: 1},
+				{action: "claim", status: StatusRunning, attempts: 2},
+				{action: "succeed", status: StatusSucceeded, attempts: 2},
+			},
+		},
+		{
+			name: "cancelled while queued",
+			steps: []step{
+				{action: "cancel", status: StatusCancelled},
+				{action: "claim", invalid: true, status: StatusCancelled},
+				{action: "cancel", invalid: true, status: StatusCancelled},
+			},
+		},
+		{
+			name:       "cancelled while running",
+			maxRetries: 1,
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "cancel", status: StatusCancelling, attempts: 1},
+				{action: "heartbeat", status: StatusCancelling, attempts: 1},
+				{action: "fail", status: StatusCancelled, attempts: 1},
+			},
+		},
+		{
+			name: "requeued while running",
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "requeue", status: StatusQueued, attempts: 0},
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "succeed", status: StatusSucceeded, attempts: 1},
+			},
+		},
+		{
+			name: "requeued while cancelling",
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "cancel", status: StatusCancelling, attempts: 1},
+				{action: "requeue", status: StatusCancelled, attempts: 1},
+			},
+		},
+		{
+			name: "another worker",
+			steps: []step{
+				{action: "claim", status: StatusRunning, attempts: 1},
+				{action: "heartbeat", worker: "worker-2", invalid: true, err: ErrJobNotOwned, status: StatusRunning, attempts: 1},
+				{action: "succeed", worker: "worker-2", invalid: true, err: ErrJobNotOwned, status: StatusRunning, attempts: 1},
+				{action: "requeue", worker: "worker-2", invalid: true, err: ErrJobNotOwned, status: StatusRunning, attempts: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := newTestQueue(t)
+			job := submitTestJob(t, queue, test.maxRetries)
+
+			for offset, step := range test.steps {
+				worker := step.worker
+				if worker == "" {
+					worker = "worker-1"
+				}
+
+				var e error
+				switch step.action {
+				case "claim":
+					var claimed Job
+					var ok bool
+					claimed, ok, e = queue.Claim(worker, time.Hour)
+					if e == nil && !ok {
+						e = errors.New("no job claimed")
+					}
+					if e == nil && claimed.Id != job.Id {
+						t.Fatalf("step %d: claimed job %s instead of %s", offset, claimed.Id, job.Id)
+					}
+				case "heartbeat":
+					_, e = queue.Heartbeat(job.Id, worker)
+				case "succeed":
+					_, e = queue.Finish(job.Id, worker, nil)
+				case "fail":
+					_, e = queue.Finish(job.Id, worker, errors.New("training failed"))
+				case "cancel":
+					_, e = queue.Cancel(job.Id)
+				case "requeue":
+					_, e = queue.Requeue(job.Id, worker)
+				default:
+					t.Fatalf("unknown action: %s", step.action)
+				}
+				if step.invalid && e == nil {
+					t.Errorf("step %d: expected %s to fail", offset, step.action)
+				}
+				if step.err != nil && !errors.Is(e, step.err) {
+					t.Errorf("step %d: expected %s to fail with %v, got %v", offset, step.action, step.err, e)
+				}
+				if !step.invalid && e != nil {
+					t.Fatalf("step %d: %s: %s", offset, step.action, e)
+				}
+
+				stored, e := queue.Get(job.Id)
+				if e != nil {
+					t.Fatal(e)
+				}
+				if stored.Status != step.status {
+					t.Errorf("step %d: %s: expected the status %s, got %s", offset, step.action, step.status, stored.Status)
+				}
+				if stored.Attempts != step.attempts {
+					t.Errorf("step %d: %s: expected %d attempts, got %d", offset, step.action, step.attempts, stored.Attempts)
+				}
+				if stored.Done() && stored.Finished.IsZero() {
+					t.Errorf("step %d: %s: expected a finished time for a done job", offset, step.action)
+				}
+				if step.action == "fail" && !step.invalid && stored.Error == "" {
+					t.Errorf("step %d: expected the error to be recorded", offset)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueClaim(t *testing.T) {
+	t.Run("oldest first", func(t *testing.T) {
+		queue := newTestQueue(t)
+		first := submitTestJob(t, queue, 0)
+		second := submitTestJob(t, queue, 0)
+
+		for _, expected := range []Job{first, second} {
+			job, ok, e := queue.Claim("worker-1", time.Hour)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !ok || job.Id != expected.Id {
+				t.Errorf("expected to claim %s, got %s (%t)", expected.Id, job.Id, ok)
+			}
+		}
+
+		_, ok, e := queue.Claim("worker-1", time.Hour)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if ok {
+			t.Error("expected no job to claim")
+		}
+	})
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		status     Status
+		claimed    bool
+	}{
+		{name: "stale job is retried", maxRetries: 1, status: StatusRunning, claimed: true},
+		{name: "stale job fails", maxRetries: 0, status: StatusFailed, claimed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := newTestQueue(t)
+			job := submitTestJob(t, queue, test.maxRetries)
+			_, _, e := queue.Claim("worker-1", time.Hour)
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			// Every running job is stale when staleAfter is 0
+			claimed, ok, e := queue.Claim("worker-2", 0)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if ok != test.claimed {
+				t.Errorf("expected claimed %t, got %t", test.claimed, ok)
+			}
+			stored, e := queue.Get(job.Id)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if stored.Status != test.status {
+				t.Errorf("expected the status %s, got %s", test.status, stored.Status)
+			}
+			if stored.Error == "" {
+				t.Error("expected the missed heartbeats to be recorded as the error")
+			}
+			if ok && (claimed.Worker != "worker-2" || claimed.Attempts != 2) {
+				t.Errorf("expected worker-2 to claim the second attempt, got %s attempt %d", claimed.Worker, claimed.Attempts)
+			}
+		})
+	}
+}
+
+func TestQueueGetInvalidId(t *testing.T) {
+	queue := newTestQueue(t)
+	for _, id := range []string{"", "missing", "../queue", ".hidden"} {
+		_, e := queue.Get(id)
+		if !errors.Is(e, ErrJobNotFound) {
+			t.Errorf("expected ErrJobNotFound for id %q, got %v", id, e)
+		}
+	}
+}
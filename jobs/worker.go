@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+type WorkerConfig struct {
+	// Name identifies the worker in the jobs it runs, defaults to <hostname>-<pid>
+	Name string
+	// LogDir is where each job gets a <Name>-<Id> dir, E.G. the logs dir read by the web metrics module
+	LogDir string
+	// PollInterval is how often the queue is checked when it is empty, defaults to 5 seconds
+	PollInterval time.Duration
+	// HeartbeatInterval is how often a running job is marked alive and checked for cancellation, defaults to 5 seconds
+	HeartbeatInterval time.Duration
+	// StaleAfter is how long a running job of another worker can go without a heartbeat before it is queued again,
+	// defaults to 1 minute
+	StaleAfter time.Duration
+	// TrainCommand trains the config of a job, defaults to the train command of the running executable, E.G.
+	// ["tfkg", "train"]. Jobs only hold a config so the commands a worker runs are never taken from the web api
+	TrainCommand []string
+	// Dir is the working dir of the train command, relative paths in the job configs such as the dataset file_path are
+	// read from it. Defaults to the working dir of the worker
+	Dir string
+}
+
+type Worker struct {
+	errorHandler *cberrors.ErrorsContainer
+	logger       *cblog.Logger
+	queue        *Queue
+	config       WorkerConfig
+}
+
+func NewWorker(
+	errorHandler *cberrors.ErrorsContainer,
+	logger *cblog.Logger,
+	queue *Queue,
+	config WorkerConfig,
+) (*Worker, error) {
+	if config.LogDir == "" {
+		e := fmt.Errorf("no LogDir set for the worker")
+		errorHandler.Error(e)
+		return nil, e
+	}
+	if config.Name == "" {
+		hostname, e := os.Hostname()
+		if e != nil {
+			hostname = "worker"
+		}
+		config.Name = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.HeartbeatInterval == 0 {
+		config.HeartbeatInterval = 5 * time.Second
+	}
+	if config.StaleAfter == 0 {
+		config.StaleAfter = time.Minute
+	}
+	if len(config.TrainCommand) == 0 {
+		executable, e := os.Executable()
+		if e != nil {
+			errorHandler.Error(e)
+			return nil, e
+		}
+		config.TrainCommand = []string{executable, "train"}
+	}
+	if config.StaleAfter <= config.HeartbeatInterval {
+		e := fmt.Errorf("the worker StaleAfter (%s) must be longer than the HeartbeatInterval (%s)", config.StaleAfter, config.HeartbeatInterval)
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	return &Worker{
+		errorHandler: errorHandler,
+		logger:       logger,
+		queue:        queue,
+		config:       config,
+	}, nil
+}
+
+// Run claims and runs jobs one at a time until ctx is cancelled. Run several workers to train several jobs at once
+func (w *Worker) Run(ctx context.Context) error {
+	w.logger.InfoF("jobs", "Worker %s waiting for jobs", w.config.Name)
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		job, found, e := w.queue.Claim(w.config.Name, w.config.StaleAfter)
+		if e != nil {
+			w.errorHandler.Error(e)
+		}
+		if e != nil || !found {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(w.config.PollInterval):
+			}
+			continue
+		}
+
+		w.runJob(ctx, job)
+	}
+}
+
+func (w *Worker) runJob(ctx context.Context, job Job) {
+	w.logger.InfoF("jobs", "Worker %s starting attempt %d of job %s (%s)", w.config.Name, job.Attempts, job.Id, job.Name)
+
+	runError := w.runCommand(ctx, job)
+	if errors.Is(runError, ErrJobNotOwned) {
+		return
+	}
+	if ctx.Err() != nil && runError != nil {
+		requeued, e := w.queue.Requeue(job.Id, w.config.Name)
+		if e != nil {
+			w.errorHandler.Error(e)
+			return
+		}
+		w.logger.InfoF("jobs", "Worker %s was stopped during job %s, status: %s", w.config.Name, job.Id, requeued.Status)
+		return
+	}
+
+	finished, e := w.queue.Finish(job.Id, w.config.Name, runError)
+	if e != nil {
+		w.errorHandler.Error(e)
+		return
+	}
+	if runError != nil {
+		w.logger.InfoF("jobs", "Job %s attempt %d ended with: %s, status: %s", job.Id, job.Attempts, runError.Error(), finished.Status)
+		return
+	}
+	w.logger.InfoF("jobs", "Job %s %s", job.Id, finished.Status)
+}
+
+func (w *Worker) runCommand(ctx context.Context, job Job) error {
+	logDir := filepath.Join(w.config.LogDir, fmt.Sprintf("%s-%s", job.Name, job.Id))
+	e := os.MkdirAll(logDir, os.ModePerm)
+	if e != nil {
+		return e
+	}
+	_, e = w.queue.SetLogDir(job.Id, logDir)
+	if e != nil {
+		return e
+	}
+
+	logFile, e := os.OpenFile(filepath.Join(logDir, "training.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	if e != nil {
+		return e
+	}
+	defer logFile.Close()
+	_, _ = fmt.Fprintf(logFile, "Worker %s starting attempt %d of job %s\n", w.config.Name, job.Attempts, job.Id)
+
+	configPath := filepath.Join(logDir, "job-config.json")
+	e = ioutil.WriteFile(configPath, job.Config, os.ModePerm)
+	if e != nil {
+		return e
+	}
+
+	cmd := exec.Command(w.config.TrainCommand[0], w.config.TrainCommand[1:]...)
+	cmd.Dir = w.config.Dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(
+		os.Environ(),
+		fmt.Sprintf("%s=%s", EnvJobId, job.Id),
+		fmt.Sprintf("%s=%s", EnvJobLogDir, logDir),
+		fmt.Sprintf("%s=%s", EnvJobConfig, configPath),
+	)
+	setProcessGroup(cmd)
+
+	e = cmd.Start()
+	if e != nil {
+		return e
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(w.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e = <-done:
+			return e
+		case <-ctx.Done():
+			w.kill(cmd)
+			return <-done
+		case <-ticker.C:
+			current, e := w.queue.Heartbeat(job.Id, w.config.Name)
+			if errors.Is(e, ErrJobNotOwned) {
+				// Another worker may be running the job, so this attempt is abandoned without finishing it
+				w.logger.InfoF("jobs", "Worker %s no longer owns job %s, stopping it", w.config.Name, job.Id)
+				_, _ = fmt.Fprintf(logFile, "Worker %s no longer owns job %s\n", w.config.Name, job.Id)
+				w.kill(cmd)
+				<-done
+				return e
+			}
+			if e != nil {
+				w.errorHandler.Error(e)
+				continue
+			}
+			if current.Status == StatusCancelling {
+				w.logger.InfoF("jobs", "Cancelling job %s", job.Id)
+				_, _ = fmt.Fprintf(logFile, "Job %s was cancelled\n", job.Id)
+				w.kill(cmd)
+				return <-done
+			}
+		}
+	}
+}
+
+func (w *Worker) kill(cmd *exec.Cmd) {
+	e := killProcessGroup(cmd)
+	if e != nil {
+		w.errorHandler.Error(e)
+	}
+}
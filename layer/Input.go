@@ -41,6 +41,11 @@ func (i *LInput) SetInputShape(inputShape tf.Shape) *LInput {
 	return i
 }
 
+// SetBatchInputShape is SetInputShape under the name of the keras config key, so InputLayer configs can be loaded
+func (i *LInput) SetBatchInputShape(batchInputShape tf.Shape) *LInput {
+	return i.SetInputShape(batchInputShape)
+}
+
 func (i *LInput) SetBatchSize(batchSize float64) *LInput {
 	i.batchSize = batchSize
 	return i
@@ -9,3 +9,9 @@ type NilConstraint struct{}
 func (n *NilConstraint) GetKerasLayerConfig() interface{} {
 	return nil
 }
+
+// Constructor builds a constraint from its keras class name and config in the same way as layer.Constructor
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
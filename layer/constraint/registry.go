@@ -0,0 +1,20 @@
+package constraint
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"MaxNorm": {
+		Function: MaxNorm,
+	},
+	"MinMaxNorm": {
+		Function: MinMaxNorm,
+	},
+	"NonNeg": {
+		Function: NonNeg,
+	},
+	"RadialConstraint": {
+		Function: RadialConstraint,
+	},
+	"UnitNorm": {
+		Function: UnitNorm,
+	},
+}
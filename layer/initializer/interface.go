@@ -9,3 +9,9 @@ type NilInitializer struct{}
 func (n *NilInitializer) GetKerasLayerConfig() interface{} {
 	return nil
 }
+
+// Constructor builds an initializer from its keras class name and config in the same way as layer.Constructor
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
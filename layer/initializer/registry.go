@@ -0,0 +1,44 @@
+package initializer
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"Constant": {
+		Function: Constant,
+	},
+	"GlorotNormal": {
+		Function: GlorotNormal,
+	},
+	"GlorotUniform": {
+		Function: GlorotUniform,
+	},
+	"HeNormal": {
+		Function: HeNormal,
+	},
+	"HeUniform": {
+		Function: HeUniform,
+	},
+	"Identity": {
+		Function: Identity,
+	},
+	"Ones": {
+		Function: Ones,
+	},
+	"Orthogonal": {
+		Function: Orthogonal,
+	},
+	"RandomNormal": {
+		Function: RandomNormal,
+	},
+	"RandomUniform": {
+		Function: RandomUniform,
+	},
+	"TruncatedNormal": {
+		Function: TruncatedNormal,
+	},
+	"VarianceScaling": {
+		Function: VarianceScaling,
+	},
+	"Zeros": {
+		Function: Zeros,
+	},
+}
@@ -47,6 +47,24 @@ type Layer interface {
 	GetCustomLayerDefinition() string
 }
 
+// Constructor is how an object is built from its keras class name and config. Function is called with the values of
+// the Required keras config keys in order, the rest of the config is applied with the Set<Name> methods
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
+
+// The layers which are not generated
+func init() {
+	Constructors["InputLayer"] = Constructor{
+		Function: Input,
+	}
+	Constructors["GpuLSTM"] = Constructor{
+		Function: CuDNNLSTM,
+		Required: []string{"units"},
+	}
+}
+
 var uniqueNameCounts = make(map[string]int)
 
 func UniqueName(name string) string {
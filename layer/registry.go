@@ -0,0 +1,311 @@
+package layer
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"Activation": {
+		Function: Activation,
+		Required: []string{"activation"},
+	},
+	"ActivityRegularization": {
+		Function: ActivityRegularization,
+	},
+	"Add": {
+		Function: Add,
+	},
+	"AdditiveAttention": {
+		Function: AdditiveAttention,
+	},
+	"AlphaDropout": {
+		Function: AlphaDropout,
+		Required: []string{"rate"},
+	},
+	"Attention": {
+		Function: Attention,
+	},
+	"Average": {
+		Function: Average,
+	},
+	"AveragePooling1D": {
+		Function: AveragePooling1D,
+	},
+	"AveragePooling2D": {
+		Function: AveragePooling2D,
+	},
+	"AveragePooling3D": {
+		Function: AveragePooling3D,
+	},
+	"BatchNormalization": {
+		Function: BatchNormalization,
+	},
+	"Bidirectional": {
+		Function: Bidirectional,
+		Required: []string{"layer"},
+	},
+	"CategoryCrossing": {
+		Function: CategoryCrossing,
+	},
+	"CategoryEncoding": {
+		Function: CategoryEncoding,
+	},
+	"CenterCrop": {
+		Function: CenterCrop,
+		Required: []string{"height", "width"},
+	},
+	"Concatenate": {
+		Function: Concatenate,
+	},
+	"Conv1D": {
+		Function: Conv1D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"Conv2D": {
+		Function: Conv2D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"Conv2DTranspose": {
+		Function: Conv2DTranspose,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"Conv3D": {
+		Function: Conv3D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"Conv3DTranspose": {
+		Function: Conv3DTranspose,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"ConvLSTM2D": {
+		Function: ConvLSTM2D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"Cropping1D": {
+		Function: Cropping1D,
+	},
+	"Cropping2D": {
+		Function: Cropping2D,
+	},
+	"Cropping3D": {
+		Function: Cropping3D,
+	},
+	"Dense": {
+		Function: Dense,
+		Required: []string{"units"},
+	},
+	"DepthwiseConv2D": {
+		Function: DepthwiseConv2D,
+		Required: []string{"kernel_size"},
+	},
+	"Discretization": {
+		Function: Discretization,
+	},
+	"Dot": {
+		Function: Dot,
+		Required: []string{"axes"},
+	},
+	"Dropout": {
+		Function: Dropout,
+		Required: []string{"rate"},
+	},
+	"EinsumDense": {
+		Function: EinsumDense,
+		Required: []string{"equation", "output_shape"},
+	},
+	"Embedding": {
+		Function: Embedding,
+		Required: []string{"input_dim", "output_dim"},
+	},
+	"Flatten": {
+		Function: Flatten,
+	},
+	"GRU": {
+		Function: GRU,
+		Required: []string{"units"},
+	},
+	"GaussianDropout": {
+		Function: GaussianDropout,
+		Required: []string{"rate"},
+	},
+	"GaussianNoise": {
+		Function: GaussianNoise,
+		Required: []string{"stddev"},
+	},
+	"GlobalAveragePooling1D": {
+		Function: GlobalAveragePooling1D,
+	},
+	"GlobalAveragePooling2D": {
+		Function: GlobalAveragePooling2D,
+	},
+	"GlobalAveragePooling3D": {
+		Function: GlobalAveragePooling3D,
+	},
+	"GlobalMaxPooling1D": {
+		Function: GlobalMaxPooling1D,
+	},
+	"GlobalMaxPooling2D": {
+		Function: GlobalMaxPooling2D,
+	},
+	"GlobalMaxPooling3D": {
+		Function: GlobalMaxPooling3D,
+	},
+	"Hashing": {
+		Function: Hashing,
+		Required: []string{"num_bins"},
+	},
+	"IntegerLookup": {
+		Function: IntegerLookup,
+	},
+	"LSTM": {
+		Function: LSTM,
+		Required: []string{"units"},
+	},
+	"LayerNormalization": {
+		Function: LayerNormalization,
+	},
+	"LeakyReLU": {
+		Function: LeakyReLU,
+	},
+	"Masking": {
+		Function: Masking,
+	},
+	"MaxPooling1D": {
+		Function: MaxPooling1D,
+	},
+	"MaxPooling2D": {
+		Function: MaxPooling2D,
+	},
+	"MaxPooling3D": {
+		Function: MaxPooling3D,
+	},
+	"Maximum": {
+		Function: Maximum,
+	},
+	"Minimum": {
+		Function: Minimum,
+	},
+	"MultiHeadAttention": {
+		Function: MultiHeadAttention,
+		Required: []string{"key_dim", "num_heads"},
+	},
+	"Multiply": {
+		Function: Multiply,
+	},
+	"Normalization": {
+		Function: Normalization,
+	},
+	"Permute": {
+		Function: Permute,
+		Required: []string{"dims"},
+	},
+	"PreprocessingLayer": {
+		Function: PreprocessingLayer,
+	},
+	"RandomContrast": {
+		Function: RandomContrast,
+		Required: []string{"factor"},
+	},
+	"RandomCrop": {
+		Function: RandomCrop,
+		Required: []string{"height", "width"},
+	},
+	"RandomFlip": {
+		Function: RandomFlip,
+	},
+	"RandomFourierFeatures": {
+		Function: RandomFourierFeatures,
+		Required: []string{"output_dim"},
+	},
+	"RandomHeight": {
+		Function: RandomHeight,
+		Required: []string{"factor"},
+	},
+	"RandomRotation": {
+		Function: RandomRotation,
+		Required: []string{"factor"},
+	},
+	"RandomTranslation": {
+		Function: RandomTranslation,
+		Required: []string{"height_factor", "width_factor"},
+	},
+	"RandomWidth": {
+		Function: RandomWidth,
+		Required: []string{"factor"},
+	},
+	"RandomZoom": {
+		Function: RandomZoom,
+		Required: []string{"height_factor"},
+	},
+	"RepeatVector": {
+		Function: RepeatVector,
+		Required: []string{"n"},
+	},
+	"Rescaling": {
+		Function: Rescaling,
+		Required: []string{"scale"},
+	},
+	"Reshape": {
+		Function: Reshape,
+		Required: []string{"target_shape"},
+	},
+	"Resizing": {
+		Function: Resizing,
+		Required: []string{"height", "width"},
+	},
+	"SeparableConv1D": {
+		Function: SeparableConv1D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"SeparableConv2D": {
+		Function: SeparableConv2D,
+		Required: []string{"filters", "kernel_size"},
+	},
+	"SimpleRNN": {
+		Function: SimpleRNN,
+		Required: []string{"units"},
+	},
+	"SpatialDropout1D": {
+		Function: SpatialDropout1D,
+		Required: []string{"rate"},
+	},
+	"SpatialDropout2D": {
+		Function: SpatialDropout2D,
+		Required: []string{"rate"},
+	},
+	"SpatialDropout3D": {
+		Function: SpatialDropout3D,
+		Required: []string{"rate"},
+	},
+	"StringLookup": {
+		Function: StringLookup,
+	},
+	"Subtract": {
+		Function: Subtract,
+	},
+	"SyncBatchNormalization": {
+		Function: SyncBatchNormalization,
+	},
+	"TextVectorization": {
+		Function: TextVectorization,
+	},
+	"TimeDistributed": {
+		Function: TimeDistributed,
+		Required: []string{"layer"},
+	},
+	"UpSampling1D": {
+		Function: UpSampling1D,
+	},
+	"UpSampling2D": {
+		Function: UpSampling2D,
+	},
+	"UpSampling3D": {
+		Function: UpSampling3D,
+	},
+	"ZeroPadding1D": {
+		Function: ZeroPadding1D,
+	},
+	"ZeroPadding2D": {
+		Function: ZeroPadding2D,
+	},
+	"ZeroPadding3D": {
+		Function: ZeroPadding3D,
+	},
+}
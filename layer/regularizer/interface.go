@@ -9,3 +9,9 @@ type NilRegularizer struct{}
 func (n *NilRegularizer) GetKerasLayerConfig() interface{} {
 	return nil
 }
+
+// Constructor builds a regularizer from its keras class name and config in the same way as layer.Constructor
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
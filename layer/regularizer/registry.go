@@ -0,0 +1,11 @@
+package regularizer
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"L1": {
+		Function: L1,
+	},
+	"L2": {
+		Function: L2,
+	},
+}
@@ -0,0 +1,69 @@
+package loss
+
+type LBinaryCrossentropy struct {
+	axis           float64
+	fromLogits     bool
+	labelSmoothing float64
+	name           string
+	reduction      string
+}
+
+func BinaryCrossentropy() *LBinaryCrossentropy {
+	return &LBinaryCrossentropy{
+		axis:           -1,
+		fromLogits:     false,
+		labelSmoothing: 0,
+		name:           UniqueName("binary_crossentropy"),
+		reduction:      "auto",
+	}
+}
+
+func (l *LBinaryCrossentropy) SetAxis(axis float64) *LBinaryCrossentropy {
+	l.axis = axis
+	return l
+}
+
+func (l *LBinaryCrossentropy) SetFromLogits(fromLogits bool) *LBinaryCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LBinaryCrossentropy) SetLabelSmoothing(labelSmoothing float64) *LBinaryCrossentropy {
+	l.labelSmoothing = labelSmoothing
+	return l
+}
+
+func (l *LBinaryCrossentropy) SetName(name string) *LBinaryCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LBinaryCrossentropy) SetReduction(reduction string) *LBinaryCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLBinaryCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LBinaryCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLBinaryCrossentropy{
+		ClassName: "BinaryCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"axis":            l.axis,
+			"from_logits":     l.fromLogits,
+			"label_smoothing": l.labelSmoothing,
+			"name":            l.name,
+			"reduction":       l.reduction,
+		},
+	}
+}
+
+func (l *LBinaryCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
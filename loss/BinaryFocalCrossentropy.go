@@ -0,0 +1,93 @@
+package loss
+
+type LBinaryFocalCrossentropy struct {
+	alpha               float64
+	applyClassBalancing bool
+	axis                float64
+	fromLogits          bool
+	gamma               float64
+	labelSmoothing      float64
+	name                string
+	reduction           string
+}
+
+func BinaryFocalCrossentropy() *LBinaryFocalCrossentropy {
+	return &LBinaryFocalCrossentropy{
+		alpha:               0.25,
+		applyClassBalancing: false,
+		axis:                -1,
+		fromLogits:          false,
+		gamma:               2,
+		labelSmoothing:      0,
+		name:                UniqueName("binary_focal_crossentropy"),
+		reduction:           "auto",
+	}
+}
+
+func (l *LBinaryFocalCrossentropy) SetAlpha(alpha float64) *LBinaryFocalCrossentropy {
+	l.alpha = alpha
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetApplyClassBalancing(applyClassBalancing bool) *LBinaryFocalCrossentropy {
+	l.applyClassBalancing = applyClassBalancing
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetAxis(axis float64) *LBinaryFocalCrossentropy {
+	l.axis = axis
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetFromLogits(fromLogits bool) *LBinaryFocalCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetGamma(gamma float64) *LBinaryFocalCrossentropy {
+	l.gamma = gamma
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetLabelSmoothing(labelSmoothing float64) *LBinaryFocalCrossentropy {
+	l.labelSmoothing = labelSmoothing
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetName(name string) *LBinaryFocalCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LBinaryFocalCrossentropy) SetReduction(reduction string) *LBinaryFocalCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLBinaryFocalCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LBinaryFocalCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLBinaryFocalCrossentropy{
+		ClassName: "BinaryFocalCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"alpha":                 l.alpha,
+			"apply_class_balancing": l.applyClassBalancing,
+			"axis":                  l.axis,
+			"from_logits":           l.fromLogits,
+			"gamma":                 l.gamma,
+			"label_smoothing":       l.labelSmoothing,
+			"name":                  l.name,
+			"reduction":             l.reduction,
+		},
+	}
+}
+
+func (l *LBinaryFocalCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
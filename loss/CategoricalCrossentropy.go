@@ -0,0 +1,69 @@
+package loss
+
+type LCategoricalCrossentropy struct {
+	axis           float64
+	fromLogits     bool
+	labelSmoothing float64
+	name           string
+	reduction      string
+}
+
+func CategoricalCrossentropy() *LCategoricalCrossentropy {
+	return &LCategoricalCrossentropy{
+		axis:           -1,
+		fromLogits:     false,
+		labelSmoothing: 0,
+		name:           UniqueName("categorical_crossentropy"),
+		reduction:      "auto",
+	}
+}
+
+func (l *LCategoricalCrossentropy) SetAxis(axis float64) *LCategoricalCrossentropy {
+	l.axis = axis
+	return l
+}
+
+func (l *LCategoricalCrossentropy) SetFromLogits(fromLogits bool) *LCategoricalCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LCategoricalCrossentropy) SetLabelSmoothing(labelSmoothing float64) *LCategoricalCrossentropy {
+	l.labelSmoothing = labelSmoothing
+	return l
+}
+
+func (l *LCategoricalCrossentropy) SetName(name string) *LCategoricalCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LCategoricalCrossentropy) SetReduction(reduction string) *LCategoricalCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLCategoricalCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LCategoricalCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLCategoricalCrossentropy{
+		ClassName: "CategoricalCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"axis":            l.axis,
+			"from_logits":     l.fromLogits,
+			"label_smoothing": l.labelSmoothing,
+			"name":            l.name,
+			"reduction":       l.reduction,
+		},
+	}
+}
+
+func (l *LCategoricalCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
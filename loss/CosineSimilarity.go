@@ -0,0 +1,53 @@
+package loss
+
+type LCosineSimilarity struct {
+	axis      float64
+	name      string
+	reduction string
+}
+
+func CosineSimilarity() *LCosineSimilarity {
+	return &LCosineSimilarity{
+		axis:      -1,
+		name:      UniqueName("cosine_similarity"),
+		reduction: "auto",
+	}
+}
+
+func (l *LCosineSimilarity) SetAxis(axis float64) *LCosineSimilarity {
+	l.axis = axis
+	return l
+}
+
+func (l *LCosineSimilarity) SetName(name string) *LCosineSimilarity {
+	l.name = name
+	return l
+}
+
+func (l *LCosineSimilarity) SetReduction(reduction string) *LCosineSimilarity {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLCosineSimilarity struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LCosineSimilarity) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLCosineSimilarity{
+		ClassName: "CosineSimilarity",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"axis":      l.axis,
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LCosineSimilarity) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,163 @@
+package loss
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var pythonIdentifierRegex = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+type LCustom struct {
+	name       string
+	definition string
+	config     map[string]interface{}
+}
+
+// Custom creates a loss from the body of a python function. The body has access to y_true, y_pred and the config
+// dict set with SetConfig and must return the loss of each sample E.G:
+// return tf.reduce_mean(tf.maximum(config["quantile"] * (y_true - y_pred), (config["quantile"] - 1) * (y_true - y_pred)), axis=-1)
+// The name is used as the python class name of the loss so it must be a valid python identifier
+func Custom(name string, definition string) (*LCustom, error) {
+	if !pythonIdentifierRegex.MatchString(name) || pythonKeywords[name] {
+		return nil, fmt.Errorf("custom loss name is not a valid python identifier: %s", name)
+	}
+	return &LCustom{
+		name:       name,
+		definition: definition,
+		config:     make(map[string]interface{}),
+	}, nil
+}
+
+func (l *LCustom) SetConfig(config map[string]interface{}) *LCustom {
+	l.config = config
+	return l
+}
+
+func (l *LCustom) GetName() string {
+	return l.name
+}
+
+type jsonConfigLCustom struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LCustom) GetKerasLayerConfig() interface{} {
+	config := map[string]interface{}{
+		"name":      l.name,
+		"reduction": "auto",
+	}
+	for key, value := range l.config {
+		config[key] = value
+	}
+
+	return jsonConfigLCustom{
+		ClassName: l.name,
+		Name:      l.name,
+		Config:    config,
+	}
+}
+
+func (l *LCustom) GetCustomLayerDefinition() string {
+	var body []string
+	for _, line := range strings.Split(strings.TrimSpace(l.definition), "\n") {
+		body = append(body, "    "+line)
+	}
+
+	return fmt.Sprintf(`def %s_loss(y_true, y_pred, config):
+%s
+
+
+class %s(tf.keras.losses.Loss):
+    def __init__(self, reduction="auto", name="%s", **config):
+        super().__init__(reduction=reduction, name=name)
+        self.config = config
+
+    def call(self, y_true, y_pred):
+        y_pred = tf.convert_to_tensor(y_pred)
+        y_true = tf.cast(y_true, y_pred.dtype)
+        return %s_loss(y_true, y_pred, self.config)
+
+    def get_config(self):
+        loss_config = super().get_config()
+        loss_config.update(self.config)
+        return loss_config
+
+
+custom_objects["%s"] = %s
+`,
+		l.name,
+		strings.Join(body, "\n"),
+		l.name,
+		l.name,
+		l.name,
+		l.name,
+		l.name,
+	)
+}
+
+type customLossJson struct {
+	Name       string                 `json:"name"`
+	Definition string                 `json:"definition"`
+	Config     map[string]interface{} `json:"config"`
+}
+
+const customLossesFileName = "custom-losses.json"
+
+// SaveCustomLosses saves the definitions of custom losses so they can be loaded with LoadCustomLosses to recompile a model
+func SaveCustomLosses(dir string, losses []*LCustom) error {
+	var customLosses []customLossJson
+	for _, l := range losses {
+		customLosses = append(customLosses, customLossJson{
+			Name:       l.name,
+			Definition: l.definition,
+			Config:     l.config,
+		})
+	}
+
+	jsonBytes, e := json.MarshalIndent(customLosses, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, customLossesFileName), jsonBytes, os.ModePerm)
+}
+
+// LoadCustomLosses loads the custom losses saved alongside model.json, see model.TfkgModel.GetCustomLosses
+func LoadCustomLosses(dir string) ([]*LCustom, error) {
+	jsonBytes, e := ioutil.ReadFile(filepath.Join(dir, customLossesFileName))
+	if e != nil {
+		return nil, e
+	}
+
+	var customLosses []customLossJson
+	e = json.Unmarshal(jsonBytes, &customLosses)
+	if e != nil {
+		return nil, e
+	}
+
+	var losses []*LCustom
+	for _, customLoss := range customLosses {
+		l, e := Custom(customLoss.Name, customLoss.Definition)
+		if e != nil {
+			return nil, e
+		}
+		losses = append(losses, l.SetConfig(customLoss.Config))
+	}
+
+	return losses, nil
+}
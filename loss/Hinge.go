@@ -0,0 +1,45 @@
+package loss
+
+type LHinge struct {
+	name      string
+	reduction string
+}
+
+func Hinge() *LHinge {
+	return &LHinge{
+		name:      UniqueName("hinge"),
+		reduction: "auto",
+	}
+}
+
+func (l *LHinge) SetName(name string) *LHinge {
+	l.name = name
+	return l
+}
+
+func (l *LHinge) SetReduction(reduction string) *LHinge {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLHinge struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LHinge) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLHinge{
+		ClassName: "Hinge",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LHinge) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,53 @@
+package loss
+
+type LHuber struct {
+	delta     float64
+	name      string
+	reduction string
+}
+
+func Huber() *LHuber {
+	return &LHuber{
+		delta:     1,
+		name:      UniqueName("huber_loss"),
+		reduction: "auto",
+	}
+}
+
+func (l *LHuber) SetDelta(delta float64) *LHuber {
+	l.delta = delta
+	return l
+}
+
+func (l *LHuber) SetName(name string) *LHuber {
+	l.name = name
+	return l
+}
+
+func (l *LHuber) SetReduction(reduction string) *LHuber {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLHuber struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LHuber) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLHuber{
+		ClassName: "Huber",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"delta":     l.delta,
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LHuber) GetCustomLayerDefinition() string {
+	return ``
+}
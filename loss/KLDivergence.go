@@ -0,0 +1,45 @@
+package loss
+
+type LKLDivergence struct {
+	name      string
+	reduction string
+}
+
+func KLDivergence() *LKLDivergence {
+	return &LKLDivergence{
+		name:      UniqueName("kl_divergence"),
+		reduction: "auto",
+	}
+}
+
+func (l *LKLDivergence) SetName(name string) *LKLDivergence {
+	l.name = name
+	return l
+}
+
+func (l *LKLDivergence) SetReduction(reduction string) *LKLDivergence {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLKLDivergence struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LKLDivergence) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLKLDivergence{
+		ClassName: "KLDivergence",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LKLDivergence) GetCustomLayerDefinition() string {
+	return ``
+}
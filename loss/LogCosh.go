@@ -0,0 +1,45 @@
+package loss
+
+type LLogCosh struct {
+	name      string
+	reduction string
+}
+
+func LogCosh() *LLogCosh {
+	return &LLogCosh{
+		name:      UniqueName("log_cosh"),
+		reduction: "auto",
+	}
+}
+
+func (l *LLogCosh) SetName(name string) *LLogCosh {
+	l.name = name
+	return l
+}
+
+func (l *LLogCosh) SetReduction(reduction string) *LLogCosh {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLLogCosh struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LLogCosh) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLLogCosh{
+		ClassName: "LogCosh",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LLogCosh) GetCustomLayerDefinition() string {
+	return ``
+}
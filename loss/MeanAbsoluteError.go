@@ -0,0 +1,45 @@
+package loss
+
+type LMeanAbsoluteError struct {
+	name      string
+	reduction string
+}
+
+func MeanAbsoluteError() *LMeanAbsoluteError {
+	return &LMeanAbsoluteError{
+		name:      UniqueName("mean_absolute_error"),
+		reduction: "auto",
+	}
+}
+
+func (l *LMeanAbsoluteError) SetName(name string) *LMeanAbsoluteError {
+	l.name = name
+	return l
+}
+
+func (l *LMeanAbsoluteError) SetReduction(reduction string) *LMeanAbsoluteError {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLMeanAbsoluteError struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LMeanAbsoluteError) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLMeanAbsoluteError{
+		ClassName: "MeanAbsoluteError",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LMeanAbsoluteError) GetCustomLayerDefinition() string {
+	return ``
+}
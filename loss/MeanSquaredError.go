@@ -0,0 +1,45 @@
+package loss
+
+type LMeanSquaredError struct {
+	name      string
+	reduction string
+}
+
+func MeanSquaredError() *LMeanSquaredError {
+	return &LMeanSquaredError{
+		name:      UniqueName("mean_squared_error"),
+		reduction: "auto",
+	}
+}
+
+func (l *LMeanSquaredError) SetName(name string) *LMeanSquaredError {
+	l.name = name
+	return l
+}
+
+func (l *LMeanSquaredError) SetReduction(reduction string) *LMeanSquaredError {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLMeanSquaredError struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LMeanSquaredError) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLMeanSquaredError{
+		ClassName: "MeanSquaredError",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LMeanSquaredError) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,71 @@
+package loss
+
+type LMultiLabelCrossentropy struct {
+	axis           float64
+	fromLogits     bool
+	labelSmoothing float64
+	name           string
+	reduction      string
+}
+
+// MultiLabelCrossentropy is binary crossentropy over k-hot labels, where each element of the output is an independent
+// sigmoid. Unlike BinaryCrossentropy the labels are float32 with the same shape as the output
+func MultiLabelCrossentropy() *LMultiLabelCrossentropy {
+	return &LMultiLabelCrossentropy{
+		axis:           -1,
+		fromLogits:     false,
+		labelSmoothing: 0,
+		name:           UniqueName("multi_label_crossentropy"),
+		reduction:      "auto",
+	}
+}
+
+func (l *LMultiLabelCrossentropy) SetAxis(axis float64) *LMultiLabelCrossentropy {
+	l.axis = axis
+	return l
+}
+
+func (l *LMultiLabelCrossentropy) SetFromLogits(fromLogits bool) *LMultiLabelCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LMultiLabelCrossentropy) SetLabelSmoothing(labelSmoothing float64) *LMultiLabelCrossentropy {
+	l.labelSmoothing = labelSmoothing
+	return l
+}
+
+func (l *LMultiLabelCrossentropy) SetName(name string) *LMultiLabelCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LMultiLabelCrossentropy) SetReduction(reduction string) *LMultiLabelCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLMultiLabelCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LMultiLabelCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLMultiLabelCrossentropy{
+		ClassName: "MultiLabelCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"axis":            l.axis,
+			"from_logits":     l.fromLogits,
+			"label_smoothing": l.labelSmoothing,
+			"name":            l.name,
+			"reduction":       l.reduction,
+		},
+	}
+}
+
+func (l *LMultiLabelCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
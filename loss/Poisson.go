@@ -0,0 +1,45 @@
+package loss
+
+type LPoisson struct {
+	name      string
+	reduction string
+}
+
+func Poisson() *LPoisson {
+	return &LPoisson{
+		name:      UniqueName("poisson"),
+		reduction: "auto",
+	}
+}
+
+func (l *LPoisson) SetName(name string) *LPoisson {
+	l.name = name
+	return l
+}
+
+func (l *LPoisson) SetReduction(reduction string) *LPoisson {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLPoisson struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LPoisson) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLPoisson{
+		ClassName: "Poisson",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LPoisson) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,56 @@
+package loss
+
+type LSequenceSparseCategoricalCrossentropy struct {
+	fromLogits bool
+	name       string
+	reduction  string
+}
+
+// SequenceSparseCategoricalCrossentropy is sparse categorical crossentropy over an output with a label per position
+// E.G. [batch, timesteps, classes] with labels of [batch, timesteps]. Positions labelled 0 are padding and are masked
+// out of the loss of each sample, see preprocessor.NewSequenceLabelTokenizingYProcessor
+func SequenceSparseCategoricalCrossentropy() *LSequenceSparseCategoricalCrossentropy {
+	return &LSequenceSparseCategoricalCrossentropy{
+		fromLogits: false,
+		name:       UniqueName("sequence_sparse_categorical_crossentropy"),
+		reduction:  "auto",
+	}
+}
+
+func (l *LSequenceSparseCategoricalCrossentropy) SetFromLogits(fromLogits bool) *LSequenceSparseCategoricalCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LSequenceSparseCategoricalCrossentropy) SetName(name string) *LSequenceSparseCategoricalCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LSequenceSparseCategoricalCrossentropy) SetReduction(reduction string) *LSequenceSparseCategoricalCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLSequenceSparseCategoricalCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LSequenceSparseCategoricalCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLSequenceSparseCategoricalCrossentropy{
+		ClassName: "SequenceSparseCategoricalCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"from_logits": l.fromLogits,
+			"name":        l.name,
+			"reduction":   l.reduction,
+		},
+	}
+}
+
+func (l *LSequenceSparseCategoricalCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,53 @@
+package loss
+
+type LSparseCategoricalCrossentropy struct {
+	fromLogits bool
+	name       string
+	reduction  string
+}
+
+func SparseCategoricalCrossentropy() *LSparseCategoricalCrossentropy {
+	return &LSparseCategoricalCrossentropy{
+		fromLogits: false,
+		name:       UniqueName("sparse_categorical_crossentropy"),
+		reduction:  "auto",
+	}
+}
+
+func (l *LSparseCategoricalCrossentropy) SetFromLogits(fromLogits bool) *LSparseCategoricalCrossentropy {
+	l.fromLogits = fromLogits
+	return l
+}
+
+func (l *LSparseCategoricalCrossentropy) SetName(name string) *LSparseCategoricalCrossentropy {
+	l.name = name
+	return l
+}
+
+func (l *LSparseCategoricalCrossentropy) SetReduction(reduction string) *LSparseCategoricalCrossentropy {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLSparseCategoricalCrossentropy struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LSparseCategoricalCrossentropy) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLSparseCategoricalCrossentropy{
+		ClassName: "SparseCategoricalCrossentropy",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"from_logits": l.fromLogits,
+			"name":        l.name,
+			"reduction":   l.reduction,
+		},
+	}
+}
+
+func (l *LSparseCategoricalCrossentropy) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,45 @@
+package loss
+
+type LSquaredHinge struct {
+	name      string
+	reduction string
+}
+
+func SquaredHinge() *LSquaredHinge {
+	return &LSquaredHinge{
+		name:      UniqueName("squared_hinge"),
+		reduction: "auto",
+	}
+}
+
+func (l *LSquaredHinge) SetName(name string) *LSquaredHinge {
+	l.name = name
+	return l
+}
+
+func (l *LSquaredHinge) SetReduction(reduction string) *LSquaredHinge {
+	l.reduction = reduction
+	return l
+}
+
+type jsonConfigLSquaredHinge struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (l *LSquaredHinge) GetKerasLayerConfig() interface{} {
+
+	return jsonConfigLSquaredHinge{
+		ClassName: "SquaredHinge",
+		Name:      l.name,
+		Config: map[string]interface{}{
+			"name":      l.name,
+			"reduction": l.reduction,
+		},
+	}
+}
+
+func (l *LSquaredHinge) GetCustomLayerDefinition() string {
+	return ``
+}
@@ -0,0 +1,39 @@
+package loss
+
+import "fmt"
+
+// Loss is serialised into the keras loss config. The reduction is always replaced with "none" during compilation so
+// class weights can be applied to the loss of each sample before it is averaged
+type Loss interface {
+	GetKerasLayerConfig() interface{}
+	GetCustomLayerDefinition() string
+}
+
+// Constructor builds a loss from its keras class name and config in the same way as layer.Constructor
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
+
+// The losses which are not generated. Custom losses are not addressable by name as they need a definition
+func init() {
+	Constructors["BinaryFocalCrossentropy"] = Constructor{
+		Function: BinaryFocalCrossentropy,
+	}
+	Constructors["MultiLabelCrossentropy"] = Constructor{
+		Function: MultiLabelCrossentropy,
+	}
+	Constructors["SequenceSparseCategoricalCrossentropy"] = Constructor{
+		Function: SequenceSparseCategoricalCrossentropy,
+	}
+}
+
+var uniqueNameCounts = make(map[string]int)
+
+func UniqueName(name string) string {
+	count := uniqueNameCounts[name]
+	count++
+	uniqueNameCounts[name] = count
+
+	return fmt.Sprintf("%s_%d", name, count)
+}
@@ -0,0 +1,41 @@
+package loss
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"BinaryCrossentropy": {
+		Function: BinaryCrossentropy,
+	},
+	"CategoricalCrossentropy": {
+		Function: CategoricalCrossentropy,
+	},
+	"CosineSimilarity": {
+		Function: CosineSimilarity,
+	},
+	"Hinge": {
+		Function: Hinge,
+	},
+	"Huber": {
+		Function: Huber,
+	},
+	"KLDivergence": {
+		Function: KLDivergence,
+	},
+	"LogCosh": {
+		Function: LogCosh,
+	},
+	"MeanAbsoluteError": {
+		Function: MeanAbsoluteError,
+	},
+	"MeanSquaredError": {
+		Function: MeanSquaredError,
+	},
+	"Poisson": {
+		Function: Poisson,
+	},
+	"SparseCategoricalCrossentropy": {
+		Function: SparseCategoricalCrossentropy,
+	},
+	"SquaredHinge": {
+		Function: SquaredHinge,
+	},
+}
@@ -111,8 +111,9 @@ func (m *SparseCategoricalAccuracy) Compute(yTrue interface{}, yPred interface{}
 			}
 		}
 
-		m.total += float64(correct) / float64(len(yTrueValue))
-		m.count++
+		// Weighted by the number of rows so a final partial batch counts as much as its rows
+		m.total += float64(correct)
+		m.count += float64(len(yTrueValue))
 
 		return Value{
 			Name:      m.Name,
@@ -173,8 +174,8 @@ func (m *BinaryAccuracy) Compute(yTrue interface{}, yPred interface{}) Value {
 		}
 	}
 
-	m.total += float64(correct) / float64(len(yTrueValue))
-	m.count++
+	m.total += float64(correct)
+	m.count += float64(len(yTrueValue))
 
 	return Value{
 		Name:      m.Name,
@@ -0,0 +1,207 @@
+package metric
+
+// SubsetAccuracy is the fraction of rows where every label of a multi label output is predicted correctly, a label is
+// predicted when its value is at least Confidence
+type SubsetAccuracy struct {
+	Name       string
+	Confidence float64
+	Precision  int
+	total      float64
+	count      float64
+}
+
+func (m *SubsetAccuracy) Init() {
+	if m.Precision == 0 {
+		m.Precision = 4
+	}
+}
+
+func (m *SubsetAccuracy) Reset() {
+	m.total = 0
+	m.count = 0
+}
+
+func (m *SubsetAccuracy) GetName() string {
+	return m.Name
+}
+
+func (m *SubsetAccuracy) Compute(yTrue interface{}, yPred interface{}) Value {
+	yPredValue := yPred.([][]float32)
+	yTrueValue := yTrue.([][]float32)
+	for i, pred := range yPredValue {
+		correct := true
+		for label, value := range pred {
+			if (float64(value) >= m.Confidence) != (yTrueValue[i][label] == 1) {
+				correct = false
+				break
+			}
+		}
+		if correct {
+			m.total++
+		}
+		m.count++
+	}
+
+	return m.ComputeFinal()
+}
+
+func (m *SubsetAccuracy) ComputeFinal() Value {
+	value := float64(0)
+	if m.count > 0 {
+		value = m.total / m.count
+	}
+	return Value{
+		Name:      m.Name,
+		Value:     value,
+		Precision: m.Precision,
+	}
+}
+
+// HammingLoss is the fraction of labels of a multi label output which are predicted incorrectly, a label is predicted
+// when its value is at least Confidence
+type HammingLoss struct {
+	Name       string
+	Confidence float64
+	Precision  int
+	total      float64
+	count      float64
+}
+
+func (m *HammingLoss) Init() {
+	if m.Precision == 0 {
+		m.Precision = 4
+	}
+}
+
+func (m *HammingLoss) Reset() {
+	m.total = 0
+	m.count = 0
+}
+
+func (m *HammingLoss) GetName() string {
+	return m.Name
+}
+
+func (m *HammingLoss) Compute(yTrue interface{}, yPred interface{}) Value {
+	yPredValue := yPred.([][]float32)
+	yTrueValue := yTrue.([][]float32)
+	for i, pred := range yPredValue {
+		for label, value := range pred {
+			if (float64(value) >= m.Confidence) != (yTrueValue[i][label] == 1) {
+				m.total++
+			}
+			m.count++
+		}
+	}
+
+	return m.ComputeFinal()
+}
+
+func (m *HammingLoss) ComputeFinal() Value {
+	value := float64(0)
+	if m.count > 0 {
+		value = m.total / m.count
+	}
+	return Value{
+		Name:      m.Name,
+		Value:     value,
+		Precision: m.Precision,
+	}
+}
+
+type F1Average string
+
+var (
+	// F1Micro computes F1 from the true positives, false positives and false negatives of every label combined
+	F1Micro F1Average = "micro"
+	// F1Macro computes F1 for each label and averages them, so rare labels count as much as common ones
+	F1Macro F1Average = "macro"
+)
+
+// MultiLabelF1 is the F1 score of a multi label output, a label is predicted when its value is at least Confidence
+type MultiLabelF1 struct {
+	Name           string
+	Confidence     float64
+	Average        F1Average
+	Precision      int
+	truePositives  []float64
+	falsePositives []float64
+	falseNegatives []float64
+}
+
+func (m *MultiLabelF1) Init() {
+	if m.Precision == 0 {
+		m.Precision = 4
+	}
+	if m.Average == "" {
+		m.Average = F1Micro
+	}
+}
+
+func (m *MultiLabelF1) Reset() {
+	m.truePositives = nil
+	m.falsePositives = nil
+	m.falseNegatives = nil
+}
+
+func (m *MultiLabelF1) GetName() string {
+	return m.Name
+}
+
+func (m *MultiLabelF1) Compute(yTrue interface{}, yPred interface{}) Value {
+	yPredValue := yPred.([][]float32)
+	yTrueValue := yTrue.([][]float32)
+	for i, pred := range yPredValue {
+		for len(m.truePositives) < len(pred) {
+			m.truePositives = append(m.truePositives, 0)
+			m.falsePositives = append(m.falsePositives, 0)
+			m.falseNegatives = append(m.falseNegatives, 0)
+		}
+		for label, value := range pred {
+			predicted := float64(value) >= m.Confidence
+			actual := yTrueValue[i][label] == 1
+			if predicted && actual {
+				m.truePositives[label]++
+			} else if predicted {
+				m.falsePositives[label]++
+			} else if actual {
+				m.falseNegatives[label]++
+			}
+		}
+	}
+
+	return m.ComputeFinal()
+}
+
+func (m *MultiLabelF1) ComputeFinal() Value {
+	var value float64
+	if m.Average == F1Macro {
+		for label := range m.truePositives {
+			value += f1(m.truePositives[label], m.falsePositives[label], m.falseNegatives[label])
+		}
+		if len(m.truePositives) > 0 {
+			value = value / float64(len(m.truePositives))
+		}
+	} else {
+		var truePositives, falsePositives, falseNegatives float64
+		for label := range m.truePositives {
+			truePositives += m.truePositives[label]
+			falsePositives += m.falsePositives[label]
+			falseNegatives += m.falseNegatives[label]
+		}
+		value = f1(truePositives, falsePositives, falseNegatives)
+	}
+
+	return Value{
+		Name:      m.Name,
+		Value:     value,
+		Precision: m.Precision,
+	}
+}
+
+func f1(truePositives float64, falsePositives float64, falseNegatives float64) float64 {
+	if truePositives == 0 {
+		return 0
+	}
+	return 2 * truePositives / (2*truePositives + falsePositives + falseNegatives)
+}
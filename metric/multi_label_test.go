@@ -0,0 +1,84 @@
+package metric
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMultiLabelMetrics(t *testing.T) {
+	yTrue := [][]float32{
+		{1, 0, 1},
+		{0, 1, 0},
+		{1, 1, 0},
+	}
+	// Row 0 is correct, row 1 predicts label 0 and row 2 misses label 1
+	yPred := [][]float32{
+		{0.9, 0.2, 0.7},
+		{0.6, 0.8, 0.1},
+		{0.8, 0.3, 0.4},
+	}
+
+	tests := []struct {
+		name     string
+		metric   Metric
+		expected float64
+	}{
+		{
+			name:     "subset accuracy",
+			metric:   &SubsetAccuracy{Name: "subset_acc", Confidence: 0.5},
+			expected: 1.0 / 3,
+		},
+		{
+			name:     "subset accuracy high confidence",
+			metric:   &SubsetAccuracy{Name: "subset_acc", Confidence: 0.85},
+			expected: 0,
+		},
+		{
+			name:     "hamming loss",
+			metric:   &HammingLoss{Name: "hamming_loss", Confidence: 0.5},
+			expected: 2.0 / 9,
+		},
+		{
+			name:     "f1 micro",
+			metric:   &MultiLabelF1{Name: "f1", Confidence: 0.5},
+			expected: 0.8,
+		},
+		{
+			name:     "f1 macro",
+			metric:   &MultiLabelF1{Name: "f1", Confidence: 0.5, Average: F1Macro},
+			expected: (0.8 + 2.0/3 + 1) / 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.metric.Init()
+
+			value := test.metric.Compute(yTrue, yPred)
+			if math.Abs(value.Value-test.expected) > 1e-9 {
+				t.Errorf("expected %f, got %f", test.expected, value.Value)
+			}
+			if value.Name != test.metric.GetName() {
+				t.Errorf("expected the name %s, got %s", test.metric.GetName(), value.Name)
+			}
+			if value.Precision != 4 {
+				t.Errorf("expected the default precision of 4, got %d", value.Precision)
+			}
+
+			// The same rows split over batches give the same value
+			test.metric.Reset()
+			test.metric.Compute(yTrue[:1], yPred[:1])
+			test.metric.Compute(yTrue[1:], yPred[1:])
+			value = test.metric.ComputeFinal()
+			if math.Abs(value.Value-test.expected) > 1e-9 {
+				t.Errorf("expected %f over batches, got %f", test.expected, value.Value)
+			}
+
+			test.metric.Reset()
+			value = test.metric.ComputeFinal()
+			if value.Value != 0 {
+				t.Errorf("expected 0 before any rows, got %f", value.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package metric
+
+// TokenAccuracy is the fraction of positions of a sequence output where the class with the highest prediction matches
+// the label. Positions labelled PaddingLabel are ignored, see preprocessor.NewSequenceLabelTokenizingYProcessor
+type TokenAccuracy struct {
+	Name         string
+	PaddingLabel int32
+	Precision    int
+	total        float64
+	count        float64
+}
+
+func (m *TokenAccuracy) Init() {
+	if m.Precision == 0 {
+		m.Precision = 4
+	}
+}
+
+func (m *TokenAccuracy) Reset() {
+	m.total = 0
+	m.count = 0
+}
+
+func (m *TokenAccuracy) GetName() string {
+	return m.Name
+}
+
+func (m *TokenAccuracy) Compute(yTrue interface{}, yPred interface{}) Value {
+	yPredValue := yPred.([][][]float32)
+	yTrueValue := yTrue.([][]int32)
+	for i, sequence := range yPredValue {
+		for position, pred := range sequence {
+			label := yTrueValue[i][position]
+			if label == m.PaddingLabel {
+				continue
+			}
+			var maxIndex int32
+			for index, value := range pred {
+				if value > pred[maxIndex] {
+					maxIndex = int32(index)
+				}
+			}
+			if maxIndex == label {
+				m.total++
+			}
+			m.count++
+		}
+	}
+
+	return m.ComputeFinal()
+}
+
+func (m *TokenAccuracy) ComputeFinal() Value {
+	value := float64(0)
+	if m.count > 0 {
+		value = m.total / m.count
+	}
+	return Value{
+		Name:      m.Name,
+		Value:     value,
+		Precision: m.Precision,
+	}
+}
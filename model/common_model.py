@@ -0,0 +1,367 @@
+import json
+import os
+import logging
+import sys
+
+import tensorflow as tf
+import numpy as np
+
+os.environ['TF_CPP_MIN_LOG_LEVEL'] = '2'  # ERROR
+logging.getLogger('tensorflow').setLevel(logging.ERROR)
+logging.disable(logging.WARNING)
+
+custom_objects = {}
+
+# tfkg-custom-definitions
+
+with open(sys.argv[1], "r") as f:
+    config = json.load(f)
+
+class LinearWarmup(tf.keras.optimizers.schedules.LearningRateSchedule):
+    def __init__(self, warmup_steps, after_warmup, initial_learning_rate=0.0, name="LinearWarmup"):
+        super().__init__()
+        self.warmup_steps = warmup_steps
+        self.initial_learning_rate = initial_learning_rate
+        self.name = name
+        if isinstance(after_warmup, dict):
+            after_warmup = tf.keras.optimizers.schedules.deserialize(after_warmup, custom_objects=custom_objects)
+        self.after_warmup = after_warmup
+
+    def __call__(self, step):
+        step = tf.cast(step, tf.float32)
+        warmup_steps = tf.cast(self.warmup_steps, tf.float32)
+        target_learning_rate = tf.cast(self.after_warmup(0), tf.float32)
+        warmup_learning_rate = self.initial_learning_rate + (
+                target_learning_rate - self.initial_learning_rate
+        ) * step / warmup_steps
+
+        return tf.cond(
+            step < warmup_steps,
+            lambda: warmup_learning_rate,
+            lambda: tf.cast(self.after_warmup(step - warmup_steps), tf.float32),
+        )
+
+    def get_config(self):
+        return {
+            "warmup_steps": self.warmup_steps,
+            "after_warmup": tf.keras.optimizers.schedules.serialize(self.after_warmup),
+            "initial_learning_rate": self.initial_learning_rate,
+            "name": self.name,
+        }
+
+
+custom_objects["LinearWarmup"] = LinearWarmup
+
+
+class ScaledSchedule(tf.keras.optimizers.schedules.LearningRateSchedule):
+    def __init__(self, schedule, scale):
+        super().__init__()
+        self.schedule = schedule
+        self.scale = scale
+
+    def __call__(self, step):
+        return tf.cast(self.schedule(step), tf.float32) * self.scale
+
+    def get_config(self):
+        return self.schedule.get_config()
+
+
+def freeze_batch_normalization(batch_normalization, trainable):
+    # Keras runs a frozen BatchNormalization layer in inference mode. Frozen layers are made trainable so they can be
+    # unfrozen, so the layer keeps using and not updating its moving statistics while its trainable flag is false
+    call = batch_normalization.call
+
+    def call_with_trainable_flag(inputs, training=None):
+        if training is None or training is False:
+            return call(inputs, training=training)
+
+        return tf.cond(
+            trainable,
+            lambda: call(inputs, training=training),
+            lambda: call(inputs, training=False),
+        )
+
+    batch_normalization.call = call_with_trainable_flag
+
+
+def get_learning_rate(optimizer):
+    learning_rate = optimizer.learning_rate
+    if isinstance(learning_rate, tf.keras.optimizers.schedules.LearningRateSchedule):
+        return tf.cast(learning_rate(optimizer.iterations), tf.float32)
+
+    return tf.cast(learning_rate, tf.float32)
+
+
+class BinaryFocalCrossentropy(tf.keras.losses.Loss):
+    def __init__(
+            self,
+            apply_class_balancing=False,
+            alpha=0.25,
+            gamma=2.0,
+            from_logits=False,
+            label_smoothing=0.0,
+            axis=-1,
+            reduction="auto",
+            name="binary_focal_crossentropy"
+    ):
+        super().__init__(reduction=reduction, name=name)
+        self.apply_class_balancing = apply_class_balancing
+        self.alpha = alpha
+        self.gamma = gamma
+        self.from_logits = from_logits
+        self.label_smoothing = label_smoothing
+        self.axis = axis
+
+    def call(self, y_true, y_pred):
+        y_pred = tf.convert_to_tensor(y_pred)
+        y_true = tf.cast(y_true, y_pred.dtype)
+        if self.label_smoothing > 0:
+            y_true = y_true * (1.0 - self.label_smoothing) + 0.5 * self.label_smoothing
+
+        y_prob = y_pred
+        if self.from_logits:
+            y_prob = tf.sigmoid(y_pred)
+
+        cross_entropy = tf.keras.backend.binary_crossentropy(y_true, y_pred, from_logits=self.from_logits)
+        p_t = y_true * y_prob + (1 - y_true) * (1 - y_prob)
+        focal = tf.pow(1.0 - p_t, self.gamma) * cross_entropy
+        if self.apply_class_balancing:
+            focal = (y_true * self.alpha + (1 - y_true) * (1 - self.alpha)) * focal
+
+        return tf.reduce_mean(focal, axis=self.axis)
+
+    def get_config(self):
+        loss_config = super().get_config()
+        loss_config.update({
+            "apply_class_balancing": self.apply_class_balancing,
+            "alpha": self.alpha,
+            "gamma": self.gamma,
+            "from_logits": self.from_logits,
+            "label_smoothing": self.label_smoothing,
+            "axis": self.axis,
+        })
+        return loss_config
+
+
+class MultiLabelCrossentropy(tf.keras.losses.BinaryCrossentropy):
+    # Binary crossentropy over k-hot float labels with the same shape as the output, so it is not a sparse label loss
+    def __init__(
+            self,
+            from_logits=False,
+            label_smoothing=0.0,
+            axis=-1,
+            reduction="auto",
+            name="multi_label_crossentropy"
+    ):
+        super().__init__(
+            from_logits=from_logits,
+            label_smoothing=label_smoothing,
+            axis=axis,
+            reduction=reduction,
+            name=name,
+        )
+
+
+class SequenceSparseCategoricalCrossentropy(tf.keras.losses.SparseCategoricalCrossentropy):
+    # Sparse categorical crossentropy over a label per position, positions labelled 0 are padding and are masked out of
+    # the mean loss of each sample
+    def __init__(
+            self,
+            from_logits=False,
+            reduction="auto",
+            name="sequence_sparse_categorical_crossentropy"
+    ):
+        super().__init__(
+            from_logits=from_logits,
+            reduction=reduction,
+            name=name,
+        )
+
+    def call(self, y_true, y_pred):
+        position_loss = super().call(y_true, y_pred)
+        mask = tf.cast(tf.not_equal(tf.reshape(y_true, tf.shape(position_loss)), 0), position_loss.dtype)
+        return tf.reduce_sum(position_loss * mask, axis=-1) / tf.maximum(tf.reduce_sum(mask, axis=-1), 1.0)
+
+
+tfkg_losses = {
+    "BinaryFocalCrossentropy": BinaryFocalCrossentropy,
+    "MultiLabelCrossentropy": MultiLabelCrossentropy,
+    "SequenceSparseCategoricalCrossentropy": SequenceSparseCategoricalCrossentropy,
+}
+
+sparse_label_losses = [
+    "BinaryCrossentropy",
+    "BinaryFocalCrossentropy",
+    "SparseCategoricalCrossentropy",
+    "SequenceSparseCategoricalCrossentropy",
+]
+
+
+def get_loss(loss_config):
+    class_name = loss_config["class_name"]
+    loss_class_config = dict(loss_config["config"])
+    loss_class_config["reduction"] = "none"
+
+    if class_name in custom_objects:
+        loss_class = custom_objects[class_name]
+    elif class_name in tfkg_losses:
+        loss_class = tfkg_losses[class_name]
+    else:
+        loss_class = getattr(tf.keras.losses, class_name)
+
+    loss_func = loss_class.from_config(loss_class_config)
+
+    def loss(y_true, y_pred, class_weights):
+        sample_loss = loss_func(y_true, y_pred)
+        if len(sample_loss.shape) > 1:
+            sample_loss = tf.reduce_mean(sample_loss, axis=list(range(1, len(sample_loss.shape))))
+        weighted_loss = tf.multiply(
+            sample_loss,
+            class_weights
+        )
+        return tf.reduce_mean(weighted_loss)
+
+    return loss
+
+
+def get_layer_node(model, model_layer):
+    # A layer called in more than one graph, E.G. inside a nested model or shared with a sub model, has a node for each
+    # call. Only the node belonging to the outer model can be reached from its inputs, None if the model has none
+    network_nodes = getattr(model, "_network_nodes", set())
+    for node_index, node in enumerate(model_layer._inbound_nodes):
+        if "%s_ib-%d" % (model_layer.name, node_index) in network_nodes:
+            return node
+
+    return None
+
+
+def get_predict_layers(model):
+    # The output of every layer is traced into predict_layers so they can be fetched by name. Layers with multiple
+    # outputs are named layer_name_N. Layers without a node in the model are skipped
+    names = []
+    outputs = []
+    for model_layer in model.layers:
+        node = get_layer_node(model, model_layer)
+        if node is None:
+            continue
+        layer_outputs = tf.nest.flatten(node.outputs)
+        for output_offset, layer_output in enumerate(layer_outputs):
+            if len(layer_outputs) > 1:
+                names.append("%s_%d" % (model_layer.name, output_offset))
+            else:
+                names.append(model_layer.name)
+            outputs.append(layer_output)
+
+    if len(outputs) == 0:
+        # E.G. a subclassed model has no nodes, so only its outputs can be fetched
+        return list(model.output_names), list(model.outputs)
+
+    return names, outputs
+
+
+def interpolate_embedding(embedding_layer):
+    # While explain sets an alpha and a baseline the layer returns the point alpha of the way from the baseline embedding
+    # to the embedding of its tokens. Returns the original call and the interpolation dict
+    call = embedding_layer.call
+    interpolation = {}
+
+    def call_with_interpolation(inputs):
+        embeddings = call(inputs)
+        if "alpha" not in interpolation:
+            return embeddings
+
+        baseline = tf.cast(interpolation["baseline"], embeddings.dtype)
+        return baseline + tf.cast(interpolation["alpha"], embeddings.dtype) * (embeddings - baseline)
+
+    embedding_layer.call = call_with_interpolation
+
+    return call, interpolation
+
+
+def get_explain_baseline_signature(input_signature):
+    # Baselines are broadcast against the inputs, or the embeddings of token inputs, so [0] is a zero baseline
+    baseline_signature = []
+    for input_spec in input_signature:
+        if input_spec.dtype.is_floating:
+            baseline_signature.append(tf.TensorSpec(shape=None, dtype=input_spec.dtype))
+        else:
+            baseline_signature.append(tf.TensorSpec(shape=None, dtype=tf.float32))
+
+    return baseline_signature
+
+
+class Explainer:
+    # Integer inputs can not be differentiated, so a token input feeding an Embedding layer is interpolated in embedding
+    # space and its gradient is taken at the output of the Embedding layer. The gradient multiplied with the difference
+    # between the embedding and the baseline embedding gives one attribution per token
+    def __init__(self, model):
+        self._embedding_offsets = []
+        self._embedding_calls = []
+        self._interpolations = []
+        explain_outputs = [model.outputs[0]]
+        for model_input in model.inputs:
+            embedding_offset = -1
+            if not model_input.dtype.is_floating:
+                for model_layer in model.layers:
+                    if not isinstance(model_layer, tf.keras.layers.Embedding):
+                        continue
+                    node = get_layer_node(model, model_layer)
+                    if node is None or tf.nest.flatten(node.input_tensors)[0].name != model_input.name:
+                        continue
+                    embedding_offset = len(self._embedding_calls)
+                    call, interpolation = interpolate_embedding(model_layer)
+                    self._embedding_calls.append(call)
+                    self._interpolations.append(interpolation)
+                    explain_outputs.append(tf.nest.flatten(node.outputs)[0])
+                    break
+            self._embedding_offsets.append(embedding_offset)
+        self._model = tf.keras.Model(inputs=model.inputs, outputs=explain_outputs)
+
+    def explain(self, class_index, alpha, inputs, baselines):
+        interpolated_inputs = []
+        for input_offset, model_input in enumerate(inputs):
+            if model_input.dtype.is_floating:
+                baseline = tf.cast(baselines[input_offset], model_input.dtype)
+                model_input = baseline + tf.cast(alpha, model_input.dtype) * (model_input - baseline)
+            else:
+                embedding_offset = self._embedding_offsets[input_offset]
+                if embedding_offset != -1:
+                    self._interpolations[embedding_offset]["alpha"] = alpha
+                    self._interpolations[embedding_offset]["baseline"] = baselines[input_offset]
+            interpolated_inputs.append(model_input)
+
+        try:
+            with tf.GradientTape() as tape:
+                for model_input in interpolated_inputs:
+                    if model_input.dtype.is_floating:
+                        tape.watch(model_input)
+                explain_outputs = tf.nest.flatten(self._model(interpolated_inputs, training=False))
+                flat_output = tf.reshape(explain_outputs[0], [tf.shape(explain_outputs[0])[0], -1])
+                target = tf.gather(flat_output, class_index, axis=1)
+        finally:
+            # Only explain interpolates, other signatures traced afterwards get the plain embeddings
+            for interpolation in self._interpolations:
+                interpolation.clear()
+
+        sources = []
+        for input_offset, model_input in enumerate(interpolated_inputs):
+            if model_input.dtype.is_floating or self._embedding_offsets[input_offset] == -1:
+                sources.append(model_input)
+            else:
+                sources.append(explain_outputs[1 + self._embedding_offsets[input_offset]])
+        gradients = tape.gradient(target, sources)
+
+        attributions = []
+        for input_offset, model_input in enumerate(inputs):
+            if gradients[input_offset] is None:
+                attributions.append(tf.zeros_like(tf.cast(model_input, tf.float32)))
+            elif model_input.dtype.is_floating:
+                attributions.append(gradients[input_offset])
+            else:
+                embeddings = self._embedding_calls[self._embedding_offsets[input_offset]](model_input)
+                baseline = tf.cast(baselines[input_offset], embeddings.dtype)
+                attributions.append(
+                    tf.cast(tf.reduce_sum(gradients[input_offset] * (embeddings - baseline), axis=-1), tf.float32)
+                )
+
+        return attributions
@@ -0,0 +1,348 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/callback"
+	"github.com/codingbeard/tfkg/layer"
+	"github.com/codingbeard/tfkg/metric"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Config describes a model and how to train it so it can be kept in a YAML or JSON file, E.G. as the config of a job.
+// Layers, optimizers, losses, initializers, regularizers, constraints and schedules are addressed by their keras class
+// name with a config of keras keys, metrics and callbacks by their Go type name with a config of snake_case fields:
+//
+//	name: iris
+//	layers:
+//	  - class_name: InputLayer
+//	    config: {batch_input_shape: [null, 4]}
+//	  - class_name: Dense
+//	    config: {units: 3, activation: softmax, kernel_initializer: he_normal}
+//	optimizer: {class_name: Adam, config: {learning_rate: 0.001}}
+//	loss: {class_name: SparseCategoricalCrossentropy}
+//	batch_size: 32
+type Config struct {
+	Name string `json:"name"`
+	// Layers are built in order. The model is sequential unless a layer has Inputs or Outputs is set
+	Layers []LayerConfig `json:"layers"`
+	// Outputs are the names of the output layers of a functional model, defaults to the last layer
+	Outputs []string `json:"outputs"`
+	// Optimizer defaults to Adam
+	Optimizer *ObjectConfig `json:"optimizer"`
+	// Loss defaults to MeanSquaredError
+	Loss *ObjectConfig `json:"loss"`
+	// Losses sets a loss per output of a functional model, in the order of Outputs
+	Losses                    []ObjectConfig `json:"losses"`
+	LossWeights               []float64      `json:"loss_weights"`
+	BatchSize                 int            `json:"batch_size"`
+	CpuInference              bool           `json:"cpu_inference"`
+	GradientAccumulationSteps int            `json:"gradient_accumulation_steps"`
+	Dataset                   *DatasetConfig `json:"dataset"`
+	Training                  TrainingConfig `json:"training"`
+}
+
+type LayerConfig struct {
+	ClassName string `json:"class_name"`
+	// Name is the name of the layer used by Inputs and Outputs, defaults to a unique name like keras
+	Name   string                 `json:"name"`
+	Config map[string]interface{} `json:"config"`
+	// Inputs are the names of the input layers of a functional model. A layer without Inputs uses the previous layer
+	Inputs []string `json:"inputs"`
+}
+
+// TrainingConfig is the part of FitConfig which can be described in a config file
+type TrainingConfig struct {
+	Epochs      int   `json:"epochs"`
+	Validation  bool  `json:"validation"`
+	PreFetch    int   `json:"pre_fetch"`
+	Verbose     int   `json:"verbose"`
+	ShuffleSeed int64 `json:"shuffle_seed"`
+	// Metrics are Go type names from the metric package, E.G. {"class_name": "SparseCategoricalAccuracy",
+	// "config": {"name": "acc", "confidence": 0.5, "average": true}}
+	Metrics []ObjectConfig `json:"metrics"`
+	// OutputMetrics are computed for each output of a functional model, in the order of Outputs
+	OutputMetrics [][]ObjectConfig `json:"output_metrics"`
+	// Callbacks are Go type names from the callback package. The save and record dirs default to the dir passed to
+	// GetFitConfig and the Logger callback logs to its logger
+	Callbacks []ObjectConfig `json:"callbacks"`
+	// WeightAveraging is a WeightAveragingConfig, E.G. {"mode": "ema", "decay": 0.999}
+	WeightAveraging map[string]interface{} `json:"weight_averaging"`
+}
+
+var configMetrics = map[string]func() metric.Metric{
+	"SparseCategoricalAccuracy": func() metric.Metric { return &metric.SparseCategoricalAccuracy{} },
+	"BinaryAccuracy":            func() metric.Metric { return &metric.BinaryAccuracy{} },
+	"SparseCategoricalFprAtTpr": func() metric.Metric { return &metric.SparseCategoricalFprAtTpr{} },
+	"BinaryFprAtTpr":            func() metric.Metric { return &metric.BinaryFprAtTpr{} },
+	"SparseCategoricalTprAtFpr": func() metric.Metric { return &metric.SparseCategoricalTprAtFpr{} },
+	"BinaryTprAtFpr":            func() metric.Metric { return &metric.BinaryTprAtFpr{} },
+	"SubsetAccuracy":            func() metric.Metric { return &metric.SubsetAccuracy{} },
+	"HammingLoss":               func() metric.Metric { return &metric.HammingLoss{} },
+	"MultiLabelF1":              func() metric.Metric { return &metric.MultiLabelF1{} },
+	"TokenAccuracy":             func() metric.Metric { return &metric.TokenAccuracy{} },
+}
+
+var configCallbacks = map[string]func() callback.Callback{
+	"Checkpoint":            func() callback.Callback { return &callback.Checkpoint{} },
+	"EarlyStoppingOnMetric": func() callback.Callback { return &callback.EarlyStoppingOnMetric{} },
+	"Logger":                func() callback.Callback { return &callback.Logger{} },
+	"RecordStats":           func() callback.Callback { return &callback.RecordStats{} },
+	"ReduceLROnPlateau":     func() callback.Callback { return &callback.ReduceLROnPlateau{} },
+}
+
+// LoadConfig reads a .yaml, .yml or .json config file
+func LoadConfig(path string) (Config, error) {
+	contents, e := ioutil.ReadFile(path)
+	if e != nil {
+		return Config{}, e
+	}
+
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension == ".yaml" || extension == ".yml" {
+		return ParseYamlConfig(contents)
+	}
+
+	return ParseJsonConfig(contents)
+}
+
+func ParseJsonConfig(contents []byte) (Config, error) {
+	var config Config
+	e := json.Unmarshal(contents, &config)
+	if e != nil {
+		return config, e
+	}
+	return config, nil
+}
+
+// ParseYamlConfig converts the YAML to JSON first so numbers are decoded as float64 the same as in JSON configs
+func ParseYamlConfig(contents []byte) (Config, error) {
+	var decoded interface{}
+	e := yaml.Unmarshal(contents, &decoded)
+	if e != nil {
+		return Config{}, e
+	}
+	jsonBytes, e := json.Marshal(decoded)
+	if e != nil {
+		return Config{}, e
+	}
+	return ParseJsonConfig(jsonBytes)
+}
+
+// FromConfig builds the layers of the config into a model, which is then compiled with GetCompileConfig
+func FromConfig(
+	errorHandler *cberrors.ErrorsContainer,
+	logger *cblog.Logger,
+	config Config,
+) (*TfkgModel, error) {
+	if len(config.Layers) == 0 {
+		e := fmt.Errorf("no layers set in the model config")
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	isSequential := len(config.Outputs) == 0
+	for _, layerConfig := range config.Layers {
+		if len(layerConfig.Inputs) > 0 {
+			isSequential = false
+		}
+	}
+
+	var layers []layer.Layer
+	layersByName := make(map[string]layer.Layer)
+	for offset, layerConfig := range config.Layers {
+		layerConfigValues := make(map[string]interface{})
+		for name, value := range layerConfig.Config {
+			layerConfigValues[name] = value
+		}
+		if _, ok := layerConfigValues["name"]; !ok && layerConfig.Name != "" {
+			layerConfigValues["name"] = layerConfig.Name
+		}
+
+		l, e := newLayer(layerConfig.ClassName, layerConfigValues)
+		if e != nil {
+			e = fmt.Errorf("error building layer %d of the model config: %s", offset, e.Error())
+			errorHandler.Error(e)
+			return nil, e
+		}
+		if _, ok := layersByName[l.GetName()]; ok {
+			e = fmt.Errorf("the model config has more than one layer named: %s", l.GetName())
+			errorHandler.Error(e)
+			return nil, e
+		}
+
+		if !isSequential {
+			var inputs []layer.Layer
+			for _, inputName := range layerConfig.Inputs {
+				input, ok := layersByName[inputName]
+				if !ok {
+					e = fmt.Errorf("the input %s of layer %s must be defined before it", inputName, l.GetName())
+					errorHandler.Error(e)
+					return nil, e
+				}
+				inputs = append(inputs, input)
+			}
+			if len(inputs) == 0 && layerConfig.ClassName != "InputLayer" && len(layers) > 0 {
+				inputs = append(inputs, layers[len(layers)-1])
+			}
+			if len(inputs) > 0 {
+				l.SetInputs(inputs...)
+			}
+		}
+
+		layers = append(layers, l)
+		layersByName[l.GetName()] = l
+	}
+
+	if isSequential {
+		if config.Layers[0].ClassName != "InputLayer" {
+			e := fmt.Errorf("the first layer of a sequential model config must be an InputLayer, got: %s", config.Layers[0].ClassName)
+			errorHandler.Error(e)
+			return nil, e
+		}
+		return NewSequentialModel(logger, errorHandler, layers[0], layers[1:]...), nil
+	}
+
+	outputs := []layer.Layer{layers[len(layers)-1]}
+	if len(config.Outputs) > 0 {
+		outputs = nil
+		for _, outputName := range config.Outputs {
+			output, ok := layersByName[outputName]
+			if !ok {
+				e := fmt.Errorf("unknown output layer in the model config: %s", outputName)
+				errorHandler.Error(e)
+				return nil, e
+			}
+			outputs = append(outputs, output)
+		}
+	}
+
+	return NewModel(logger, errorHandler, outputs...), nil
+}
+
+// GetCompileConfig builds the optimizer and losses of the config, with the model info saved to saveDir
+func (c Config) GetCompileConfig(saveDir string) (CompileConfig, error) {
+	compileConfig := CompileConfig{
+		LossWeights:               c.LossWeights,
+		ModelInfoSaveDir:          saveDir,
+		BatchSize:                 c.BatchSize,
+		CpuInference:              c.CpuInference,
+		GradientAccumulationSteps: c.GradientAccumulationSteps,
+		WeightAveraging:           len(c.Training.WeightAveraging) > 0,
+	}
+
+	if c.Optimizer != nil {
+		o, e := newOptimizer(*c.Optimizer)
+		if e != nil {
+			return compileConfig, e
+		}
+		compileConfig.Optimizer = o
+	}
+
+	if c.Loss != nil {
+		l, e := newLoss(*c.Loss)
+		if e != nil {
+			return compileConfig, e
+		}
+		compileConfig.Loss = l
+	}
+
+	for _, lossConfig := range c.Losses {
+		l, e := newLoss(lossConfig)
+		if e != nil {
+			return compileConfig, e
+		}
+		compileConfig.Losses = append(compileConfig.Losses, l)
+	}
+
+	return compileConfig, nil
+}
+
+// GetFitConfig builds the metrics and callbacks of the config. The Logger callback logs to logger and the
+// Checkpoint, RecordStats and Logger progress callbacks save to saveDir unless their config sets a dir
+func (c Config) GetFitConfig(logger *cblog.Logger, saveDir string) (FitConfig, error) {
+	fitConfig := FitConfig{
+		Epochs:      c.Training.Epochs,
+		BatchSize:   c.BatchSize,
+		Validation:  c.Training.Validation,
+		PreFetch:    c.Training.PreFetch,
+		Verbose:     c.Training.Verbose,
+		ShuffleSeed: c.Training.ShuffleSeed,
+	}
+
+	var e error
+	fitConfig.Metrics, e = newConfigMetrics(c.Training.Metrics)
+	if e != nil {
+		return fitConfig, e
+	}
+	for _, metricConfigs := range c.Training.OutputMetrics {
+		metrics, e := newConfigMetrics(metricConfigs)
+		if e != nil {
+			return fitConfig, e
+		}
+		fitConfig.OutputMetrics = append(fitConfig.OutputMetrics, metrics)
+	}
+
+	for _, callbackConfig := range c.Training.Callbacks {
+		newCallback, ok := configCallbacks[callbackConfig.ClassName]
+		if !ok {
+			return fitConfig, fmt.Errorf("unknown callback class: %s", callbackConfig.ClassName)
+		}
+		call := newCallback()
+		e = decodeConfig(callbackConfig.Config, call)
+		if e != nil {
+			return fitConfig, fmt.Errorf("error decoding the config of callback %s: %s", callbackConfig.ClassName, e.Error())
+		}
+
+		switch typedCall := call.(type) {
+		case *callback.Checkpoint:
+			if typedCall.SaveDir == "" {
+				typedCall.SaveDir = saveDir
+			}
+		case *callback.RecordStats:
+			if typedCall.RecordDir == "" {
+				typedCall.RecordDir = saveDir
+			}
+		case *callback.Logger:
+			typedCall.FileLogger = logger
+			if typedCall.ProgressLogDir == "" {
+				typedCall.ProgressLogDir = saveDir
+			}
+		}
+
+		fitConfig.Callbacks = append(fitConfig.Callbacks, call)
+	}
+
+	if len(c.Training.WeightAveraging) > 0 {
+		weightAveraging := &WeightAveragingConfig{}
+		e = decodeConfig(c.Training.WeightAveraging, weightAveraging)
+		if e != nil {
+			return fitConfig, fmt.Errorf("error decoding the weight averaging config: %s", e.Error())
+		}
+		fitConfig.WeightAveraging = weightAveraging
+	}
+
+	return fitConfig, nil
+}
+
+func newConfigMetrics(metricConfigs []ObjectConfig) ([]metric.Metric, error) {
+	var metrics []metric.Metric
+	for _, metricConfig := range metricConfigs {
+		newMetric, ok := configMetrics[metricConfig.ClassName]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric class: %s", metricConfig.ClassName)
+		}
+		m := newMetric()
+		e := decodeConfig(metricConfig.Config, m)
+		if e != nil {
+			return nil, fmt.Errorf("error decoding the config of metric %s: %s", metricConfig.ClassName, e.Error())
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/data"
+	"github.com/codingbeard/tfkg/preprocessor"
+)
+
+type YProcessorType string
+
+var (
+	YProcessorSparseCategoricalTokenizing YProcessorType = "sparse_categorical_tokenizing"
+	YProcessorSparseCategorical           YProcessorType = "sparse_categorical"
+	YProcessorBinaryTokenizing            YProcessorType = "binary_tokenizing"
+	YProcessorBinary                      YProcessorType = "binary"
+	YProcessorMultiLabelTokenizing        YProcessorType = "multi_label_tokenizing"
+	YProcessorSequenceLabelTokenizing     YProcessorType = "sequence_label_tokenizing"
+	YProcessorScaling                     YProcessorType = "scaling"
+)
+
+// DatasetConfig describes a data.SingleFileDataset and its processors
+type DatasetConfig struct {
+	FilePath string `json:"file_path"`
+	// CacheDir is where the processors are fit and cached, defaults to training-cache
+	CacheDir          string                   `json:"cache_dir"`
+	TrainPercent      float32                  `json:"train_percent"`
+	ValPercent        float32                  `json:"val_percent"`
+	TestPercent       float32                  `json:"test_percent"`
+	IgnoreParseErrors bool                     `json:"ignore_parse_errors"`
+	SkipHeaders       bool                     `json:"skip_headers"`
+	ClassWeights      map[int]float32          `json:"class_weights"`
+	Y                 YProcessorConfig         `json:"y"`
+	ExtraY            []YProcessorConfig       `json:"extra_y"`
+	Processors        []DatasetProcessorConfig `json:"processors"`
+}
+
+// YProcessorConfig selects one of the preprocessor.New<Type>YProcessor functions
+type YProcessorConfig struct {
+	Type       YProcessorType `json:"type"`
+	LineOffset int            `json:"line_offset"`
+	// Delimiter separates the labels of the multi_label_tokenizing type
+	Delimiter string `json:"delimiter"`
+	// MaxLen is the number of labels of the sequence_label_tokenizing type
+	MaxLen int `json:"max_len"`
+	// ScalerMode is the preprocessor.ScalerMode of the scaling type
+	ScalerMode preprocessor.ScalerMode `json:"scaler_mode"`
+}
+
+// DatasetProcessorConfig describes a preprocessor.Processor. Reader and Converter are function names from the
+// preprocessor package, E.G. ReadCsvFloat32s and ConvertDivisorToFloat32SliceTensor
+type DatasetProcessorConfig struct {
+	Name        string `json:"name"`
+	LineOffset  int    `json:"line_offset"`
+	DataLength  int    `json:"data_length"`
+	RequiresFit bool   `json:"requires_fit"`
+	Reader      string `json:"reader"`
+	// ReaderDelimiter is the delimiter of the ReadMultiLabelStrings reader
+	ReaderDelimiter string                  `json:"reader_delimiter"`
+	Converter       string                  `json:"converter"`
+	Divisor         bool                    `json:"divisor"`
+	Scaler          preprocessor.ScalerMode `json:"scaler"`
+	Tokenizer       *TokenizerConfig        `json:"tokenizer"`
+	Image           *ImageConfig            `json:"image"`
+}
+
+type TokenizerConfig struct {
+	MaxLen                int    `json:"max_len"`
+	NumWords              int    `json:"num_words"`
+	IsCategoryTokenizer   bool   `json:"is_category_tokenizer"`
+	IsMultiLabelTokenizer bool   `json:"is_multi_label_tokenizer"`
+	Filters               string `json:"filters"`
+	DisableFiltering      bool   `json:"disable_filtering"`
+	KeepUnknownWords      bool   `json:"keep_unknown_words"`
+}
+
+type ImageConfig struct {
+	ColorMode preprocessor.ImageColor `json:"color_mode"`
+	ResizeX   int                     `json:"resize_x"`
+	ResizeY   int                     `json:"resize_y"`
+}
+
+// GetDataset creates the dataset of the config and its processors
+func (c Config) GetDataset(
+	logger *cblog.Logger,
+	errorHandler *cberrors.ErrorsContainer,
+) (*data.SingleFileDataset, error) {
+	if c.Dataset == nil {
+		e := fmt.Errorf("no dataset set in the model config")
+		errorHandler.Error(e)
+		return nil, e
+	}
+	datasetConfig := *c.Dataset
+	if datasetConfig.CacheDir == "" {
+		datasetConfig.CacheDir = "training-cache"
+	}
+
+	yProcessor, e := datasetConfig.Y.newProcessor(errorHandler, datasetConfig.CacheDir)
+	if e != nil {
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	var extraYProcessors []*preprocessor.Processor
+	for _, yConfig := range datasetConfig.ExtraY {
+		extraYProcessor, e := yConfig.newProcessor(errorHandler, datasetConfig.CacheDir)
+		if e != nil {
+			errorHandler.Error(e)
+			return nil, e
+		}
+		extraYProcessors = append(extraYProcessors, extraYProcessor)
+	}
+
+	var processors []*preprocessor.Processor
+	for _, processorConfig := range datasetConfig.Processors {
+		processor, e := processorConfig.newProcessor(errorHandler, datasetConfig.CacheDir)
+		if e != nil {
+			errorHandler.Error(e)
+			return nil, e
+		}
+		processors = append(processors, processor)
+	}
+
+	return data.NewSingleFileDataset(
+		logger,
+		errorHandler,
+		data.SingleFileDatasetConfig{
+			FilePath:          datasetConfig.FilePath,
+			CacheDir:          datasetConfig.CacheDir,
+			TrainPercent:      datasetConfig.TrainPercent,
+			ValPercent:        datasetConfig.ValPercent,
+			TestPercent:       datasetConfig.TestPercent,
+			IgnoreParseErrors: datasetConfig.IgnoreParseErrors,
+			SkipHeaders:       datasetConfig.SkipHeaders,
+			ClassWeights:      datasetConfig.ClassWeights,
+			ExtraYProcessors:  extraYProcessors,
+		},
+		yProcessor,
+		processors...,
+	)
+}
+
+func (c YProcessorConfig) newProcessor(errorHandler *cberrors.ErrorsContainer, cacheDir string) (*preprocessor.Processor, error) {
+	switch c.Type {
+	case YProcessorSparseCategoricalTokenizing:
+		return preprocessor.NewSparseCategoricalTokenizingYProcessor(errorHandler, cacheDir, c.LineOffset), nil
+	case YProcessorSparseCategorical:
+		return preprocessor.NewSparseCategoricalYProcessor(errorHandler, cacheDir, c.LineOffset), nil
+	case YProcessorBinaryTokenizing:
+		return preprocessor.NewBinaryTokenizingYProcessor(errorHandler, cacheDir, c.LineOffset), nil
+	case YProcessorBinary:
+		return preprocessor.NewBinaryYProcessor(errorHandler, cacheDir, c.LineOffset), nil
+	case YProcessorMultiLabelTokenizing:
+		if c.Delimiter == "" {
+			return nil, fmt.Errorf("no delimiter set for the %s y processor", c.Type)
+		}
+		return preprocessor.NewMultiLabelTokenizingYProcessor(errorHandler, cacheDir, c.LineOffset, c.Delimiter), nil
+	case YProcessorSequenceLabelTokenizing:
+		if c.MaxLen == 0 {
+			return nil, fmt.Errorf("no max_len set for the %s y processor", c.Type)
+		}
+		return preprocessor.NewSequenceLabelTokenizingYProcessor(errorHandler, cacheDir, c.LineOffset, c.MaxLen), nil
+	case YProcessorScaling:
+		if c.ScalerMode == "" {
+			c.ScalerMode = preprocessor.ScalerModeStandard
+		}
+		return preprocessor.NewScalingYProcessor(errorHandler, cacheDir, c.LineOffset, c.ScalerMode), nil
+	}
+	return nil, fmt.Errorf("unknown y processor type: %s", c.Type)
+}
+
+func (c DatasetProcessorConfig) newProcessor(errorHandler *cberrors.ErrorsContainer, cacheDir string) (*preprocessor.Processor, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("no name set for a processor of the dataset config")
+	}
+
+	processorConfig := preprocessor.ProcessorConfig{
+		CacheDir:    cacheDir,
+		LineOffset:  c.LineOffset,
+		DataLength:  c.DataLength,
+		RequiresFit: c.RequiresFit,
+	}
+
+	if c.Reader == "ReadMultiLabelStrings" {
+		processorConfig.Reader = preprocessor.ReadMultiLabelStrings(c.ReaderDelimiter)
+	} else {
+		reader, ok := preprocessor.Readers[c.Reader]
+		if !ok {
+			return nil, fmt.Errorf("unknown reader for processor %s: %s", c.Name, c.Reader)
+		}
+		processorConfig.Reader = reader
+	}
+	converter, ok := preprocessor.Converters[c.Converter]
+	if !ok {
+		return nil, fmt.Errorf("unknown converter for processor %s: %s", c.Name, c.Converter)
+	}
+	processorConfig.Converter = converter
+
+	if c.Divisor {
+		processorConfig.Divisor = preprocessor.NewDivisor(errorHandler)
+	}
+	if c.Scaler != "" {
+		processorConfig.Scaler = preprocessor.NewScaler(errorHandler, c.Scaler)
+	}
+	if c.Tokenizer != nil {
+		processorConfig.Tokenizer = preprocessor.NewTokenizer(
+			errorHandler,
+			c.Tokenizer.MaxLen,
+			c.Tokenizer.NumWords,
+			preprocessor.TokenizerConfig{
+				IsCategoryTokenizer:   c.Tokenizer.IsCategoryTokenizer,
+				IsMultiLabelTokenizer: c.Tokenizer.IsMultiLabelTokenizer,
+				Filters:               c.Tokenizer.Filters,
+				DisableFiltering:      c.Tokenizer.DisableFiltering,
+				KeepUnknownWords:      c.Tokenizer.KeepUnknownWords,
+			},
+		)
+	}
+	if c.Image != nil {
+		processorConfig.Image = preprocessor.NewImage(errorHandler, preprocessor.ImageConfig{
+			ColorMode: c.Image.ColorMode,
+			ResizeX:   c.Image.ResizeX,
+			ResizeY:   c.Image.ResizeY,
+		})
+	}
+
+	return preprocessor.NewProcessor(errorHandler, c.Name, processorConfig), nil
+}
@@ -0,0 +1,300 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/codingbeard/tfkg/layer"
+	"github.com/codingbeard/tfkg/layer/constraint"
+	"github.com/codingbeard/tfkg/layer/initializer"
+	"github.com/codingbeard/tfkg/layer/regularizer"
+	"github.com/codingbeard/tfkg/loss"
+	"github.com/codingbeard/tfkg/optimizer"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ObjectConfig is an object in the keras format, E.G. {"class_name": "Adam", "config": {"learning_rate": 0.001}}
+type ObjectConfig struct {
+	ClassName string                 `json:"class_name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+var (
+	layerType       = reflect.TypeOf((*layer.Layer)(nil)).Elem()
+	initializerType = reflect.TypeOf((*initializer.Initializer)(nil)).Elem()
+	regularizerType = reflect.TypeOf((*regularizer.Regularizer)(nil)).Elem()
+	constraintType  = reflect.TypeOf((*constraint.Constraint)(nil)).Elem()
+	optimizerType   = reflect.TypeOf((*optimizer.Optimizer)(nil)).Elem()
+	scheduleType    = reflect.TypeOf((*optimizer.LearningRateSchedule)(nil)).Elem()
+	lossType        = reflect.TypeOf((*loss.Loss)(nil)).Elem()
+	shapeType       = reflect.TypeOf(tf.Shape{})
+)
+
+func newLayer(className string, config map[string]interface{}) (layer.Layer, error) {
+	object, e := newObject(layerType, className, config)
+	if e != nil {
+		return nil, e
+	}
+	return object.Interface().(layer.Layer), nil
+}
+
+func newOptimizer(config ObjectConfig) (optimizer.Optimizer, error) {
+	object, e := newObject(optimizerType, config.ClassName, config.Config)
+	if e != nil {
+		return nil, e
+	}
+	return object.Interface().(optimizer.Optimizer), nil
+}
+
+func newLoss(config ObjectConfig) (loss.Loss, error) {
+	object, e := newObject(lossType, config.ClassName, config.Config)
+	if e != nil {
+		return nil, e
+	}
+	return object.Interface().(loss.Loss), nil
+}
+
+// getConstructor finds the class in the registry of the package of the interface the object is built for
+func getConstructor(objectType reflect.Type, className string) (interface{}, []string, bool) {
+	switch objectType {
+	case layerType:
+		constructor, ok := layer.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	case initializerType:
+		constructor, ok := initializer.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	case regularizerType:
+		constructor, ok := regularizer.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	case constraintType:
+		constructor, ok := constraint.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	case optimizerType:
+		constructor, ok := optimizer.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	case scheduleType:
+		constructor, ok := optimizer.Schedules[className]
+		return constructor.Function, constructor.Required, ok
+	case lossType:
+		constructor, ok := loss.Constructors[className]
+		return constructor.Function, constructor.Required, ok
+	}
+	return nil, nil, false
+}
+
+// newObject calls the constructor of the class with its required config values, then the Set<Name> method of every
+// other config key. An unknown class or config key is an error so typos in config files are not silently ignored
+func newObject(objectType reflect.Type, className string, config map[string]interface{}) (reflect.Value, error) {
+	function, required, ok := getConstructor(objectType, className)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown %s class: %s", objectType.Name(), className)
+	}
+
+	functionValue := reflect.ValueOf(function)
+	var args []reflect.Value
+	for offset, name := range required {
+		value, ok := config[name]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s requires the config key: %s", className, name)
+		}
+		arg, e := convertConfigValue(functionValue.Type().In(offset), value)
+		if e != nil {
+			return reflect.Value{}, fmt.Errorf("%s %s: %s", className, name, e.Error())
+		}
+		args = append(args, arg)
+	}
+	object := functionValue.Call(args)[0]
+
+	var names []string
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		isRequired := false
+		for _, requiredName := range required {
+			if name == requiredName {
+				isRequired = true
+				break
+			}
+		}
+		if isRequired {
+			continue
+		}
+
+		value := config[name]
+		setter := object.MethodByName("Set" + snakeCaseToPascalCase(name))
+		if _, isObject := value.(map[string]interface{}); isObject {
+			// E.G. a learning_rate which is a schedule
+			scheduleSetter := object.MethodByName("Set" + snakeCaseToPascalCase(name) + "Schedule")
+			if scheduleSetter.IsValid() {
+				setter = scheduleSetter
+			}
+		}
+		if !setter.IsValid() || setter.Type().NumIn() != 1 || setter.Type().IsVariadic() {
+			return reflect.Value{}, fmt.Errorf("%s has no config key: %s", className, name)
+		}
+		arg, e := convertConfigValue(setter.Type().In(0), value)
+		if e != nil {
+			return reflect.Value{}, fmt.Errorf("%s %s: %s", className, name, e.Error())
+		}
+		setter.Call([]reflect.Value{arg})
+	}
+
+	if !object.Type().Implements(objectType) {
+		return reflect.Value{}, fmt.Errorf("%s is not a %s", className, objectType.Name())
+	}
+
+	return object, nil
+}
+
+// convertConfigValue converts a value decoded from JSON to the type of a constructor or setter param. Initializers,
+// regularizers, constraints, schedules and layers are either a class name such as "glorot_uniform" or an ObjectConfig
+func convertConfigValue(valueType reflect.Type, value interface{}) (reflect.Value, error) {
+	switch valueType {
+	case layerType, initializerType, regularizerType, constraintType, optimizerType, scheduleType, lossType:
+		return convertConfigObject(valueType, value)
+	case shapeType:
+		return convertConfigShape(value)
+	}
+
+	if value == nil {
+		return reflect.Zero(valueType), nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch valueType.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int32, reflect.Int64:
+		switch reflectValue.Kind() {
+		case reflect.Float64, reflect.Int:
+			return reflectValue.Convert(valueType), nil
+		}
+	case reflect.String:
+		if reflectValue.Kind() == reflect.String {
+			return reflectValue.Convert(valueType), nil
+		}
+	case reflect.Bool:
+		if reflectValue.Kind() == reflect.Bool {
+			return reflectValue, nil
+		}
+	case reflect.Interface:
+		if reflectValue.Type().Implements(valueType) {
+			return reflectValue, nil
+		}
+	case reflect.Map:
+		if reflectValue.Type().AssignableTo(valueType) {
+			return reflectValue, nil
+		}
+	case reflect.Slice:
+		if reflectValue.Type().AssignableTo(valueType) {
+			return reflectValue, nil
+		}
+		if reflectValue.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(valueType, reflectValue.Len(), reflectValue.Len())
+			for offset := 0; offset < reflectValue.Len(); offset++ {
+				element, e := convertConfigValue(valueType.Elem(), reflectValue.Index(offset).Interface())
+				if e != nil {
+					return reflect.Value{}, e
+				}
+				slice.Index(offset).Set(element)
+			}
+			return slice, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %#v as %s", value, valueType.String())
+}
+
+func convertConfigObject(objectType reflect.Type, value interface{}) (reflect.Value, error) {
+	if value == nil {
+		switch objectType {
+		case initializerType:
+			return reflect.ValueOf(&initializer.NilInitializer{}), nil
+		case regularizerType:
+			return reflect.ValueOf(&regularizer.NilRegularizer{}), nil
+		case constraintType:
+			return reflect.ValueOf(&constraint.NilConstraint{}), nil
+		}
+		return reflect.Value{}, fmt.Errorf("no %s set", objectType.Name())
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		className := typedValue
+		if _, _, ok := getConstructor(objectType, className); !ok {
+			className = snakeCaseToPascalCase(className)
+		}
+		return newObject(objectType, className, nil)
+	case map[string]interface{}:
+		var config ObjectConfig
+		e := remarshalConfig(typedValue, &config)
+		if e != nil {
+			return reflect.Value{}, e
+		}
+		return newObject(objectType, config.ClassName, config.Config)
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %#v as %s", value, objectType.Name())
+}
+
+// convertConfigShape reads a shape such as [null, 4], where null is a dimension of unknown size
+func convertConfigShape(value interface{}) (reflect.Value, error) {
+	if value == nil {
+		return reflect.ValueOf(tf.Shape{}), nil
+	}
+	dims, ok := value.([]interface{})
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("cannot use %#v as a shape", value)
+	}
+	var shape []int64
+	for _, dim := range dims {
+		if dim == nil {
+			shape = append(shape, -1)
+			continue
+		}
+		size, ok := dim.(float64)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("cannot use %#v as a shape", value)
+		}
+		shape = append(shape, int64(size))
+	}
+	return reflect.ValueOf(tf.MakeShape(shape...)), nil
+}
+
+func remarshalConfig(value interface{}, v interface{}) error {
+	jsonBytes, e := json.Marshal(value)
+	if e != nil {
+		return e
+	}
+	return json.Unmarshal(jsonBytes, v)
+}
+
+// decodeConfig decodes a config map into the exported fields of a struct, E.G. {"metric_name": "val_acc"} into
+// MetricName. Unknown keys are an error
+func decodeConfig(config map[string]interface{}, v interface{}) error {
+	fields := make(map[string]interface{})
+	for name, value := range config {
+		fields[snakeCaseToPascalCase(name)] = value
+	}
+	jsonBytes, e := json.Marshal(fields)
+	if e != nil {
+		return e
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func snakeCaseToPascalCase(str string) string {
+	words := strings.Split(str, "_")
+	for offset, word := range words {
+		if word != "" {
+			words[offset] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
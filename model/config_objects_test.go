@@ -0,0 +1,419 @@
+package model
+
+import (
+	"encoding/json"
+	"github.com/codingbeard/tfkg/callback"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+	"reflect"
+	"testing"
+)
+
+// kerasConfig decodes the keras config of an object the same way it is written to the model definition
+func kerasConfig(t *testing.T, object interface{ GetKerasLayerConfig() interface{} }) map[string]interface{} {
+	jsonBytes, e := json.Marshal(object.GetKerasLayerConfig())
+	if e != nil {
+		t.Fatal(e)
+	}
+	var config map[string]interface{}
+	e = json.Unmarshal(jsonBytes, &config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return config
+}
+
+// withoutNames drops the names of nested objects, which are unique per process
+func withoutNames(config map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{})
+	for key, value := range config {
+		if key == "name" {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = withoutNames(nested)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+func decodeTestConfig(t *testing.T, jsonString string) map[string]interface{} {
+	var config map[string]interface{}
+	e := json.Unmarshal([]byte(jsonString), &config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return config
+}
+
+func TestNewObject(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType reflect.Type
+		className  string
+		config     string
+		expected   map[string]interface{}
+	}{
+		{
+			name:       "required and optional keys",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"units": 8, "activation": "relu", "use_bias": false, "name": "dense_1"}`,
+			expected: map[string]interface{}{
+				"units":      float64(8),
+				"activation": "relu",
+				"use_bias":   false,
+				"name":       "dense_1",
+			},
+		},
+		{
+			name:       "nested objects by class name and keras object",
+			objectType: layerType,
+			className:  "Dense",
+			config: `{
+				"units": 4,
+				"kernel_initializer": "glorot_uniform",
+				"kernel_regularizer": {"class_name": "L2", "config": {"l2": 0.01}}
+			}`,
+			expected: map[string]interface{}{
+				"kernel_initializer": map[string]interface{}{
+					"class_name": "GlorotUniform",
+					"config":     map[string]interface{}{"seed": nil},
+				},
+				"kernel_regularizer": map[string]interface{}{
+					"class_name": "L2",
+					"config":     map[string]interface{}{"l2": 0.01},
+				},
+			},
+		},
+		{
+			name:       "schedule setter",
+			objectType: optimizerType,
+			className:  "Adam",
+			config: `{
+				"beta_1": 0.8,
+				"learning_rate": {
+					"class_name": "ExponentialDecay",
+					"config": {"initial_learning_rate": 0.1, "decay_steps": 100, "decay_rate": 0.5, "staircase": true}
+				}
+			}`,
+			expected: map[string]interface{}{
+				"beta_1": 0.8,
+				"learning_rate": map[string]interface{}{
+					"class_name": "ExponentialDecay",
+					"config": map[string]interface{}{
+						"initial_learning_rate": 0.1,
+						"decay_steps":           float64(100),
+						"decay_rate":            0.5,
+						"staircase":             true,
+					},
+				},
+			},
+		},
+		{
+			name:       "no config",
+			objectType: lossType,
+			className:  "SparseCategoricalCrossentropy",
+			config:     `null`,
+			expected: map[string]interface{}{
+				"from_logits": false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object, e := newObject(test.objectType, test.className, decodeTestConfig(t, test.config))
+			if e != nil {
+				t.Fatal(e)
+			}
+			keras, ok := object.Interface().(interface{ GetKerasLayerConfig() interface{} })
+			if !ok {
+				t.Fatalf("%s has no keras config", test.className)
+			}
+			config, _ := kerasConfig(t, keras)["config"].(map[string]interface{})
+			for name, expected := range test.expected {
+				actual := config[name]
+				if nested, ok := actual.(map[string]interface{}); ok {
+					actual = withoutNames(nested)
+				}
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("%s: expected %#v, got %#v", name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestNewObjectErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType reflect.Type
+		className  string
+		config     string
+		expected   string
+	}{
+		{
+			name:       "unknown class",
+			objectType: layerType,
+			className:  "Dense2",
+			config:     `{}`,
+			expected:   "unknown Layer class: Dense2",
+		},
+		{
+			name:       "missing required key",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"activation": "relu"}`,
+			expected:   "Dense requires the config key: units",
+		},
+		{
+			name:       "unknown key",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"units": 8, "unit_count": 8}`,
+			expected:   "Dense has no config key: unit_count",
+		},
+		{
+			name:       "wrong type of required key",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"units": "8"}`,
+			expected:   `Dense units: cannot use "8" as float64`,
+		},
+		{
+			name:       "wrong type of optional key",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"units": 8, "use_bias": 1}`,
+			expected:   `Dense use_bias: cannot use 1 as bool`,
+		},
+		{
+			name:       "unknown nested class",
+			objectType: layerType,
+			className:  "Dense",
+			config:     `{"units": 8, "kernel_initializer": "not_an_initializer"}`,
+			expected:   "Dense kernel_initializer: unknown Initializer class: NotAnInitializer",
+		},
+		{
+			name:       "class of the wrong type",
+			objectType: optimizerType,
+			className:  "Dense",
+			config:     `{"units": 8}`,
+			expected:   "unknown Optimizer class: Dense",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, e := newObject(test.objectType, test.className, decodeTestConfig(t, test.config))
+			if e == nil {
+				t.Fatalf("expected the error: %s", test.expected)
+			}
+			if e.Error() != test.expected {
+				t.Errorf("expected the error %q, got %q", test.expected, e.Error())
+			}
+		})
+	}
+}
+
+func TestConvertConfigValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType reflect.Type
+		value     interface{}
+		expected  interface{}
+		invalid   bool
+	}{
+		{name: "float64 to float32", valueType: reflect.TypeOf(float32(0)), value: 0.5, expected: float32(0.5)},
+		{name: "float64 to int", valueType: reflect.TypeOf(0), value: float64(3), expected: 3},
+		{name: "int to int64", valueType: reflect.TypeOf(int64(0)), value: 3, expected: int64(3)},
+		{name: "string", valueType: reflect.TypeOf(""), value: "same", expected: "same"},
+		{name: "named string", valueType: reflect.TypeOf(callback.ModeVal), value: "val", expected: callback.ModeVal},
+		{name: "bool", valueType: reflect.TypeOf(false), value: true, expected: true},
+		{name: "nil is the zero value", valueType: reflect.TypeOf(0.0), value: nil, expected: 0.0},
+		{name: "interface", valueType: reflect.TypeOf((*interface{})(nil)).Elem(), value: "x", expected: "x"},
+		{
+			name:      "slice of interfaces",
+			valueType: reflect.TypeOf([]interface{}{}),
+			value:     []interface{}{float64(1), "a"},
+			expected:  []interface{}{float64(1), "a"},
+		},
+		{
+			name:      "converted slice",
+			valueType: reflect.TypeOf([]int{}),
+			value:     []interface{}{float64(1), float64(2)},
+			expected:  []int{1, 2},
+		},
+		{
+			name:      "map",
+			valueType: reflect.TypeOf(map[string]interface{}{}),
+			value:     map[string]interface{}{"a": float64(1)},
+			expected:  map[string]interface{}{"a": float64(1)},
+		},
+		{name: "string to float", valueType: reflect.TypeOf(0.0), value: "1", invalid: true},
+		{name: "float to string", valueType: reflect.TypeOf(""), value: 1.0, invalid: true},
+		{name: "float to bool", valueType: reflect.TypeOf(false), value: 1.0, invalid: true},
+		{name: "bad slice element", valueType: reflect.TypeOf([]int{}), value: []interface{}{"a"}, invalid: true},
+		{name: "no layer", valueType: layerType, value: nil, invalid: true},
+		{name: "number as an initializer", valueType: initializerType, value: 1.0, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, e := convertConfigValue(test.valueType, test.value)
+			if test.invalid {
+				if e == nil {
+					t.Errorf("expected an error, got %#v", value.Interface())
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !reflect.DeepEqual(value.Interface(), test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, value.Interface())
+			}
+		})
+	}
+}
+
+func TestConvertConfigValueNilObjects(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType reflect.Type
+		expected  string
+	}{
+		{name: "initializer", valueType: initializerType, expected: "*initializer.NilInitializer"},
+		{name: "regularizer", valueType: regularizerType, expected: "*regularizer.NilRegularizer"},
+		{name: "constraint", valueType: constraintType, expected: "*constraint.NilConstraint"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, e := convertConfigValue(test.valueType, nil)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if value.Type().String() != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, value.Type().String())
+			}
+		})
+	}
+}
+
+func TestConvertConfigShape(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []int64
+		invalid  bool
+	}{
+		{name: "known dims", value: `[2, 3]`, expected: []int64{2, 3}},
+		{name: "unknown dim", value: `[null, 4]`, expected: []int64{-1, 4}},
+		{name: "string dim", value: `["a", 4]`, invalid: true},
+		{name: "not a list", value: `4`, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value interface{}
+			e := json.Unmarshal([]byte(test.value), &value)
+			if e != nil {
+				t.Fatal(e)
+			}
+			shapeValue, e := convertConfigValue(shapeType, value)
+			if test.invalid {
+				if e == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			shape := shapeValue.Interface().(tf.Shape)
+			if shape.NumDimensions() != len(test.expected) {
+				t.Fatalf("expected %d dims, got %d", len(test.expected), shape.NumDimensions())
+			}
+			for dim, size := range test.expected {
+				if shape.Size(dim) != size {
+					t.Errorf("dim %d: expected %d, got %d", dim, size, shape.Size(dim))
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected WeightAveragingConfig
+		invalid  bool
+	}{
+		{
+			name:     "snake case keys",
+			config:   `{"mode": "ema", "decay": 0.99, "start_epoch": 2, "apply_at_end": true}`,
+			expected: WeightAveragingConfig{Mode: "ema", Decay: 0.99, StartEpoch: 2, ApplyAtEnd: true},
+		},
+		{
+			name:     "empty",
+			config:   `{}`,
+			expected: WeightAveragingConfig{},
+		},
+		{
+			name:    "unknown key",
+			config:  `{"mode": "ema", "decay_rate": 0.99}`,
+			invalid: true,
+		},
+		{
+			name:    "wrong type",
+			config:  `{"start_epoch": "2"}`,
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var config WeightAveragingConfig
+			e := decodeConfig(decodeTestConfig(t, test.config), &config)
+			if test.invalid {
+				if e == nil {
+					t.Errorf("expected an error, got %#v", config)
+				}
+				return
+			}
+			if e != nil {
+				t.Fatal(e)
+			}
+			if config != test.expected {
+				t.Errorf("expected %#v, got %#v", test.expected, config)
+			}
+		})
+	}
+}
+
+func TestSnakeCaseToPascalCase(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "units", expected: "Units"},
+		{value: "beta_1", expected: "Beta1"},
+		{value: "kernel_initializer", expected: "KernelInitializer"},
+		{value: "glorot_uniform", expected: "GlorotUniform"},
+		{value: "GlorotUniform", expected: "GlorotUniform"},
+		{value: "_private__key_", expected: "PrivateKey"},
+		{value: "", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			value := snakeCaseToPascalCase(test.value)
+			if value != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"github.com/codingbeard/tfkg/data"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+)
+
+type DistillConfig struct {
+	// Temperature softens the predictions of the teacher and student before they are compared, defaults to 1
+	Temperature float32
+	// Alpha is the weight of the distillation loss, the loss against the labels is weighted by 1 - Alpha. Defaults to
+	// 0.5, use Fit to train against the labels alone
+	Alpha float32
+}
+
+type distillation struct {
+	teacher *TfkgModel
+	config  DistillConfig
+}
+
+type distillInputOps struct {
+	teacher           *TfkgModel
+	softTargets       tf.Output
+	temperature       tf.Output
+	alpha             tf.Output
+	temperatureTensor *tf.Tensor
+	alphaTensor       *tf.Tensor
+}
+
+// FitDistill trains the model to match the predictions of a teacher model as well as the labels, errors are sent to
+// the error handler. Use FitDistillContext to cancel training or to receive the error and History
+func (m *TfkgModel) FitDistill(
+	teacher *TfkgModel,
+	dataset data.Dataset,
+	config FitConfig,
+	distillConfig DistillConfig,
+) {
+	_, _ = m.FitDistillContext(context.Background(), teacher, dataset, config, distillConfig)
+}
+
+// FitDistillContext trains like FitContext, but the teacher predicts each batch first and the first output of the
+// model learns from a mix of its soft targets and the labels. The teacher takes the same inputs as the model and its
+// first output must have the same shape, E.G. a large CuDNNLSTM classifier distilled into a small Dense classifier.
+// When the loss of the first output is from_logits the teacher must also output logits, otherwise probabilities
+func (m *TfkgModel) FitDistillContext(
+	ctx context.Context,
+	teacher *TfkgModel,
+	dataset data.Dataset,
+	config FitConfig,
+	distillConfig DistillConfig,
+) (*History, error) {
+	if teacher == nil {
+		e := fmt.Errorf("no teacher model provided to distill")
+		m.errorHandler.Error(e)
+		return &History{}, e
+	}
+	if distillConfig.Temperature == 0 {
+		distillConfig.Temperature = 1
+	}
+	if distillConfig.Alpha == 0 {
+		distillConfig.Alpha = 0.5
+	}
+	if distillConfig.Alpha < 0 || distillConfig.Alpha > 1 {
+		e := fmt.Errorf("alpha must be between 0 and 1 to distill, got: %f", distillConfig.Alpha)
+		m.errorHandler.Error(e)
+		return &History{}, e
+	}
+
+	return m.fit(ctx, dataset, config, "distill", &distillation{
+		teacher: teacher,
+		config:  distillConfig,
+	})
+}
+
+func (m *TfkgModel) getDistillInputOps(distillation *distillation) (*distillInputOps, error) {
+	temperatureTensor, e := tf.NewTensor(distillation.config.Temperature)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	alphaTensor, e := tf.NewTensor(distillation.config.Alpha)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	return &distillInputOps{
+		teacher:           distillation.teacher,
+		softTargets:       m.model.Graph.Operation("distill_soft_targets").Output(0),
+		temperature:       m.model.Graph.Operation("distill_temperature").Output(0),
+		alpha:             m.model.Graph.Operation("distill_alpha").Output(0),
+		temperatureTensor: temperatureTensor,
+		alphaTensor:       alphaTensor,
+	}, nil
+}
+
+func (o *distillInputOps) addFeeds(feeds map[tf.Output]*tf.Tensor, batch data.Batch) error {
+	softTargets, e := o.teacher.Predict(batch.X...)
+	if e != nil {
+		return e
+	}
+
+	feeds[o.softTargets] = softTargets
+	feeds[o.temperature] = o.temperatureTensor
+	feeds[o.alpha] = o.alphaTensor
+
+	return nil
+}
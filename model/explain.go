@@ -0,0 +1,212 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+)
+
+// Saliency returns the gradient of classIndex of the first output with respect to each input, with the same shape as
+// the input. Integer inputs feeding an Embedding layer, such as tokenized strings, get one attribution per token: the
+// gradient at the embedding multiplied with the embedding. Use Tokenizer.AttributeWords to map them back to words
+func (m *TfkgModel) Saliency(classIndex int, inputs ...*tf.Tensor) ([]*tf.Tensor, error) {
+	if len(inputs) < 1 {
+		e := fmt.Errorf("no inputs provided")
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	return m.explain(classIndex, 1, inputs, make([]*tf.Tensor, len(inputs)))
+}
+
+// IntegratedGradients attributes classIndex of the first output to each input by averaging the gradients along steps
+// interpolations from the baselines to the inputs, multiplied by the difference between the input and the baseline.
+// Integer inputs feeding an Embedding layer are interpolated from the baseline embedding to their embeddings, so their
+// baselines are float32 embeddings, such as the embedding of the padding token, and they get one attribution per
+// token. Baselines are broadcast against the inputs or embeddings, a nil baseline is zeros
+func (m *TfkgModel) IntegratedGradients(
+	classIndex int,
+	inputs []*tf.Tensor,
+	baselines []*tf.Tensor,
+	steps int,
+) ([]*tf.Tensor, error) {
+	if len(inputs) != len(baselines) {
+		e := fmt.Errorf("the number of inputs (%d) did not match the number of baselines (%d)", len(inputs), len(baselines))
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	if steps < 1 {
+		e := fmt.Errorf("steps must be at least 1 for integrated gradients, got: %d", steps)
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	inputValues := make([][]float32, len(inputs))
+	baselineValues := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		switch input.DataType() {
+		case tf.Float:
+		case tf.Int32, tf.Int64:
+			if baselines[i] != nil && baselines[i].DataType() != tf.Float {
+				e := fmt.Errorf("the baseline of token input %d must be a float32 embedding, got data type: %v", i, baselines[i].DataType())
+				m.errorHandler.Error(e)
+				return nil, e
+			}
+			continue
+		default:
+			e := fmt.Errorf("integrated gradients supports float32 and integer inputs, input %d has data type: %v", i, input.DataType())
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		values, e := tensorToFloat32s(input)
+		if e != nil {
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		inputValues[i] = values
+		if baselines[i] == nil {
+			baselineValues[i] = make([]float32, len(values))
+			continue
+		}
+		baselineValue, e := tensorToFloat32s(baselines[i])
+		if e != nil {
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		if len(baselineValue) != len(values) {
+			e = fmt.Errorf("the shape of baseline %d %v did not match the input %v", i, baselines[i].Shape(), input.Shape())
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		baselineValues[i] = baselineValue
+	}
+
+	// Token attributions are already multiplied by the difference from the baseline embedding, so they are only averaged
+	totals := make([][]float32, len(inputs))
+	shapes := make([][]int64, len(inputs))
+	for step := 1; step <= steps; step++ {
+		alpha := float32(step) / float32(steps)
+
+		results, e := m.explain(classIndex, alpha, inputs, baselines)
+		if e != nil {
+			return nil, e
+		}
+
+		for i, result := range results {
+			values, e := tensorToFloat32s(result)
+			if e != nil {
+				m.errorHandler.Error(e)
+				return nil, e
+			}
+			if totals[i] == nil {
+				totals[i] = make([]float32, len(values))
+				shapes[i] = result.Shape()
+			}
+			for offset, value := range values {
+				totals[i][offset] += value
+			}
+		}
+	}
+
+	attributions := make([]*tf.Tensor, len(inputs))
+	for i := range inputs {
+		integrated := make([]float32, len(totals[i]))
+		for offset, total := range totals[i] {
+			integrated[offset] = total / float32(steps)
+			if inputValues[i] != nil {
+				integrated[offset] *= inputValues[i][offset] - baselineValues[i][offset]
+			}
+		}
+		attribution, e := float32sToTensor(integrated, shapes[i])
+		if e != nil {
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		attributions[i] = attribution
+	}
+
+	return attributions, nil
+}
+
+// explain runs the explain signature at the point alpha of the way from the baselines to the inputs, nil baselines are
+// zeros
+func (m *TfkgModel) explain(classIndex int, alpha float32, inputs []*tf.Tensor, baselines []*tf.Tensor) ([]*tf.Tensor, error) {
+	explainOutputs, e := m.getSignatureOutputs("explain")
+	if e != nil {
+		return nil, e
+	}
+
+	classIndexTensor, e := tf.NewTensor(int32(classIndex))
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	alphaTensor, e := tf.NewTensor(alpha)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	explainInputs := map[tf.Output]*tf.Tensor{
+		m.model.Graph.Operation("explain_class_index").Output(0): classIndexTensor,
+		m.model.Graph.Operation("explain_alpha").Output(0):       alphaTensor,
+	}
+	for i, inputTensor := range inputs {
+		explainInputs[m.model.Graph.Operation(fmt.Sprintf("explain_inputs_%d", i)).Output(0)] = inputTensor
+	}
+	// The baselines follow the inputs in the signature
+	for i, baseline := range baselines {
+		if baseline == nil {
+			var zero interface{} = []float32{0}
+			if inputs[i].DataType() == tf.Double {
+				zero = []float64{0}
+			}
+			baseline, e = tf.NewTensor(zero)
+			if e != nil {
+				m.errorHandler.Error(e)
+				return nil, e
+			}
+		}
+		explainInputs[m.model.Graph.Operation(fmt.Sprintf("explain_inputs_%d", len(inputs)+i)).Output(0)] = baseline
+	}
+
+	results, e := m.model.Session.Run(
+		explainInputs,
+		explainOutputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	return results, nil
+}
+
+func tensorToFloat32s(tensor *tf.Tensor) ([]float32, error) {
+	if tensor.DataType() != tf.Float {
+		return nil, fmt.Errorf("expected a float32 tensor, got data type: %v", tensor.DataType())
+	}
+	var buffer bytes.Buffer
+	_, e := tensor.WriteContentsTo(&buffer)
+	if e != nil {
+		return nil, e
+	}
+	values := make([]float32, buffer.Len()/4)
+	e = binary.Read(&buffer, binary.LittleEndian, values)
+	if e != nil {
+		return nil, e
+	}
+
+	return values, nil
+}
+
+func float32sToTensor(values []float32, shape []int64) (*tf.Tensor, error) {
+	var buffer bytes.Buffer
+	e := binary.Write(&buffer, binary.LittleEndian, values)
+	if e != nil {
+		return nil, e
+	}
+
+	return tf.ReadTensor(tf.Float, shape, &buffer)
+}
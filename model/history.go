@@ -0,0 +1,32 @@
+package model
+
+import (
+	"github.com/codingbeard/tfkg/callback"
+	"strings"
+)
+
+// History holds the logs the callbacks received at the end of each epoch of FitContext or EvaluateContext
+type History struct {
+	Epochs []HistoryEpoch
+}
+
+type HistoryEpoch struct {
+	Epoch int
+	// Logs are keyed by the callback mode. The validation logs of an epoch include its training logs
+	Logs map[callback.Mode][]callback.Log
+}
+
+// GetMetric returns the value of a log at the end of each epoch for the mode E.G. GetMetric(callback.ModeVal, "val_loss")
+func (h *History) GetMetric(mode callback.Mode, name string) []float64 {
+	var values []float64
+	for _, epoch := range h.Epochs {
+		for _, log := range epoch.Logs[mode] {
+			if strings.ToLower(log.Name) == strings.ToLower(name) {
+				values = append(values, log.Value)
+				break
+			}
+		}
+	}
+
+	return values
+}
@@ -4,6 +4,7 @@ package model
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/codingbeard/cberrors"
@@ -11,23 +12,23 @@ import (
 	"github.com/codingbeard/tfkg/callback"
 	"github.com/codingbeard/tfkg/data"
 	"github.com/codingbeard/tfkg/layer"
+	"github.com/codingbeard/tfkg/loss"
 	"github.com/codingbeard/tfkg/metric"
 	"github.com/codingbeard/tfkg/optimizer"
 	tf "github.com/galeone/tensorflow/tensorflow/go"
 	"github.com/galeone/tensorflow/tensorflow/go/core/protobuf/for_core_protos_go_proto"
 	"github.com/golang/protobuf/proto"
-	"github.com/remeh/sizedwaitgroup"
 	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"strconv"
 	"strings"
-	"sync"
 )
 
+// Loss is kept for backwards compatibility, each value is converted to the equivalent loss from the loss package
 type Loss string
 
 var (
@@ -38,13 +39,30 @@ var (
 	LossMSE                           Loss = "mse"
 )
 
+func (l Loss) GetKerasLayerConfig() interface{} {
+	switch l {
+	case LossBinaryCrossentropy:
+		return loss.BinaryCrossentropy().GetKerasLayerConfig()
+	case LossSparseCategoricalCrossentropy:
+		return loss.SparseCategoricalCrossentropy().GetKerasLayerConfig()
+	}
+	return loss.MeanSquaredError().GetKerasLayerConfig()
+}
+
+func (l Loss) GetCustomLayerDefinition() string {
+	return ""
+}
+
 type TfkgModel struct {
 	model                  *tf.SavedModel
 	layers                 []layer.Layer
 	isSequential           bool
+	outputNames            []string
 	pbCache                []byte
 	cpuPbCache             []byte
 	modelDefinitionSaveDir string
+	customLosses           []*loss.LCustom
+	fitState               *fitState
 
 	errorHandler *cberrors.ErrorsContainer
 	logger       *cblog.Logger
@@ -68,19 +86,29 @@ func NewSequentialModel(
 	return &TfkgModel{
 		isSequential: true,
 		layers:       layersWithInputs,
+		outputNames:  []string{lastLayer.GetName()},
 		errorHandler: errorHandler,
 		logger:       logger,
 	}
 }
 
+// NewModel creates a functional model from one or more output layers. Each output gets its own label, loss and
+// metrics, and they are returned in the same order by PredictOutputs
 func NewModel(
 	logger *cblog.Logger,
 	errorHandler *cberrors.ErrorsContainer,
-	output layer.Layer,
+	outputs ...layer.Layer,
 ) *TfkgModel {
+	var layers []layer.Layer
+	var outputNames []string
+	for _, output := range outputs {
+		layers = getPreviousLayers(output, layers)
+		outputNames = append(outputNames, output.GetName())
+	}
 	return &TfkgModel{
 		isSequential: false,
-		layers:       getPreviousLayers(output, []layer.Layer{}),
+		layers:       layers,
+		outputNames:  outputNames,
 		errorHandler: errorHandler,
 		logger:       logger,
 	}
@@ -107,6 +135,9 @@ func getPreviousLayers(l layer.Layer, layers []layer.Layer) []layer.Layer {
 	return layers
 }
 
+// LoadModel loads a model saved by TFKG. The layers are rebuilt from the model.json saved with it, so GetLayerWeights,
+// transfer learning and CompileAndLoad work on the loaded model. Custom layers must be added to layer.Constructors,
+// custom losses are read from custom-losses.json, see GetCustomLosses
 func LoadModel(
 	errorHandler *cberrors.ErrorsContainer,
 	logger *cblog.Logger,
@@ -158,8 +189,20 @@ func LoadModel(
 		return nil, e
 	}
 
+	layers, e := loadLayers(dir)
+	if e != nil && !os.IsNotExist(e) {
+		logger.InfoF("model", "Could not rebuild the layers of %s, GetLayerWeights and CompileAndLoad will not work on it: %s", dir, e.Error())
+	}
+	customLosses, e := loss.LoadCustomLosses(dir)
+	if e != nil && !os.IsNotExist(e) {
+		logger.InfoF("model", "Could not load the custom losses of %s: %s", dir, e.Error())
+	}
+
 	return &TfkgModel{
 		model:                  m,
+		layers:                 layers,
+		customLosses:           customLosses,
+		outputNames:            getOutputNames(dir, len(m.Signatures["predict"].Outputs)),
 		pbCache:                pbCache,
 		errorHandler:           errorHandler,
 		logger:                 logger,
@@ -167,10 +210,110 @@ func LoadModel(
 	}, nil
 }
 
+type kerasLayerConfig struct {
+	ClassName    string                 `json:"class_name"`
+	Name         string                 `json:"name"`
+	Config       map[string]interface{} `json:"config"`
+	InboundNodes [][][]interface{}      `json:"inbound_nodes"`
+}
+
+// loadLayers rebuilds the layers of the model.json saved with a model from their class names, see layer.Constructors,
+// and connects them through their inbound nodes. Register the constructors of custom layers to load them
+func loadLayers(dir string) ([]layer.Layer, error) {
+	configBytes, e := ioutil.ReadFile(filepath.Join(dir, "model.json"))
+	if e != nil {
+		return nil, e
+	}
+	var config kerasModelConfigStruct
+	e = json.Unmarshal(configBytes, &config)
+	if e != nil {
+		return nil, e
+	}
+	var layerConfigs []kerasLayerConfig
+	e = remarshalConfig(config.Config.Layers, &layerConfigs)
+	if e != nil {
+		return nil, e
+	}
+
+	var layers []layer.Layer
+	layersByName := make(map[string]layer.Layer)
+	for _, layerConfig := range layerConfigs {
+		if layerConfig.Config == nil {
+			layerConfig.Config = make(map[string]interface{})
+		}
+		if _, ok := layerConfig.Config["name"]; !ok && layerConfig.Name != "" {
+			layerConfig.Config["name"] = layerConfig.Name
+		}
+		l, e := newLayer(layerConfig.ClassName, layerConfig.Config)
+		if e != nil {
+			return nil, fmt.Errorf("error rebuilding layer %s: %s", layerConfig.Name, e.Error())
+		}
+		layers = append(layers, l)
+		layersByName[l.GetName()] = l
+	}
+
+	for offset, layerConfig := range layerConfigs {
+		var inputs []layer.Layer
+		for _, node := range layerConfig.InboundNodes {
+			for _, inbound := range node {
+				if len(inbound) == 0 {
+					continue
+				}
+				inputName, ok := inbound[0].(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid inbound node of layer %s: %v", layerConfig.Name, inbound)
+				}
+				input, ok := layersByName[inputName]
+				if !ok {
+					return nil, fmt.Errorf("unknown input %s of layer %s", inputName, layerConfig.Name)
+				}
+				inputs = append(inputs, input)
+			}
+		}
+		if len(inputs) > 0 {
+			layers[offset].SetInputs(inputs...)
+		}
+	}
+
+	return layers, nil
+}
+
+// getOutputNames reads the output layer names from the model.json written by CompileAndLoad, falling back to
+// output_N names if it was not saved alongside the model
+func getOutputNames(dir string, numOutputs int) []string {
+	var outputNames []string
+	configBytes, e := ioutil.ReadFile(filepath.Join(dir, "model.json"))
+	if e == nil {
+		var config kerasModelConfigStruct
+		e = json.Unmarshal(configBytes, &config)
+		if e == nil && len(config.Config.OutputLayers) == numOutputs {
+			for _, outputLayer := range config.Config.OutputLayers {
+				if len(outputLayer) == 0 {
+					break
+				}
+				name, ok := outputLayer[0].(string)
+				if !ok {
+					break
+				}
+				outputNames = append(outputNames, name)
+			}
+			if len(outputNames) == numOutputs {
+				return outputNames
+			}
+		}
+	}
+
+	outputNames = []string{}
+	for i := 0; i < numOutputs; i++ {
+		outputNames = append(outputNames, fmt.Sprintf("output_%d", i))
+	}
+	return outputNames
+}
+
 type vanillaPythonConfig struct {
 	ModelDir  string      `json:"model_dir"`
 	SaveDir   string      `json:"save_dir"`
-	Loss      string      `json:"loss"`
+	Loss      interface{} `json:"loss"`
 	Optimizer interface{} `json:"optimizer"`
 }
 
@@ -178,7 +321,7 @@ func LoadVanillaModel(
 	errorHandler *cberrors.ErrorsContainer,
 	logger *cblog.Logger,
 	dir string,
-	loss Loss,
+	modelLoss loss.Loss,
 	optimizer optimizer.Optimizer,
 	sessionOptions ...*for_core_protos_go_proto.ConfigProto,
 ) (*TfkgModel, error) {
@@ -195,7 +338,7 @@ func LoadVanillaModel(
 	config := vanillaPythonConfig{
 		ModelDir:  dir,
 		SaveDir:   filepath.Join(tempDir, tempModelDir),
-		Loss:      string(loss),
+		Loss:      modelLoss.GetKerasLayerConfig(),
 		Optimizer: optimizer.GetKerasLayerConfig(),
 	}
 
@@ -207,7 +350,12 @@ func LoadVanillaModel(
 
 	tempPythonPath := filepath.Join(tempDir, "tfkg_create_model.py")
 
-	e = ioutil.WriteFile(tempPythonPath, []byte(GetVanillaPythonCode()), os.ModePerm)
+	// Custom losses are defined in the script so get_loss finds them in custom_objects
+	var customDefinitions []string
+	if definition := modelLoss.GetCustomLayerDefinition(); definition != "" {
+		customDefinitions = append(customDefinitions, definition)
+	}
+	e = ioutil.WriteFile(tempPythonPath, []byte(GetVanillaPythonCode(customDefinitions)), os.ModePerm)
 	if e != nil {
 		errorHandler.Error(e)
 		return nil, e
@@ -269,6 +417,7 @@ func LoadVanillaModel(
 		model:                  model,
 		layers:                 nil,
 		isSequential:           false,
+		outputNames:            getOutputNames(dir, len(model.Signatures["predict"].Outputs)),
 		pbCache:                pbCache,
 		modelDefinitionSaveDir: "",
 		errorHandler:           errorHandler,
@@ -369,33 +518,224 @@ func (m *TfkgModel) SetModelWeights(weights []*tf.Tensor) error {
 	return nil
 }
 
+// GetCustomLosses returns the custom losses the model was compiled with. LoadModel reads them from custom-losses.json so
+// they can be set in the CompileConfig to recompile a loaded model
+func (m *TfkgModel) GetCustomLosses() []*loss.LCustom {
+	return m.customLosses
+}
+
+// GetLearningRate returns the learning rate the optimizer will use for the next step
+func (m *TfkgModel) GetLearningRate() (float32, error) {
+	outputs, e := m.getSignatureOutputs("get_learning_rate")
+	if e != nil {
+		return 0, e
+	}
+
+	results, e := m.model.Session.Run(
+		nil,
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return 0, e
+	}
+
+	return results[0].Value().(float32), nil
+}
+
+// SetLearningRate changes the learning rate of the optimizer. If the optimizer uses a learning rate schedule, the
+// schedule is scaled so the current learning rate matches and the schedule continues from there
+func (m *TfkgModel) SetLearningRate(learningRate float32) error {
+	outputs, e := m.getSignatureOutputs("set_learning_rate")
+	if e != nil {
+		return e
+	}
+
+	learningRateTensor, e := tf.NewTensor(learningRate)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	_, e = m.model.Session.Run(
+		map[tf.Output]*tf.Tensor{
+			m.model.Graph.Operation("set_learning_rate_learning_rate").Output(0): learningRateTensor,
+		},
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
+
+// GetGlobalStep returns the number of batches the model has been trained on
+func (m *TfkgModel) GetGlobalStep() (int, error) {
+	outputs, e := m.getSignatureOutputs("get_global_step")
+	if e != nil {
+		return 0, e
+	}
+
+	results, e := m.model.Session.Run(
+		nil,
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return 0, e
+	}
+
+	return int(results[0].Value().(int32)), nil
+}
+
+// SetLayerTrainable freezes or unfreezes a layer of a compiled or loaded model. The weights of frozen layers are not
+// changed by Fit
+func (m *TfkgModel) SetLayerTrainable(name string, trainable bool) error {
+	outputs, e := m.getSignatureOutputs("set_layer_trainable")
+	if e != nil {
+		return e
+	}
+
+	nameTensor, e := tf.NewTensor(name)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+	trainableTensor, e := tf.NewTensor(trainable)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	results, e := m.model.Session.Run(
+		map[tf.Output]*tf.Tensor{
+			m.model.Graph.Operation("set_layer_trainable_layer_name").Output(0): nameTensor,
+			m.model.Graph.Operation("set_layer_trainable_trainable").Output(0):  trainableTensor,
+		},
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	if results[0].Value().(int32) == 0 {
+		e = fmt.Errorf("layer %s not found in model", name)
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
+
+// GetLayersTrainable returns the name of every layer in the order they are defined, from the inputs to the outputs,
+// and whether each is trainable
+func (m *TfkgModel) GetLayersTrainable() ([]string, []bool, error) {
+	outputs, e := m.getSignatureOutputs("get_layer_trainable")
+	if e != nil {
+		return nil, nil, e
+	}
+
+	results, e := m.model.Session.Run(
+		nil,
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, nil, e
+	}
+
+	return results[0].Value().([]string), results[1].Value().([]bool), nil
+}
+
 func (m *TfkgModel) Predict(inputs ...*tf.Tensor) (*tf.Tensor, error) {
+	results, e := m.PredictOutputs(inputs...)
+	if e != nil {
+		return nil, e
+	}
+
+	return results[0], nil
+}
+
+// PredictOutputs returns the prediction of every output of the model, in the order the outputs were passed to NewModel
+func (m *TfkgModel) PredictOutputs(inputs ...*tf.Tensor) ([]*tf.Tensor, error) {
 	if len(inputs) < 1 {
 		e := fmt.Errorf("no inputs provided")
 		m.errorHandler.Error(e)
 		return nil, e
 	}
-	var predictOutputs []tf.Output
-	output := 0
-	for _, info := range m.model.Signatures["predict"].Outputs {
-		parts := strings.Split(info.Name, ":")
-		if len(parts) != 2 {
-			e := fmt.Errorf("error getting output for predict signature in fit")
+	predictOutputs, e := m.getSignatureOutputs("predict")
+	if e != nil {
+		return nil, e
+	}
+	predictInputs := map[tf.Output]*tf.Tensor{}
+	for i, inputTensor := range inputs {
+		predictInputs[m.model.Graph.Operation(fmt.Sprintf("predict_inputs_%d", i)).Output(0)] = inputTensor
+	}
+
+	results, e := m.model.Session.Run(
+		predictInputs,
+		predictOutputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	return results, nil
+}
+
+// PredictLayers returns the output of each named layer, E.G. the activations of the penultimate Dense layer to use as
+// embeddings. Layers with multiple outputs, such as a MultiHeadAttention returning its attention scores, are named
+// layer_name_0, layer_name_1 and so on. GetPredictLayerNames lists the names which can be requested
+func (m *TfkgModel) PredictLayers(layerNames []string, inputs ...*tf.Tensor) (map[string]*tf.Tensor, error) {
+	if len(inputs) < 1 {
+		e := fmt.Errorf("no inputs provided")
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	predictLayerNames, e := m.GetPredictLayerNames()
+	if e != nil {
+		return nil, e
+	}
+	predictLayersOutputs, e := m.getSignatureOutputs("predict_layers")
+	if e != nil {
+		return nil, e
+	}
+
+	var outputs []tf.Output
+	for _, layerName := range layerNames {
+		found := false
+		for offset, predictLayerName := range predictLayerNames {
+			if predictLayerName == layerName {
+				outputs = append(outputs, predictLayersOutputs[offset])
+				found = true
+				break
+			}
+		}
+		if !found {
+			e = fmt.Errorf("layer %s not found in model, layers: %s", layerName, strings.Join(predictLayerNames, ", "))
 			m.errorHandler.Error(e)
 			return nil, e
 		}
-		name := parts[0]
-		predictOutputs = append(predictOutputs, m.model.Graph.Operation(name).Output(output))
-		output++
 	}
+
 	predictInputs := map[tf.Output]*tf.Tensor{}
 	for i, inputTensor := range inputs {
-		predictInputs[m.model.Graph.Operation(fmt.Sprintf("predict_inputs_%d", i)).Output(0)] = inputTensor
+		predictInputs[m.model.Graph.Operation(fmt.Sprintf("predict_layers_inputs_%d", i)).Output(0)] = inputTensor
 	}
 
 	results, e := m.model.Session.Run(
 		predictInputs,
-		predictOutputs,
+		outputs,
 		nil,
 	)
 	if e != nil {
@@ -403,7 +743,121 @@ func (m *TfkgModel) Predict(inputs ...*tf.Tensor) (*tf.Tensor, error) {
 		return nil, e
 	}
 
-	return results[0], nil
+	layerOutputs := make(map[string]*tf.Tensor)
+	for offset, layerName := range layerNames {
+		layerOutputs[layerName] = results[offset]
+	}
+
+	return layerOutputs, nil
+}
+
+// GetPredictLayerNames returns the names of the layer outputs which can be passed to PredictLayers
+func (m *TfkgModel) GetPredictLayerNames() ([]string, error) {
+	outputs, e := m.getSignatureOutputs("get_predict_layer_names")
+	if e != nil {
+		return nil, e
+	}
+
+	results, e := m.model.Session.Run(
+		nil,
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+
+	return results[0].Value().([]string), nil
+}
+
+func (m *TfkgModel) getSignatureOutputs(signatureName string) ([]tf.Output, error) {
+	signature, ok := m.model.Signatures[signatureName]
+	if !ok {
+		e := fmt.Errorf("%s signature not found in model", signatureName)
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	outputs := make([]tf.Output, len(signature.Outputs))
+	for key, info := range signature.Outputs {
+		position, e := strconv.Atoi(strings.TrimPrefix(key, "output_"))
+		if e != nil || position >= len(outputs) {
+			e = fmt.Errorf("unexpected output %s for %s signature", key, signatureName)
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		parts := strings.Split(info.Name, ":")
+		if len(parts) != 2 {
+			e = fmt.Errorf("error getting output for %s signature", signatureName)
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		index, e := strconv.Atoi(parts[1])
+		if e != nil {
+			m.errorHandler.Error(e)
+			return nil, e
+		}
+		outputs[position] = m.model.Graph.Operation(parts[0]).Output(index)
+	}
+
+	return outputs, nil
+}
+
+type signatureInputOps struct {
+	labels       []tf.Output
+	classWeights tf.Output
+	inputs       []tf.Output
+	distill      *distillInputOps
+	// afterRun is called on the session goroutine after each batch is run
+	afterRun func() error
+}
+
+func (m *TfkgModel) getSignatureInputOps(signatureName string, numInputs int) signatureInputOps {
+	ops := signatureInputOps{
+		labels: []tf.Output{
+			m.model.Graph.Operation(fmt.Sprintf("%s_%s", signatureName, "y")).Output(0),
+		},
+		classWeights: m.model.Graph.Operation(fmt.Sprintf("%s_%s", signatureName, "class_weights")).Output(0),
+	}
+
+	// The labels of any additional outputs are passed in before the model inputs
+	extraLabels := len(m.outputNames) - 1
+	for offset := 0; offset < extraLabels; offset++ {
+		ops.labels = append(ops.labels, m.model.Graph.Operation(fmt.Sprintf("%s_inputs_%d", signatureName, offset)).Output(0))
+	}
+	for offset := 0; offset < numInputs; offset++ {
+		ops.inputs = append(ops.inputs, m.model.Graph.Operation(fmt.Sprintf("%s_inputs_%d", signatureName, extraLabels+offset)).Output(0))
+	}
+
+	return ops
+}
+
+func (m *TfkgModel) getFeeds(ops signatureInputOps, batch data.Batch) (map[tf.Output]*tf.Tensor, []*tf.Tensor, error) {
+	labels := append([]*tf.Tensor{batch.Y}, batch.ExtraY...)
+	if len(labels) != len(ops.labels) {
+		e := fmt.Errorf("the batch has %d labels but the model has %d outputs", len(labels), len(ops.labels))
+		m.errorHandler.Error(e)
+		return nil, nil, e
+	}
+
+	feeds := map[tf.Output]*tf.Tensor{
+		ops.classWeights: batch.ClassWeights,
+	}
+	for offset, op := range ops.labels {
+		feeds[op] = labels[offset]
+	}
+	for offset, op := range ops.inputs {
+		feeds[op] = batch.X[offset]
+	}
+
+	if ops.distill != nil {
+		e := ops.distill.addFeeds(feeds, batch)
+		if e != nil {
+			return nil, nil, e
+		}
+	}
+
+	return feeds, labels, nil
 }
 
 type FitConfig struct {
@@ -412,104 +866,136 @@ type FitConfig struct {
 	Validation bool
 	PreFetch   int
 	Metrics    []metric.Metric
-	Callbacks  []callback.Callback
-	Verbose    int
+	// OutputMetrics are computed for each output of a multiple output model, in the order of the outputs
+	OutputMetrics [][]metric.Metric
+	Callbacks     []callback.Callback
+	Verbose       int
+	// InitialEpoch is the number of epochs already trained, training continues from epoch InitialEpoch+1 up to Epochs
+	InitialEpoch int
+	// InitialBatch is the number of batches of the first epoch which have already been trained, they are skipped
+	InitialBatch int
+	// ShuffleSeed shuffles the dataset before training when it is not 0. It is saved with checkpoints so a resumed run
+	// uses the same training and validation split
+	ShuffleSeed int64
+	// WeightAveraging keeps an exponential moving average or stochastic weight average of the weights while training
+	WeightAveraging *WeightAveragingConfig
+	// InitialAveragedWeights is the number of weights already in the averaged weights of the model, the average is
+	// started from the current weights when it is 0
+	InitialAveragedWeights int
 }
 
+// Fit trains the model, errors are sent to the error handler. Use FitContext to cancel training or to receive the
+// error and History
 func (m *TfkgModel) Fit(
 	dataset data.Dataset,
 	config FitConfig,
 ) {
-	trainSig := m.model.Signatures["learn"]
-	var trainOutputs []tf.Output
-	output := 0
-	for _, info := range trainSig.Outputs {
-		parts := strings.Split(info.Name, ":")
-		if len(parts) != 2 {
-			e := fmt.Errorf("error getting output for train signature in fit")
-			m.errorHandler.Error(e)
-			return
-		}
-		name := parts[0]
-		trainOutputs = append(trainOutputs, m.model.Graph.Operation(name).Output(output))
-		output++
+	_, _ = m.FitContext(context.Background(), dataset, config)
+}
+
+// FitContext trains the model until every epoch is complete, a callback halts training, an error occurs or ctx is
+// cancelled. The History holds the logs the callbacks received at the end of each completed epoch
+func (m *TfkgModel) FitContext(
+	ctx context.Context,
+	dataset data.Dataset,
+	config FitConfig,
+) (*History, error) {
+	return m.fit(ctx, dataset, config, "learn", nil)
+}
+
+// fit trains with the learn signature, or the distill signature when distillation is set
+func (m *TfkgModel) fit(
+	ctx context.Context,
+	dataset data.Dataset,
+	config FitConfig,
+	signatureName string,
+	distillation *distillation,
+) (*History, error) {
+	history := &History{}
+
+	trainOutputs, e := m.getSignatureOutputs(signatureName)
+	if e != nil {
+		return history, e
 	}
 
 	if config.Epochs == 0 {
 		config.Epochs = 1
 	}
 
-	for i := range config.Metrics {
-		config.Metrics[i].Init()
-	}
+	initMetrics(config.Metrics, config.OutputMetrics)
 	for i := range config.Callbacks {
 		e := config.Callbacks[i].Init()
 		if e != nil {
 			m.errorHandler.Error(e)
-			return
+			return history, e
 		}
 	}
 
-	for epoch := 1; epoch <= config.Epochs; epoch++ {
-
-		generatorChan := dataset.
-			SetMode(data.GeneratorModeTrain).
-			GeneratorChan(config.BatchSize, config.PreFetch)
+	if config.ShuffleSeed != 0 {
+		dataset.Shuffle(config.ShuffleSeed)
+	}
 
-		labelOp := m.model.Graph.Operation(fmt.Sprintf("learn_%s", "y")).Output(0)
-		classWeightOp := m.model.Graph.Operation(fmt.Sprintf("learn_%s", "class_weights")).Output(0)
+	m.fitState = &fitState{
+		config: config,
+	}
+	defer func() {
+		m.fitState = nil
+	}()
 
-		var inputOps []tf.Output
-		for offset := range dataset.GetColumnNames() {
-			inputOps = append(inputOps, m.model.Graph.Operation(fmt.Sprintf("learn_inputs_%d", offset)).Output(0))
+	var averaging *weightAveraging
+	if config.WeightAveraging != nil {
+		averaging, e = m.newWeightAveraging(*config.WeightAveraging, config.InitialAveragedWeights)
+		if e != nil {
+			return history, e
 		}
+		m.fitState.averaging = averaging
+	}
 
-		halt := false
+	for epoch := config.InitialEpoch + 1; epoch <= config.Epochs; epoch++ {
 
-		var trainLogs []callback.Log
+		dataset.SetMode(data.GeneratorModeTrain)
 
-		batch := 1
-		totalBatches := dataset.Len() / config.BatchSize
-		trainTotalLoss := float64(0)
-		swg := sizedwaitgroup.New(runtime.NumCPU())
-		modelLock := &sync.Mutex{}
-		for generatorBatch := range generatorChan {
-			if halt {
-				break
+		trainInputOps := m.getSignatureInputOps(signatureName, len(dataset.GetColumnNames()))
+		if distillation != nil {
+			trainInputOps.distill, e = m.getDistillInputOps(distillation)
+			if e != nil {
+				return history, e
 			}
+		}
+		if averaging != nil {
+			trainInputOps.afterRun = func() error {
+				return averaging.afterBatch(m)
+			}
+		}
 
-			swg.Add()
-			go func(generatorBatch data.Batch) {
-				defer swg.Done()
-
-				x, y, classWeights := generatorBatch.X, generatorBatch.Y, generatorBatch.ClassWeights
-
-				inputs := map[tf.Output]*tf.Tensor{
-					labelOp:       y,
-					classWeightOp: classWeights,
-				}
-
-				for offset, op := range inputOps {
-					inputs[op] = x[offset]
-				}
-
-				modelLock.Lock()
-				result, e := m.model.Session.Run(
-					inputs,
-					trainOutputs,
-					nil,
-				)
-				modelLock.Unlock()
-				if e != nil {
-					m.errorHandler.Error(e)
-					return
-				}
-
-				loss := result[0].Value().(float32)
-				yTrue := y.Value()
-				yPred := result[1].Value()
+		var trainLogs []callback.Log
 
-				trainTotalLoss += float64(loss)
+		totalBatches := int(math.Ceil(float64(dataset.Len()) / float64(config.BatchSize)))
+		skipBatches := 0
+		if epoch == config.InitialEpoch+1 {
+			skipBatches = config.InitialBatch
+			totalBatches -= skipBatches
+		}
+		trainTotalLoss := float64(0)
+		trainRows := 0
+		trainOutputTotalLosses := make([]float64, len(m.outputNames))
+		batch, halt, e := m.runBatches(
+			ctx,
+			func(done <-chan struct{}) chan data.Batch {
+				return dataset.GeneratorChan(config.BatchSize, config.PreFetch, done)
+			},
+			trainInputOps,
+			trainOutputs,
+			skipBatches,
+			// A callback which saves or sets the learning rate must act before the next batch is trained
+			callbacksActPerBatch(config.Callbacks),
+			func(batch int, isLastBatch bool, result batchResult) bool {
+				loss := result.results[0].Value().(float32)
+				yTrue := result.labels[0].Value()
+				yPred := result.results[1].Value()
+
+				trainTotalLoss += float64(loss) * float64(result.rows)
+				trainRows += result.rows
 
 				trainLogs = []callback.Log{
 					{
@@ -522,31 +1008,46 @@ func (m *TfkgModel) Fit(
 					},
 					{
 						Name:  callback.LoggerPrefetched,
-						Value: float64(len(generatorChan)),
+						Value: float64(result.prefetched),
 					},
 					{
 						Name:      "loss",
-						Value:     trainTotalLoss / float64(batch),
+						Value:     trainTotalLoss / float64(trainRows),
 						Precision: 4,
 					},
 				}
 
+				trainLogs = append(trainLogs, m.getLearningRateLogs(result.results)...)
+
 				trainLogs = append(trainLogs, m.getMetricLogs(
 					config.Metrics,
 					"",
-					batch == totalBatches,
+					isLastBatch,
 					yTrue,
 					yPred,
 				)...)
 
+				trainLogs = append(trainLogs, m.getOutputLogs(
+					config.OutputMetrics,
+					"",
+					isLastBatch,
+					result.rows,
+					trainRows,
+					trainOutputTotalLosses,
+					result.labels,
+					result.results,
+				)...)
+
+				trainLogs = append(trainLogs, result.stall.getLogs()...)
+
 				event := callback.EventDuring
 				if batch == 1 {
 					event = callback.EventStart
-				} else if batch == totalBatches {
-					return
+				} else if isLastBatch {
+					return false
 				}
 
-				halt, _ = m.processCallbacks(
+				halt, _ := m.processCallbacks(
 					config.Callbacks,
 					event,
 					callback.ModeTrain,
@@ -555,11 +1056,13 @@ func (m *TfkgModel) Fit(
 					trainLogs,
 				)
 
-				batch++
-			}(generatorBatch)
+				return halt
+			},
+		)
+		if e != nil {
+			return history, e
 		}
-		swg.Wait()
-		halt, _ = m.processCallbacks(
+		endHalt, _ := m.processCallbacks(
 			config.Callbacks,
 			callback.EventEnd,
 			callback.ModeTrain,
@@ -567,80 +1070,64 @@ func (m *TfkgModel) Fit(
 			batch,
 			trainLogs,
 		)
-		for i := range config.Metrics {
-			config.Metrics[i].Reset()
+		halt = halt || endHalt
+		resetMetrics(config.Metrics, config.OutputMetrics)
+		if averaging != nil {
+			e = averaging.afterEpoch(m, epoch)
+			if e != nil {
+				return history, e
+			}
 		}
-		if config.Validation {
-			valSig := m.model.Signatures["evaluate"]
-			output := 0
-			var valOutputs []tf.Output
-			for _, info := range valSig.Outputs {
-				parts := strings.Split(info.Name, ":")
-				if len(parts) != 2 {
-					e := fmt.Errorf("error getting output for val signature in fit")
-					m.errorHandler.Error(e)
-					return
-				}
-				name := parts[0]
-				valOutputs = append(valOutputs, m.model.Graph.Operation(name).Output(output))
-				output++
+		historyEpoch := HistoryEpoch{
+			Epoch: epoch,
+			Logs: map[callback.Mode][]callback.Log{
+				callback.ModeTrain: trainLogs,
+			},
+		}
+		if config.Validation && !halt {
+			valOutputs, e := m.getSignatureOutputs("evaluate")
+			if e != nil {
+				return history, e
 			}
 
-			generatorChan := dataset.
-				SetMode(data.GeneratorModeVal).
-				GeneratorChan(config.BatchSize, config.PreFetch)
+			swapAveragedWeights := averaging != nil && averaging.config.Validate && averaging.averaged > 0
+			if swapAveragedWeights {
+				e = averaging.swap(m)
+				if e != nil {
+					return history, e
+				}
+			}
 
-			valLabelOp := m.model.Graph.Operation(fmt.Sprintf("evaluate_%s", "y")).Output(0)
-			classWeightOp := m.model.Graph.Operation(fmt.Sprintf("evaluate_%s", "class_weights")).Output(0)
+			dataset.SetMode(data.GeneratorModeVal)
 
-			var valInputOps []tf.Output
-			for offset := range dataset.GetColumnNames() {
-				valInputOps = append(valInputOps, m.model.Graph.Operation(fmt.Sprintf("evaluate_inputs_%d", offset)).Output(0))
-			}
+			valInputOps := m.getSignatureInputOps("evaluate", len(dataset.GetColumnNames()))
 
 			var valLogs []callback.Log
-			batch = 1
-			totalBatches := dataset.Len() / config.BatchSize
+			totalBatches := int(math.Ceil(float64(dataset.Len()) / float64(config.BatchSize)))
 			valTotalLoss := float64(0)
-			for generatorBatch := range generatorChan {
-				if halt {
-					break
-				}
-
-				swg.Add()
-				go func(generatorBatch data.Batch) {
-					defer swg.Done()
-					x, y, classWeights := generatorBatch.X, generatorBatch.Y, generatorBatch.ClassWeights
-
-					inputs := map[tf.Output]*tf.Tensor{
-						valLabelOp:    y,
-						classWeightOp: classWeights,
-					}
-
-					for offset, op := range valInputOps {
-						inputs[op] = x[offset]
-					}
-
-					result, e := m.model.Session.Run(
-						inputs,
-						valOutputs,
-						nil,
-					)
-					if e != nil {
-						m.errorHandler.Error(e)
-						return
-					}
-
-					yTrue := y.Value()
-					loss := result[0].Value().(float32)
-					yPred := result[1].Value()
+			valRows := 0
+			valOutputTotalLosses := make([]float64, len(m.outputNames))
+			batch, valHalt, e := m.runBatches(
+				ctx,
+				func(done <-chan struct{}) chan data.Batch {
+					return dataset.GeneratorChan(config.BatchSize, config.PreFetch, done)
+				},
+				valInputOps,
+				valOutputs,
+				0,
+				false,
+				func(batch int, isLastBatch bool, result batchResult) bool {
+					yTrue := result.labels[0].Value()
+					loss := result.results[0].Value().(float32)
+					yPred := result.results[1].Value()
 
-					valTotalLoss += float64(loss)
+					valTotalLoss += float64(loss) * float64(result.rows)
+					valRows += result.rows
 
 					valLogs = []callback.Log{
 						{
 							Name:  callback.LoggerPrefetched,
-							Value: float64(len(generatorChan)),
+							Value: float64(result.prefetched),
 						},
 						{
 							Name:  callback.LoggerTotalBatches,
@@ -648,7 +1135,7 @@ func (m *TfkgModel) Fit(
 						},
 						{
 							Name:      "val_loss",
-							Value:     valTotalLoss / float64(batch),
+							Value:     valTotalLoss / float64(valRows),
 							Precision: 4,
 						},
 					}
@@ -656,19 +1143,30 @@ func (m *TfkgModel) Fit(
 					valLogs = append(valLogs, m.getMetricLogs(
 						config.Metrics,
 						"val_",
-						batch == totalBatches,
+						isLastBatch,
 						yTrue,
 						yPred,
 					)...)
 
+					valLogs = append(valLogs, m.getOutputLogs(
+						config.OutputMetrics,
+						"val_",
+						isLastBatch,
+						result.rows,
+						valRows,
+						valOutputTotalLosses,
+						result.labels,
+						result.results,
+					)...)
+
 					event := callback.EventDuring
 					if batch == 1 {
 						event = callback.EventStart
-					} else if batch == totalBatches {
-						return
+					} else if isLastBatch {
+						return false
 					}
 
-					halt, _ = m.processCallbacks(
+					halt, _ := m.processCallbacks(
 						config.Callbacks,
 						event,
 						callback.ModeVal,
@@ -677,11 +1175,16 @@ func (m *TfkgModel) Fit(
 						append(trainLogs, valLogs...),
 					)
 
-					batch++
-				}(generatorBatch)
+					return halt
+				},
+			)
+			if e != nil {
+				if swapAveragedWeights {
+					_ = averaging.swap(m)
+				}
+				return history, e
 			}
-			swg.Wait()
-			halt, _ = m.processCallbacks(
+			endHalt, _ := m.processCallbacks(
 				config.Callbacks,
 				callback.EventEnd,
 				callback.ModeVal,
@@ -689,63 +1192,78 @@ func (m *TfkgModel) Fit(
 				batch,
 				append(trainLogs, valLogs...),
 			)
-			for i := range config.Metrics {
-				config.Metrics[i].Reset()
+			if swapAveragedWeights {
+				e = averaging.swap(m)
+				if e != nil {
+					return history, e
+				}
 			}
+			halt = valHalt || endHalt
+			resetMetrics(config.Metrics, config.OutputMetrics)
+			historyEpoch.Logs[callback.ModeVal] = append(trainLogs, valLogs...)
+		}
+		history.Epochs = append(history.Epochs, historyEpoch)
+		if halt {
+			break
 		}
 	}
+
+	if averaging != nil && averaging.config.ApplyAtEnd && averaging.averaged > 0 {
+		e = averaging.swap(m)
+		if e != nil {
+			return history, e
+		}
+	}
+
+	return history, nil
 }
 
 type EvaluateConfig struct {
 	BatchSize int
 	PreFetch  int
 	Metrics   []metric.Metric
-	Callbacks []callback.Callback
-	Verbose   int
+	// OutputMetrics are computed for each output of a multiple output model, in the order of the outputs
+	OutputMetrics [][]metric.Metric
+	Callbacks     []callback.Callback
+	Verbose       int
 }
 
+// Evaluate runs the model over the mode of the dataset, errors are sent to the error handler. Use EvaluateContext to
+// cancel evaluation or to receive the error and History
 func (m *TfkgModel) Evaluate(
 	mode data.GeneratorMode,
 	dataset data.Dataset,
 	config EvaluateConfig,
 ) {
+	_, _ = m.EvaluateContext(context.Background(), mode, dataset, config)
+}
 
-	for i := range config.Metrics {
-		config.Metrics[i].Init()
-	}
+// EvaluateContext runs the model over the mode of the dataset until it is complete, a callback halts, an error occurs
+// or ctx is cancelled. The History holds a single epoch with the logs the callbacks received at the end
+func (m *TfkgModel) EvaluateContext(
+	ctx context.Context,
+	mode data.GeneratorMode,
+	dataset data.Dataset,
+	config EvaluateConfig,
+) (*History, error) {
+	history := &History{}
+
+	initMetrics(config.Metrics, config.OutputMetrics)
 	for i := range config.Callbacks {
 		e := config.Callbacks[i].Init()
 		if e != nil {
 			m.errorHandler.Error(e)
-			return
+			return history, e
 		}
 	}
-	evaluateSig := m.model.Signatures["evaluate"]
-	output := 0
-	var evaluateOutputs []tf.Output
-	for _, info := range evaluateSig.Outputs {
-		parts := strings.Split(info.Name, ":")
-		if len(parts) != 2 {
-			e := fmt.Errorf("error getting output for evaluate signature in fit")
-			m.errorHandler.Error(e)
-			return
-		}
-		name := parts[0]
-		evaluateOutputs = append(evaluateOutputs, m.model.Graph.Operation(name).Output(output))
-		output++
+	evaluateOutputs, e := m.getSignatureOutputs("evaluate")
+	if e != nil {
+		return history, e
 	}
 
-	generatorChan := dataset.
-		SetMode(mode).
-		GeneratorChan(config.BatchSize, config.PreFetch)
+	dataset.SetMode(mode)
 
-	evaluateLabelOp := m.model.Graph.Operation(fmt.Sprintf("evaluate_%s", "y")).Output(0)
-	classWeightOp := m.model.Graph.Operation(fmt.Sprintf("evaluate_%s", "class_weights")).Output(0)
-
-	var evaluateInputOps []tf.Output
-	for offset := range dataset.GetColumnNames() {
-		evaluateInputOps = append(evaluateInputOps, m.model.Graph.Operation(fmt.Sprintf("evaluate_inputs_%d", offset)).Output(0))
-	}
+	evaluateInputOps := m.getSignatureInputOps("evaluate", len(dataset.GetColumnNames()))
 
 	callbackMode := callback.ModeTrain
 	if mode == data.GeneratorModeVal {
@@ -754,97 +1272,191 @@ func (m *TfkgModel) Evaluate(
 		callbackMode = callback.ModeTest
 	}
 	var evaluateLogs []callback.Log
-	batch := 1
-	totalBatches := int(math.Floor(float64(dataset.Len() / config.BatchSize)))
+	totalBatches := int(math.Ceil(float64(dataset.Len()) / float64(config.BatchSize)))
 	evaluateTotalLoss := float64(0)
-	var halt bool
-	for generatorBatch := range generatorChan {
-		if halt {
-			break
-		}
+	evaluateRows := 0
+	evaluateOutputTotalLosses := make([]float64, len(m.outputNames))
+	batch, _, e := m.runBatches(
+		ctx,
+		func(done <-chan struct{}) chan data.Batch {
+			return dataset.GeneratorChan(config.BatchSize, config.PreFetch, done)
+		},
+		evaluateInputOps,
+		evaluateOutputs,
+		0,
+		false,
+		func(batch int, isLastBatch bool, result batchResult) bool {
+			yTrue := result.labels[0].Value()
+			loss := result.results[0].Value().(float32)
+			yPred := result.results[1].Value()
+
+			evaluateTotalLoss += float64(loss) * float64(result.rows)
+			evaluateRows += result.rows
+
+			evaluateLogs = []callback.Log{
+				{
+					Name:  callback.LoggerVerbose,
+					Value: float64(config.Verbose),
+				},
+				{
+					Name:  callback.LoggerPrefetched,
+					Value: float64(result.prefetched),
+				},
+				{
+					Name:  callback.LoggerTotalBatches,
+					Value: float64(totalBatches),
+				},
+				{
+					Name:      string(mode) + "_loss",
+					Value:     evaluateTotalLoss / float64(evaluateRows),
+					Precision: 4,
+				},
+			}
 
-		x, y, classWeights := generatorBatch.X, generatorBatch.Y, generatorBatch.ClassWeights
+			evaluateLogs = append(evaluateLogs, m.getMetricLogs(
+				config.Metrics,
+				string(mode)+"_",
+				isLastBatch,
+				yTrue,
+				yPred,
+			)...)
+
+			evaluateLogs = append(evaluateLogs, m.getOutputLogs(
+				config.OutputMetrics,
+				string(mode)+"_",
+				isLastBatch,
+				result.rows,
+				evaluateRows,
+				evaluateOutputTotalLosses,
+				result.labels,
+				result.results,
+			)...)
+
+			event := callback.EventDuring
+			if batch == 1 {
+				event = callback.EventStart
+			} else if isLastBatch {
+				return false
+			}
 
-		inputs := map[tf.Output]*tf.Tensor{
-			evaluateLabelOp: y,
-			classWeightOp:   classWeights,
-		}
+			halt, _ := m.processCallbacks(
+				config.Callbacks,
+				event,
+				callbackMode,
+				1,
+				batch,
+				evaluateLogs,
+			)
 
-		for offset, op := range evaluateInputOps {
-			inputs[op] = x[offset]
-		}
+			return halt
+		},
+	)
+	if e != nil {
+		return history, e
+	}
 
-		result, e := m.model.Session.Run(
-			inputs,
-			evaluateOutputs,
-			nil,
-		)
-		if e != nil {
-			m.errorHandler.Error(e)
-			return
-		}
+	m.processCallbacks(
+		config.Callbacks,
+		callback.EventEnd,
+		callbackMode,
+		1,
+		batch,
+		evaluateLogs,
+	)
 
-		yTrue := y.Value()
-		loss := result[0].Value().(float32)
-		yPred := result[1].Value()
+	history.Epochs = append(history.Epochs, HistoryEpoch{
+		Epoch: 1,
+		Logs: map[callback.Mode][]callback.Log{
+			callbackMode: evaluateLogs,
+		},
+	})
 
-		evaluateTotalLoss += float64(loss)
+	return history, nil
+}
 
-		evaluateLogs = []callback.Log{
-			{
-				Name:  callback.LoggerVerbose,
-				Value: float64(config.Verbose),
-			},
-			{
-				Name:  callback.LoggerPrefetched,
-				Value: float64(len(generatorChan)),
-			},
-			{
-				Name:  callback.LoggerTotalBatches,
-				Value: float64(totalBatches),
-			},
-			{
-				Name:      string(mode) + "_loss",
-				Value:     evaluateTotalLoss / float64(batch),
-				Precision: 4,
-			},
+// getLearningRateLogs reads the learning rate used for the step, which the learn signature returns after the loss,
+// predictions, and per output losses. Models saved by older versions do not return it
+func (m *TfkgModel) getLearningRateLogs(results []*tf.Tensor) []callback.Log {
+	numResults := 2
+	if len(m.outputNames) > 1 {
+		numResults = 1 + len(m.outputNames)*2
+	}
+	if len(results) <= numResults {
+		return nil
+	}
+	learningRate, ok := results[len(results)-1].Value().(float32)
+	if !ok {
+		return nil
+	}
+
+	return []callback.Log{
+		{
+			Name:      "lr",
+			Value:     float64(learningRate),
+			Precision: 6,
+		},
+	}
+}
+
+func initMetrics(metrics []metric.Metric, outputMetrics [][]metric.Metric) {
+	for i := range metrics {
+		metrics[i].Init()
+	}
+	for _, outputMetric := range outputMetrics {
+		for i := range outputMetric {
+			outputMetric[i].Init()
 		}
+	}
+}
 
-		evaluateLogs = append(evaluateLogs, m.getMetricLogs(
-			config.Metrics,
-			string(mode)+"_",
-			batch == totalBatches,
-			yTrue,
-			yPred,
-		)...)
-
-		event := callback.EventDuring
-		if batch == 1 {
-			event = callback.EventStart
-		} else if batch == totalBatches {
-			continue
+func resetMetrics(metrics []metric.Metric, outputMetrics [][]metric.Metric) {
+	for i := range metrics {
+		metrics[i].Reset()
+	}
+	for _, outputMetric := range outputMetrics {
+		for i := range outputMetric {
+			outputMetric[i].Reset()
 		}
+	}
+}
 
-		halt, _ = m.processCallbacks(
-			config.Callbacks,
-			event,
-			callbackMode,
-			1,
-			batch,
-			evaluateLogs,
-		)
+// getOutputLogs adds the per output losses and metrics of a multiple output model. The learn and evaluate signatures
+// return the total loss, then the prediction of each output, then the loss of each output
+func (m *TfkgModel) getOutputLogs(
+	outputMetrics [][]metric.Metric,
+	namePrefix string,
+	isLastBatch bool,
+	batchRows int,
+	totalRows int,
+	outputTotalLosses []float64,
+	yTrues []*tf.Tensor,
+	results []*tf.Tensor,
+) []callback.Log {
+	numOutputs := len(m.outputNames)
+	if numOutputs < 2 {
+		return nil
+	}
 
-		batch++
+	var logs []callback.Log
+	for i, outputName := range m.outputNames {
+		outputTotalLosses[i] += float64(results[1+numOutputs+i].Value().(float32)) * float64(batchRows)
+		logs = append(logs, callback.Log{
+			Name:      namePrefix + outputName + "_loss",
+			Value:     outputTotalLosses[i] / float64(totalRows),
+			Precision: 4,
+		})
+		if len(outputMetrics) > i {
+			logs = append(logs, m.getMetricLogs(
+				outputMetrics[i],
+				namePrefix+outputName+"_",
+				isLastBatch,
+				yTrues[i].Value(),
+				results[1+i].Value(),
+			)...)
+		}
 	}
 
-	halt, _ = m.processCallbacks(
-		config.Callbacks,
-		callback.EventEnd,
-		callbackMode,
-		1,
-		batch,
-		evaluateLogs,
-	)
+	return logs
 }
 
 func (m *TfkgModel) getMetricLogs(
@@ -928,6 +1540,19 @@ func (m *TfkgModel) processCallbacks(
 	return halt, saved
 }
 
+// callbacksActPerBatch is whether any of the callbacks may act after a training batch, callbacks which do not implement
+// callback.ActsPerBatch are assumed to
+func callbacksActPerBatch(callbacks []callback.Callback) bool {
+	for _, call := range callbacks {
+		actsPerBatch, ok := call.(callback.ActsPerBatch)
+		if !ok || actsPerBatch.ActsPerBatch() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (m *TfkgModel) callCallbacks(
 	callbacks []callback.Callback,
 	event callback.Event,
@@ -952,10 +1577,24 @@ func (m *TfkgModel) callCallbacks(
 						m.errorHandler.Error(e)
 					} else {
 						saved = true
+						if m.fitState != nil {
+							e = m.saveTrainingState(saveDirGetter.GetSaveDir(), callbacks, event, mode, epoch, batch)
+							if e != nil {
+								m.errorHandler.Error(e)
+							}
+						}
 					}
 				}
 			} else if action == callback.ActionHalt {
 				halt = true
+			} else if action == callback.ActionSetLearningRate {
+				learningRateGetter, ok := call.(callback.HasLearningRate)
+				if ok {
+					e = m.SetLearningRate(learningRateGetter.GetLearningRate())
+					if e != nil {
+						m.errorHandler.Error(e)
+					}
+				}
 			}
 		}
 	}
@@ -1012,30 +1651,63 @@ func (m *TfkgModel) Save(dir string) error {
 		return e
 	}
 
+	// LoadModel rebuilds the layers from model.json and reads the custom losses
+	if len(m.layers) > 0 && dir != m.modelDefinitionSaveDir {
+		modelConfig, e := m.generateKerasDefinitionJson()
+		if e != nil {
+			return e
+		}
+		e = m.writeKerasDefinitionJson(dir, modelConfig)
+		if e != nil {
+			return e
+		}
+	}
+	if len(m.customLosses) > 0 && dir != m.modelDefinitionSaveDir {
+		e = loss.SaveCustomLosses(dir, m.customLosses)
+		if e != nil {
+			m.errorHandler.Error(e)
+			return e
+		}
+	}
+
 	return nil
 }
 
 type pythonConfig struct {
-	ModelConfig            string      `json:"model_config"`
-	SaveDir                string      `json:"save_dir"`
-	ModelDefinitionSaveDir string      `json:"model_definition_save_dir"`
-	Loss                   string      `json:"loss"`
-	Optimizer              interface{} `json:"optimizer"`
-	BatchSize              int         `json:"batch_size"`
-	CpuInference           bool        `json:"cpu_inference"`
+	ModelConfig               string        `json:"model_config"`
+	SaveDir                   string        `json:"save_dir"`
+	ModelDefinitionSaveDir    string        `json:"model_definition_save_dir"`
+	Losses                    []interface{} `json:"losses"`
+	LossWeights               []float64     `json:"loss_weights"`
+	Optimizer                 interface{}   `json:"optimizer"`
+	BatchSize                 int           `json:"batch_size"`
+	CpuInference              bool          `json:"cpu_inference"`
+	GradientAccumulationSteps int           `json:"gradient_accumulation_steps"`
+	WeightAveraging           bool          `json:"weight_averaging"`
 }
 
 type CompileConfig struct {
-	Loss             Loss
+	Loss loss.Loss
+	// Losses sets a loss per output of a multiple output model, in the order of the outputs. If empty Loss is used
+	// for every output
+	Losses []loss.Loss
+	// LossWeights scales the loss of each output before they are summed. Defaults to 1 for every output
+	LossWeights      []float64
 	Optimizer        optimizer.Optimizer
 	ModelInfoSaveDir string
 	BatchSize        int
 	CpuInference     bool
+	// GradientAccumulationSteps averages the gradients of this many batches before the optimizer applies them, giving
+	// an effective batch size of BatchSize * GradientAccumulationSteps
+	GradientAccumulationSteps int
+	// WeightAveraging adds shadow weights to the model so FitConfig.WeightAveraging can keep an EMA or SWA of the
+	// weights
+	WeightAveraging bool
 }
 
 func (m *TfkgModel) CompileAndLoad(config CompileConfig, sessionOptions ...*for_core_protos_go_proto.ConfigProto) error {
-	if config.Loss == "" {
-		config.Loss = LossMSE
+	if config.Loss == nil {
+		config.Loss = loss.MeanSquaredError()
 	}
 	if config.Optimizer == nil {
 		config.Optimizer = optimizer.Adam()
@@ -1043,6 +1715,38 @@ func (m *TfkgModel) CompileAndLoad(config CompileConfig, sessionOptions ...*for_
 	if config.BatchSize == 0 {
 		config.BatchSize = 1
 	}
+	if len(config.Losses) == 0 {
+		for range m.outputNames {
+			config.Losses = append(config.Losses, config.Loss)
+		}
+	}
+	if len(config.LossWeights) == 0 {
+		for range m.outputNames {
+			config.LossWeights = append(config.LossWeights, 1)
+		}
+	}
+	if len(config.Losses) != len(m.outputNames) {
+		e := fmt.Errorf("%d losses were provided for a model with %d outputs", len(config.Losses), len(m.outputNames))
+		m.errorHandler.Error(e)
+		return e
+	}
+	if len(config.LossWeights) != len(m.outputNames) {
+		e := fmt.Errorf("%d loss weights were provided for a model with %d outputs", len(config.LossWeights), len(m.outputNames))
+		m.errorHandler.Error(e)
+		return e
+	}
+	var losses []interface{}
+	var customLosses []*loss.LCustom
+	for offset, outputLoss := range config.Losses {
+		if outputLoss == nil {
+			outputLoss = loss.MeanSquaredError()
+			config.Losses[offset] = outputLoss
+		}
+		losses = append(losses, outputLoss.GetKerasLayerConfig())
+		if customLoss, ok := outputLoss.(*loss.LCustom); ok {
+			customLosses = append(customLosses, customLoss)
+		}
+	}
 	m.logger.InfoF("model", "Compiling and loading model. If anything goes wrong python error messages will be printed out.")
 	m.modelDefinitionSaveDir = config.ModelInfoSaveDir
 	modelConfig, e := m.generateKerasDefinitionJson()
@@ -1059,15 +1763,8 @@ func (m *TfkgModel) CompileAndLoad(config CompileConfig, sessionOptions ...*for_
 	}
 
 	if config.ModelInfoSaveDir != "" {
-		indentedJson := bytes.NewBuffer([]byte{})
-		e = json.Indent(indentedJson, []byte(modelConfig), "", "  ")
+		e = m.writeKerasDefinitionJson(config.ModelInfoSaveDir, modelConfig)
 		if e != nil {
-			m.errorHandler.Error(e)
-			return e
-		}
-		e = ioutil.WriteFile(filepath.Join(config.ModelInfoSaveDir, "model.json"), indentedJson.Bytes(), os.ModePerm)
-		if e != nil {
-			m.errorHandler.Error(e)
 			return e
 		}
 	}
@@ -1076,10 +1773,14 @@ func (m *TfkgModel) CompileAndLoad(config CompileConfig, sessionOptions ...*for_
 		ModelConfig:            modelConfig,
 		SaveDir:                filepath.Join(tempDir, tempModelDir),
 		ModelDefinitionSaveDir: config.ModelInfoSaveDir,
-		Loss:                   string(config.Loss),
+		Losses:                 losses,
+		LossWeights:            config.LossWeights,
 		Optimizer:              config.Optimizer.GetKerasLayerConfig(),
 		BatchSize:              config.BatchSize,
 		CpuInference:           config.CpuInference,
+
+		GradientAccumulationSteps: config.GradientAccumulationSteps,
+		WeightAveraging:           config.WeightAveraging,
 	}
 
 	configBytes, e := json.Marshal(pConfig)
@@ -1103,6 +1804,25 @@ func (m *TfkgModel) CompileAndLoad(config CompileConfig, sessionOptions ...*for_
 			layerTypesDefined[definition] = true
 		}
 	}
+	for _, outputLoss := range config.Losses {
+		definition := outputLoss.GetCustomLayerDefinition()
+		if len(definition) == 0 {
+			continue
+		}
+		if _, ok := layerTypesDefined[definition]; !ok {
+			customDefinitions = append(customDefinitions, definition)
+			layerTypesDefined[definition] = true
+		}
+	}
+
+	m.customLosses = customLosses
+	if config.ModelInfoSaveDir != "" && len(customLosses) > 0 {
+		e = loss.SaveCustomLosses(config.ModelInfoSaveDir, customLosses)
+		if e != nil {
+			m.errorHandler.Error(e)
+			return e
+		}
+	}
 
 	tempPythonPath := filepath.Join(tempDir, "tfkg_create_model.py")
 
@@ -1213,11 +1933,27 @@ type kerasModelConfigStruct struct {
 	Backend      string `json:"backend"`
 }
 
+func (m *TfkgModel) writeKerasDefinitionJson(dir string, modelConfig string) error {
+	indentedJson := bytes.NewBuffer([]byte{})
+	e := json.Indent(indentedJson, []byte(modelConfig), "", "  ")
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+	e = ioutil.WriteFile(filepath.Join(dir, "model.json"), indentedJson.Bytes(), os.ModePerm)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
+
 func (m *TfkgModel) generateKerasDefinitionJson() (string, error) {
 	var inputLayerConfigs [][]interface{}
 	var outputLayerConfigs [][]interface{}
 	var layerConfigs []interface{}
-	for i, l := range m.layers {
+	for _, l := range m.layers {
 		if _, ok := l.(*layer.LInput); ok {
 			inputLayerConfigs = append(inputLayerConfigs, []interface{}{
 				l.GetName(),
@@ -1225,15 +1961,15 @@ func (m *TfkgModel) generateKerasDefinitionJson() (string, error) {
 				0,
 			})
 		}
-		if i+1 == len(m.layers) {
-			outputLayerConfigs = append(outputLayerConfigs, []interface{}{
-				l.GetName(),
-				0,
-				0,
-			})
-		}
 		layerConfigs = append(layerConfigs, l.GetKerasLayerConfig())
 	}
+	for _, outputName := range m.outputNames {
+		outputLayerConfigs = append(outputLayerConfigs, []interface{}{
+			outputName,
+			0,
+			0,
+		})
+	}
 	config := kerasModelConfigStruct{
 		ClassName: "Functional",
 		Config: struct {
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/tfkg/layer"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadLayersRoundTrip(t *testing.T) {
+	inputA := layer.Input().SetInputShape(tf.MakeShape(4)).SetName("input_a")
+	inputB := layer.Input().SetInputShape(tf.MakeShape(3)).SetName("input_b")
+	denseA := layer.Dense(2).SetName("dense_a").SetInputs(inputA)
+	concatenate := layer.Concatenate().SetName("concatenate").SetInputs(denseA, inputB)
+	output := layer.Dense(1).SetActivation("sigmoid").SetName("output").SetInputs(concatenate)
+
+	m := NewModel(nil, cberrors.NewErrorContainer(), output)
+	modelConfig, e := m.generateKerasDefinitionJson()
+	if e != nil {
+		t.Fatal(e)
+	}
+	dir := t.TempDir()
+	e = ioutil.WriteFile(filepath.Join(dir, "model.json"), []byte(modelConfig), os.ModePerm)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	layers, e := loadLayers(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expected := []struct {
+		className string
+		name      string
+		inputs    []string
+	}{
+		{className: "InputLayer", name: "input_a"},
+		{className: "Dense", name: "dense_a", inputs: []string{"input_a"}},
+		{className: "InputLayer", name: "input_b"},
+		{className: "Concatenate", name: "concatenate", inputs: []string{"dense_a", "input_b"}},
+		{className: "Dense", name: "output", inputs: []string{"concatenate"}},
+	}
+	if len(layers) != len(expected) {
+		t.Fatalf("expected %d layers, got %d", len(expected), len(layers))
+	}
+	for offset, expectedLayer := range expected {
+		loaded := layers[offset]
+		config := kerasConfig(t, loaded)
+		if config["class_name"] != expectedLayer.className || loaded.GetName() != expectedLayer.name {
+			t.Errorf("layer %d: expected %s %s, got %s %s", offset, expectedLayer.className, expectedLayer.name, config["class_name"], loaded.GetName())
+		}
+		var inputs []string
+		for _, input := range loaded.GetInputs() {
+			inputs = append(inputs, input.GetName())
+		}
+		if !reflect.DeepEqual(inputs, expectedLayer.inputs) {
+			t.Errorf("layer %d: expected the inputs %v, got %v", offset, expectedLayer.inputs, inputs)
+		}
+		// The rebuilt layer writes the same config, so it saves and compiles the same as the original
+		if original := kerasConfig(t, m.layers[offset]); !reflect.DeepEqual(config, original) {
+			t.Errorf("layer %d: expected the config %v, got %v", offset, original, config)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"context"
+	"github.com/codingbeard/tfkg/callback"
+	"github.com/codingbeard/tfkg/data"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+	"time"
+)
+
+// batchResult is passed from the session runner to the stage which computes the metrics and calls the callbacks
+type batchResult struct {
+	labels      []*tf.Tensor
+	results     []*tf.Tensor
+	rows        int
+	prefetched  int
+	isLastBatch bool
+	stall       pipelineStall
+}
+
+// pipelineStall is the time the session runner has spent waiting since the start of the epoch, either for the dataset
+// to generate a batch or for the metrics and callbacks of a previous batch to finish
+type pipelineStall struct {
+	dataset   time.Duration
+	callbacks time.Duration
+}
+
+func (p pipelineStall) getLogs() []callback.Log {
+	return []callback.Log{
+		{
+			Name:  callback.LoggerDatasetStall,
+			Value: p.dataset.Seconds(),
+		},
+		{
+			Name:  callback.LoggerCallbackStall,
+			Value: p.callbacks.Seconds(),
+		},
+	}
+}
+
+// runBatches runs the batches through the session on a single goroutine in the order the dataset generates them. Each
+// result is passed to process in the same order on the calling goroutine, so the metrics and callbacks never run
+// concurrently with each other. The runner reads the next batch before passing on a result so process knows which
+// batch is the last. Unless waitForProcess is set the next batch is in the session while process runs, so training
+// sets it when callbacks may save the model, change the learning rate or halt after a batch. The generator is started
+// with a done channel which is closed when runBatches returns. It returns the number of batches processed and whether
+// process halted
+func (m *TfkgModel) runBatches(
+	ctx context.Context,
+	generate func(done <-chan struct{}) chan data.Batch,
+	inputOps signatureInputOps,
+	outputs []tf.Output,
+	skipBatches int,
+	waitForProcess bool,
+	process func(batch int, isLastBatch bool, result batchResult) bool,
+) (int, bool, error) {
+	resultChan := make(chan batchResult, 1)
+	processed := make(chan struct{})
+	stop := make(chan struct{})
+	var runError error
+	generatorChan := generate(stop)
+
+	// Models saved before the batch dimension was dynamic can only run full batches
+	fixedBatchSize := inputOps.classWeights.Shape().Size(0)
+	warnedPartialBatch := false
+
+	// nextBatch reads the next batch to run, done is set when the dataset is exhausted and stopped when the pipeline is
+	// stopped or ctx is cancelled first
+	nextBatch := func() (generatorBatch data.Batch, done bool, stopped bool) {
+		for {
+			var ok bool
+			select {
+			case generatorBatch, ok = <-generatorChan:
+			case <-stop:
+				return generatorBatch, false, true
+			case <-ctx.Done():
+				return generatorBatch, false, true
+			}
+			if !ok {
+				return generatorBatch, true, false
+			}
+			if skipBatches > 0 {
+				skipBatches--
+				continue
+			}
+			if fixedBatchSize > 0 && generatorBatch.ClassWeights.Shape()[0] != fixedBatchSize {
+				if !warnedPartialBatch {
+					m.logger.InfoF("model", "Skipping partial batch, recompile the model to train and evaluate every row")
+					warnedPartialBatch = true
+				}
+				continue
+			}
+			return generatorBatch, false, false
+		}
+	}
+
+	go func() {
+		defer close(resultChan)
+		var stall pipelineStall
+		waitStart := time.Now()
+		generatorBatch, done, stopped := nextBatch()
+		stall.dataset += time.Since(waitStart)
+		for !done && !stopped {
+			prefetched := len(generatorChan)
+
+			feeds, labels, e := m.getFeeds(inputOps, generatorBatch)
+			if e != nil {
+				runError = e
+				return
+			}
+
+			results, e := m.model.Session.Run(
+				feeds,
+				outputs,
+				nil,
+			)
+			if e != nil {
+				m.errorHandler.Error(e)
+				runError = e
+				return
+			}
+			if inputOps.afterRun != nil {
+				e = inputOps.afterRun()
+				if e != nil {
+					runError = e
+					return
+				}
+			}
+
+			waitStart = time.Now()
+			var nextGeneratorBatch data.Batch
+			nextGeneratorBatch, done, stopped = nextBatch()
+			stall.dataset += time.Since(waitStart)
+			if stopped {
+				return
+			}
+
+			waitStart = time.Now()
+			select {
+			case resultChan <- batchResult{
+				labels:      labels,
+				results:     results,
+				rows:        int(generatorBatch.ClassWeights.Shape()[0]),
+				prefetched:  prefetched,
+				isLastBatch: done,
+				stall:       stall,
+			}:
+			case <-stop:
+				return
+			}
+			if waitForProcess {
+				select {
+				case <-processed:
+				case <-stop:
+					return
+				}
+			}
+			stall.callbacks += time.Since(waitStart)
+			generatorBatch = nextGeneratorBatch
+		}
+	}()
+
+	batch := 0
+	halt := false
+	for result := range resultChan {
+		batch++
+		halt = process(batch, result.isLastBatch, result)
+		if halt {
+			break
+		}
+		if waitForProcess {
+			processed <- struct{}{}
+		}
+	}
+	close(stop)
+	for range resultChan {
+	}
+
+	if runError != nil {
+		return batch, halt, runError
+	}
+	if ctx.Err() != nil {
+		return batch, halt, ctx.Err()
+	}
+
+	return batch, halt, nil
+}
@@ -4,9 +4,9 @@ import (
 	"strings"
 )
 
-// This code is generated automatically using "go generate ./..." from model/tfkg_model.py. DO NOT EDIT manually.
-func GetTfkgPythonCode(customDefinitions []string) string {
-	return strings.ReplaceAll(`import json
+// This code is generated automatically using "go generate ./..." from model/common_model.py, model/tfkg_model.py and
+// model/vanilla_model.py. DO NOT EDIT manually.
+const pythonCommonCode = `import json
 import os
 import logging
 import sys
@@ -25,6 +25,359 @@ custom_objects = {}
 with open(sys.argv[1], "r") as f:
     config = json.load(f)
 
+class LinearWarmup(tf.keras.optimizers.schedules.LearningRateSchedule):
+    def __init__(self, warmup_steps, after_warmup, initial_learning_rate=0.0, name="LinearWarmup"):
+        super().__init__()
+        self.warmup_steps = warmup_steps
+        self.initial_learning_rate = initial_learning_rate
+        self.name = name
+        if isinstance(after_warmup, dict):
+            after_warmup = tf.keras.optimizers.schedules.deserialize(after_warmup, custom_objects=custom_objects)
+        self.after_warmup = after_warmup
+
+    def __call__(self, step):
+        step = tf.cast(step, tf.float32)
+        warmup_steps = tf.cast(self.warmup_steps, tf.float32)
+        target_learning_rate = tf.cast(self.after_warmup(0), tf.float32)
+        warmup_learning_rate = self.initial_learning_rate + (
+                target_learning_rate - self.initial_learning_rate
+        ) * step / warmup_steps
+
+        return tf.cond(
+            step < warmup_steps,
+            lambda: warmup_learning_rate,
+            lambda: tf.cast(self.after_warmup(step - warmup_steps), tf.float32),
+        )
+
+    def get_config(self):
+        return {
+            "warmup_steps": self.warmup_steps,
+            "after_warmup": tf.keras.optimizers.schedules.serialize(self.after_warmup),
+            "initial_learning_rate": self.initial_learning_rate,
+            "name": self.name,
+        }
+
+
+custom_objects["LinearWarmup"] = LinearWarmup
+
+
+class ScaledSchedule(tf.keras.optimizers.schedules.LearningRateSchedule):
+    def __init__(self, schedule, scale):
+        super().__init__()
+        self.schedule = schedule
+        self.scale = scale
+
+    def __call__(self, step):
+        return tf.cast(self.schedule(step), tf.float32) * self.scale
+
+    def get_config(self):
+        return self.schedule.get_config()
+
+
+def freeze_batch_normalization(batch_normalization, trainable):
+    # Keras runs a frozen BatchNormalization layer in inference mode. Frozen layers are made trainable so they can be
+    # unfrozen, so the layer keeps using and not updating its moving statistics while its trainable flag is false
+    call = batch_normalization.call
+
+    def call_with_trainable_flag(inputs, training=None):
+        if training is None or training is False:
+            return call(inputs, training=training)
+
+        return tf.cond(
+            trainable,
+            lambda: call(inputs, training=training),
+            lambda: call(inputs, training=False),
+        )
+
+    batch_normalization.call = call_with_trainable_flag
+
+
+def get_learning_rate(optimizer):
+    learning_rate = optimizer.learning_rate
+    if isinstance(learning_rate, tf.keras.optimizers.schedules.LearningRateSchedule):
+        return tf.cast(learning_rate(optimizer.iterations), tf.float32)
+
+    return tf.cast(learning_rate, tf.float32)
+
+
+class BinaryFocalCrossentropy(tf.keras.losses.Loss):
+    def __init__(
+            self,
+            apply_class_balancing=False,
+            alpha=0.25,
+            gamma=2.0,
+            from_logits=False,
+            label_smoothing=0.0,
+            axis=-1,
+            reduction="auto",
+            name="binary_focal_crossentropy"
+    ):
+        super().__init__(reduction=reduction, name=name)
+        self.apply_class_balancing = apply_class_balancing
+        self.alpha = alpha
+        self.gamma = gamma
+        self.from_logits = from_logits
+        self.label_smoothing = label_smoothing
+        self.axis = axis
+
+    def call(self, y_true, y_pred):
+        y_pred = tf.convert_to_tensor(y_pred)
+        y_true = tf.cast(y_true, y_pred.dtype)
+        if self.label_smoothing > 0:
+            y_true = y_true * (1.0 - self.label_smoothing) + 0.5 * self.label_smoothing
+
+        y_prob = y_pred
+        if self.from_logits:
+            y_prob = tf.sigmoid(y_pred)
+
+        cross_entropy = tf.keras.backend.binary_crossentropy(y_true, y_pred, from_logits=self.from_logits)
+        p_t = y_true * y_prob + (1 - y_true) * (1 - y_prob)
+        focal = tf.pow(1.0 - p_t, self.gamma) * cross_entropy
+        if self.apply_class_balancing:
+            focal = (y_true * self.alpha + (1 - y_true) * (1 - self.alpha)) * focal
+
+        return tf.reduce_mean(focal, axis=self.axis)
+
+    def get_config(self):
+        loss_config = super().get_config()
+        loss_config.update({
+            "apply_class_balancing": self.apply_class_balancing,
+            "alpha": self.alpha,
+            "gamma": self.gamma,
+            "from_logits": self.from_logits,
+            "label_smoothing": self.label_smoothing,
+            "axis": self.axis,
+        })
+        return loss_config
+
+
+class MultiLabelCrossentropy(tf.keras.losses.BinaryCrossentropy):
+    # Binary crossentropy over k-hot float labels with the same shape as the output, so it is not a sparse label loss
+    def __init__(
+            self,
+            from_logits=False,
+            label_smoothing=0.0,
+            axis=-1,
+            reduction="auto",
+            name="multi_label_crossentropy"
+    ):
+        super().__init__(
+            from_logits=from_logits,
+            label_smoothing=label_smoothing,
+            axis=axis,
+            reduction=reduction,
+            name=name,
+        )
+
+
+class SequenceSparseCategoricalCrossentropy(tf.keras.losses.SparseCategoricalCrossentropy):
+    # Sparse categorical crossentropy over a label per position, positions labelled 0 are padding and are masked out of
+    # the mean loss of each sample
+    def __init__(
+            self,
+            from_logits=False,
+            reduction="auto",
+            name="sequence_sparse_categorical_crossentropy"
+    ):
+        super().__init__(
+            from_logits=from_logits,
+            reduction=reduction,
+            name=name,
+        )
+
+    def call(self, y_true, y_pred):
+        position_loss = super().call(y_true, y_pred)
+        mask = tf.cast(tf.not_equal(tf.reshape(y_true, tf.shape(position_loss)), 0), position_loss.dtype)
+        return tf.reduce_sum(position_loss * mask, axis=-1) / tf.maximum(tf.reduce_sum(mask, axis=-1), 1.0)
+
+
+tfkg_losses = {
+    "BinaryFocalCrossentropy": BinaryFocalCrossentropy,
+    "MultiLabelCrossentropy": MultiLabelCrossentropy,
+    "SequenceSparseCategoricalCrossentropy": SequenceSparseCategoricalCrossentropy,
+}
+
+sparse_label_losses = [
+    "BinaryCrossentropy",
+    "BinaryFocalCrossentropy",
+    "SparseCategoricalCrossentropy",
+    "SequenceSparseCategoricalCrossentropy",
+]
+
+
+def get_loss(loss_config):
+    class_name = loss_config["class_name"]
+    loss_class_config = dict(loss_config["config"])
+    loss_class_config["reduction"] = "none"
+
+    if class_name in custom_objects:
+        loss_class = custom_objects[class_name]
+    elif class_name in tfkg_losses:
+        loss_class = tfkg_losses[class_name]
+    else:
+        loss_class = getattr(tf.keras.losses, class_name)
+
+    loss_func = loss_class.from_config(loss_class_config)
+
+    def loss(y_true, y_pred, class_weights):
+        sample_loss = loss_func(y_true, y_pred)
+        if len(sample_loss.shape) > 1:
+            sample_loss = tf.reduce_mean(sample_loss, axis=list(range(1, len(sample_loss.shape))))
+        weighted_loss = tf.multiply(
+            sample_loss,
+            class_weights
+        )
+        return tf.reduce_mean(weighted_loss)
+
+    return loss
+
+
+def get_layer_node(model, model_layer):
+    # A layer called in more than one graph, E.G. inside a nested model or shared with a sub model, has a node for each
+    # call. Only the node belonging to the outer model can be reached from its inputs, None if the model has none
+    network_nodes = getattr(model, "_network_nodes", set())
+    for node_index, node in enumerate(model_layer._inbound_nodes):
+        if "%s_ib-%d" % (model_layer.name, node_index) in network_nodes:
+            return node
+
+    return None
+
+
+def get_predict_layers(model):
+    # The output of every layer is traced into predict_layers so they can be fetched by name. Layers with multiple
+    # outputs are named layer_name_N. Layers without a node in the model are skipped
+    names = []
+    outputs = []
+    for model_layer in model.layers:
+        node = get_layer_node(model, model_layer)
+        if node is None:
+            continue
+        layer_outputs = tf.nest.flatten(node.outputs)
+        for output_offset, layer_output in enumerate(layer_outputs):
+            if len(layer_outputs) > 1:
+                names.append("%s_%d" % (model_layer.name, output_offset))
+            else:
+                names.append(model_layer.name)
+            outputs.append(layer_output)
+
+    if len(outputs) == 0:
+        # E.G. a subclassed model has no nodes, so only its outputs can be fetched
+        return list(model.output_names), list(model.outputs)
+
+    return names, outputs
+
+
+def interpolate_embedding(embedding_layer):
+    # While explain sets an alpha and a baseline the layer returns the point alpha of the way from the baseline embedding
+    # to the embedding of its tokens. Returns the original call and the interpolation dict
+    call = embedding_layer.call
+    interpolation = {}
+
+    def call_with_interpolation(inputs):
+        embeddings = call(inputs)
+        if "alpha" not in interpolation:
+            return embeddings
+
+        baseline = tf.cast(interpolation["baseline"], embeddings.dtype)
+        return baseline + tf.cast(interpolation["alpha"], embeddings.dtype) * (embeddings - baseline)
+
+    embedding_layer.call = call_with_interpolation
+
+    return call, interpolation
+
+
+def get_explain_baseline_signature(input_signature):
+    # Baselines are broadcast against the inputs, or the embeddings of token inputs, so [0] is a zero baseline
+    baseline_signature = []
+    for input_spec in input_signature:
+        if input_spec.dtype.is_floating:
+            baseline_signature.append(tf.TensorSpec(shape=None, dtype=input_spec.dtype))
+        else:
+            baseline_signature.append(tf.TensorSpec(shape=None, dtype=tf.float32))
+
+    return baseline_signature
+
+
+class Explainer:
+    # Integer inputs can not be differentiated, so a token input feeding an Embedding layer is interpolated in embedding
+    # space and its gradient is taken at the output of the Embedding layer. The gradient multiplied with the difference
+    # between the embedding and the baseline embedding gives one attribution per token
+    def __init__(self, model):
+        self._embedding_offsets = []
+        self._embedding_calls = []
+        self._interpolations = []
+        explain_outputs = [model.outputs[0]]
+        for model_input in model.inputs:
+            embedding_offset = -1
+            if not model_input.dtype.is_floating:
+                for model_layer in model.layers:
+                    if not isinstance(model_layer, tf.keras.layers.Embedding):
+                        continue
+                    node = get_layer_node(model, model_layer)
+                    if node is None or tf.nest.flatten(node.input_tensors)[0].name != model_input.name:
+                        continue
+                    embedding_offset = len(self._embedding_calls)
+                    call, interpolation = interpolate_embedding(model_layer)
+                    self._embedding_calls.append(call)
+                    self._interpolations.append(interpolation)
+                    explain_outputs.append(tf.nest.flatten(node.outputs)[0])
+                    break
+            self._embedding_offsets.append(embedding_offset)
+        self._model = tf.keras.Model(inputs=model.inputs, outputs=explain_outputs)
+
+    def explain(self, class_index, alpha, inputs, baselines):
+        interpolated_inputs = []
+        for input_offset, model_input in enumerate(inputs):
+            if model_input.dtype.is_floating:
+                baseline = tf.cast(baselines[input_offset], model_input.dtype)
+                model_input = baseline + tf.cast(alpha, model_input.dtype) * (model_input - baseline)
+            else:
+                embedding_offset = self._embedding_offsets[input_offset]
+                if embedding_offset != -1:
+                    self._interpolations[embedding_offset]["alpha"] = alpha
+                    self._interpolations[embedding_offset]["baseline"] = baselines[input_offset]
+            interpolated_inputs.append(model_input)
+
+        try:
+            with tf.GradientTape() as tape:
+                for model_input in interpolated_inputs:
+                    if model_input.dtype.is_floating:
+                        tape.watch(model_input)
+                explain_outputs = tf.nest.flatten(self._model(interpolated_inputs, training=False))
+                flat_output = tf.reshape(explain_outputs[0], [tf.shape(explain_outputs[0])[0], -1])
+                target = tf.gather(flat_output, class_index, axis=1)
+        finally:
+            # Only explain interpolates, other signatures traced afterwards get the plain embeddings
+            for interpolation in self._interpolations:
+                interpolation.clear()
+
+        sources = []
+        for input_offset, model_input in enumerate(interpolated_inputs):
+            if model_input.dtype.is_floating or self._embedding_offsets[input_offset] == -1:
+                sources.append(model_input)
+            else:
+                sources.append(explain_outputs[1 + self._embedding_offsets[input_offset]])
+        gradients = tape.gradient(target, sources)
+
+        attributions = []
+        for input_offset, model_input in enumerate(inputs):
+            if gradients[input_offset] is None:
+                attributions.append(tf.zeros_like(tf.cast(model_input, tf.float32)))
+            elif model_input.dtype.is_floating:
+                attributions.append(gradients[input_offset])
+            else:
+                embeddings = self._embedding_calls[self._embedding_offsets[input_offset]](model_input)
+                baseline = tf.cast(baselines[input_offset], embeddings.dtype)
+                attributions.append(
+                    tf.cast(tf.reduce_sum(gradients[input_offset] * (embeddings - baseline), axis=-1), tf.float32)
+                )
+
+        return attributions
+`
+
+func GetTfkgPythonCode(customDefinitions []string) string {
+	return strings.ReplaceAll(pythonCommonCode+"\n\n"+`# Run after common_model.py, which loads the config and defines the schedules and losses
+
 def save_model(dir):
     model = tf.keras.models.model_from_json(config["model_config"], custom_objects=custom_objects)
 
@@ -53,6 +406,7 @@ def save_model(dir):
     for model_layer in model_config["config"]["layers"]:
         if model_layer["class_name"] == "InputLayer":
             input_shape = [config["batch_size"]]
+            # The batch dimension is dynamic so the final partial batch of a dataset can be trained and evaluated
             predict_input_shape = [None]
             for dim in model_layer["config"]["batch_input_shape"][1:]:
                 input_shape.append(dim)
@@ -61,7 +415,7 @@ def save_model(dir):
                 tf.zeros(shape=input_shape, dtype=model_layer["config"]["dtype"])
             )
             learn_signature.append(tf.TensorSpec(
-                shape=input_shape,
+                shape=predict_input_shape,
                 dtype=model_layer["config"]["dtype"],
             ))
             predict_input_signature.append(tf.TensorSpec(
@@ -69,24 +423,59 @@ def save_model(dir):
                 dtype=model_layer["config"]["dtype"],
             ))
 
-    last_layer = model_config["config"]["layers"][-1]
+    losses = config["losses"]
+    gradient_accumulation_steps = config["gradient_accumulation_steps"]
+    weight_averaging = config["weight_averaging"]
+    loss_weights = config["loss_weights"]
+    num_outputs = len(model.outputs)
+
+    y_signature = []
+    y_zeros = []
+    for i, model_output in enumerate(model.outputs):
+        y_dtype = model_output.dtype
+        y_shape = model_output.shape
 
-    y_dtype = model.output.dtype
-    y_shape = model.output.shape
+        if losses[i]["class_name"] in sparse_label_losses:
+            y_dtype = tf.int32
+            y_shape = [None, 1]
+            # Sequence outputs have a label per position E.G. [batch, timesteps, classes] takes [batch, timesteps]
+            if len(model_output.shape) > 2:
+                y_shape = model_output.shape[:-1]
 
-    if config["loss"] == "binary_crossentropy" or config["loss"] == "sparse_categorical_crossentropy":
-        y_dtype = tf.int32
-        y_shape = [config["batch_size"], 1]
+        y_signature.append(tf.TensorSpec(shape=y_shape, dtype=y_dtype))
 
+        output_shape = [config["batch_size"]]
+        for dim in y_shape[1:]:
+            output_shape.append(dim)
+
+        y_zeros.append(tf.zeros(shape=output_shape, dtype=y_dtype))
+
+    # The labels of any additional outputs come before the model inputs. This keeps the signature flat and means
+    # single output models keep the same learn_y, learn_class_weights and learn_inputs_N placeholders
     learn_input_signature = [
-        tf.TensorSpec(shape=y_shape, dtype=y_dtype),
-        tf.TensorSpec(shape=config["batch_size"], dtype=tf.float32),
+        y_signature[0],
+        tf.TensorSpec(shape=[None], dtype=tf.float32),
     ]
+    for sig in y_signature[1:]:
+        learn_input_signature.append(sig)
     for sig in learn_signature:
         learn_input_signature.append(sig)
 
     evaluate_input_signature = learn_input_signature
 
+    # distill takes the predictions of a teacher model for the first output, a temperature and the weight of the
+    # distillation loss after the labels
+    soft_targets_shape = [None] + model.outputs[0].shape[1:].as_list()
+    distill_input_signature = [
+        y_signature[0],
+        tf.TensorSpec(shape=[None], dtype=tf.float32),
+        tf.TensorSpec(shape=soft_targets_shape, dtype=tf.float32),
+        tf.TensorSpec(shape=[], dtype=tf.float32),
+        tf.TensorSpec(shape=[], dtype=tf.float32),
+    ]
+    for sig in learn_input_signature[2:]:
+        distill_input_signature.append(sig)
+
 
     class GolangModel(tf.Module):
         def __init__(self):
@@ -94,38 +483,165 @@ def save_model(dir):
 
             self._model = model
 
+            # Frozen layers with weights are made trainable so set_layer_trainable can unfreeze them after
+            # compilation. Whether a layer is actually trained is controlled by its flag in _layer_trainable
+            self._layer_names = []
+            self._layer_trainable = []
+            variable_layers = {}
+            for layer_offset, model_layer in enumerate(self._model.layers):
+                self._layer_names.append(model_layer.name)
+                layer_trainable = tf.Variable(model_layer.trainable, dtype=tf.bool, trainable=False)
+                self._layer_trainable.append(layer_trainable)
+                if not model_layer.trainable and model_layer.weights:
+                    model_layer.trainable = True
+                for sub_layer in [model_layer] + list(model_layer.submodules):
+                    if isinstance(sub_layer, tf.keras.layers.BatchNormalization):
+                        freeze_batch_normalization(sub_layer, layer_trainable)
+                for weight in model_layer.trainable_weights:
+                    variable_layers[weight.ref()] = layer_offset
+            self._variable_trainable = []
+            for variable in self._model.trainable_variables:
+                if variable.ref() in variable_layers:
+                    self._variable_trainable.append(self._layer_trainable[variable_layers[variable.ref()]])
+                else:
+                    self._variable_trainable.append(None)
+
+            self._predict_layer_names, predict_layer_outputs = get_predict_layers(self._model)
+            self._layer_model = tf.keras.Model(inputs=self._model.inputs, outputs=predict_layer_outputs)
+
+            self._explainer = Explainer(self._model)
+
             self._global_step = tf.Variable(0, dtype=tf.int32, trainable=False)
             opt = tf.keras.optimizers.get(config["optimizer"]["class_name"])
-            self._optimizer = opt.from_config(config["optimizer"]["config"])
-            if config["loss"] == "binary_crossentropy":
-                loss_func = tf.keras.losses.BinaryCrossentropy(reduction="none")
-
-                def loss(y_true, y_pred, class_weights):
-                    weighted_loss = tf.multiply(
-                        loss_func(y_true, y_pred),
-                        class_weights
+            self._optimizer = opt.from_config(config["optimizer"]["config"], custom_objects=custom_objects)
+            # Schedules are scaled so set_learning_rate can change the learning rate without losing the schedule
+            self._learning_rate_scale = tf.Variable(1.0, dtype=tf.float32, trainable=False)
+            if isinstance(self._optimizer.learning_rate, tf.keras.optimizers.schedules.LearningRateSchedule):
+                self._optimizer.learning_rate = ScaledSchedule(
+                    self._optimizer.learning_rate,
+                    self._learning_rate_scale
+                )
+            # Slots must exist before apply_gradients is called inside tf.cond
+            self._optimizer._create_all_weights(self._model.trainable_variables)
+            if gradient_accumulation_steps > 1:
+                self._accumulation_step = tf.Variable(0, dtype=tf.int32, trainable=False)
+                self._accumulated_gradients = []
+                for variable in self._model.trainable_variables:
+                    self._accumulated_gradients.append(
+                        tf.Variable(tf.zeros_like(variable), trainable=False)
                     )
-                    return tf.reduce_mean(weighted_loss)
-
-                self._loss = loss
-            elif config["loss"] == "sparse_categorical_crossentropy":
-                loss_func = tf.keras.losses.SparseCategoricalCrossentropy(reduction="none")
+            # Shadow weights holding an exponential moving average or stochastic weight average of the weights
+            self._averaged_weights = []
+            if weight_averaging:
+                for weight in self._model.weights:
+                    self._averaged_weights.append(tf.Variable(weight.read_value(), trainable=False))
+            self._losses = []
+            for loss_config in losses:
+                self._losses.append(get_loss(loss_config))
+
+        def _split_labels(self, y, inputs):
+            ys = [y]
+            for extra_y in inputs[:num_outputs - 1]:
+                ys.append(extra_y)
+
+            return ys, list(inputs[num_outputs - 1:])
+
+        def _call_model(self, inputs, training):
+            logits = self._model(inputs, training=training)
+            if num_outputs == 1:
+                return [logits]
+
+            return list(logits)
+
+        def _loss(self, ys, logits, class_weights):
+            output_losses = []
+            weighted_losses = []
+            for i in range(num_outputs):
+                output_loss = self._losses[i](ys[i], logits[i], class_weights)
+                output_losses.append(output_loss)
+                weighted_losses.append(output_loss * loss_weights[i])
+
+            return tf.add_n(weighted_losses), output_losses
+
+        def _apply_gradients(self, gradients):
+            variables = self._model.trainable_variables
+
+            def apply():
+                self._optimizer.apply_gradients(
+                    (gradient, variable) for gradient, variable in zip(gradients, variables) if gradient is not None
+                )
+                return tf.constant(True)
+
+            def apply_and_restore_frozen():
+                previous_values = []
+                for variable, trainable in zip(variables, self._variable_trainable):
+                    if trainable is not None:
+                        previous_values.append(variable.read_value())
+                    else:
+                        previous_values.append(None)
+
+                apply()
+
+                # Optimizers with momentum still move weights with a zero gradient, so frozen weights are restored
+                for variable, previous_value, trainable in zip(variables, previous_values, self._variable_trainable):
+                    if trainable is not None:
+                        variable.assign(tf.where(trainable, variable, previous_value))
+                return tf.constant(True)
+
+            if len(self._layer_trainable) == 0:
+                apply()
+                return
+
+            tf.cond(
+                tf.reduce_all(tf.stack(self._layer_trainable)),
+                apply,
+                apply_and_restore_frozen,
+            )
 
-                def loss(y_true, y_pred, class_weights):
-                    weighted_loss = tf.multiply(
-                        loss_func(y_true, y_pred),
-                        class_weights
-                    )
-                    return tf.reduce_mean(weighted_loss)
+        def _minimize(self, loss, tape):
+            gradients = tape.gradient(loss, self._model.trainable_variables)
+            masked_gradients = []
+            for gradient, trainable in zip(gradients, self._variable_trainable):
+                if gradient is not None and trainable is not None:
+                    gradient = tf.where(trainable, tf.convert_to_tensor(gradient), tf.zeros_like(gradient))
+                masked_gradients.append(gradient)
+            gradients = masked_gradients
+
+            if gradient_accumulation_steps <= 1:
+                self._apply_gradients(gradients)
+                return
+
+            for accumulated_gradient, gradient in zip(self._accumulated_gradients, gradients):
+                if gradient is not None:
+                    accumulated_gradient.assign_add(tf.convert_to_tensor(gradient) / gradient_accumulation_steps)
+            self._accumulation_step.assign_add(1)
+
+            def apply():
+                self._apply_gradients(
+                    [accumulated_gradient.read_value() for accumulated_gradient in self._accumulated_gradients]
+                )
+                for accumulated_gradient in self._accumulated_gradients:
+                    accumulated_gradient.assign(tf.zeros_like(accumulated_gradient))
+                self._accumulation_step.assign(0)
+                return tf.constant(True)
+
+            tf.cond(
+                self._accumulation_step >= gradient_accumulation_steps,
+                apply,
+                lambda: tf.constant(False),
+            )
 
-                self._loss = loss
-            elif config["loss"] == "mse":
-                loss_func = tf.keras.losses.MeanSquaredError(reduction="none")
+        def _results(self, loss, logits, output_losses, extra_results=None):
+            if extra_results is None:
+                extra_results = []
 
-                def loss(y_true, y_pred, class_weights):
-                    return tf.reduce_mean(loss_func(y_true, y_pred))
+            if num_outputs == 1:
+                return [
+                    loss,
+                    logits[0]
+                ] + extra_results
 
-                self._loss = loss
+            return [loss] + logits + output_losses + extra_results
 
         @tf.function(input_signature=learn_input_signature)
         def learn(
@@ -134,16 +650,68 @@ def save_model(dir):
                 class_weights,
                 *inputs
         ):
+            ys, inputs = self._split_labels(y, inputs)
             self._global_step.assign_add(1)
+            learning_rate = get_learning_rate(self._optimizer)
             with tf.GradientTape() as tape:
-                logits = self._model(inputs, training=True)
-                loss = self._loss(y, logits, class_weights)
+                logits = self._call_model(inputs, True)
+                loss, output_losses = self._loss(ys, logits, class_weights)
+
+            self._minimize(loss, tape)
+            # The learning rate used for this step is always the last result
+            return self._results(loss, logits, output_losses, [learning_rate])
+
+        def _distillation_loss(self, soft_targets, logits, temperature, class_weights):
+            # The predictions are softened by the temperature as logits. The teacher is expected to output logits when
+            # the loss of the first output is from_logits, otherwise both outputs are probabilities which are converted
+            # back to logits
+            teacher = soft_targets
+            student = logits
+            if not losses[0]["config"].get("from_logits", False):
+                epsilon = tf.keras.backend.epsilon()
+                teacher = tf.clip_by_value(teacher, epsilon, 1 - epsilon)
+                student = tf.clip_by_value(student, epsilon, 1 - epsilon)
+                if logits.shape[-1] == 1:
+                    teacher = tf.math.log(teacher) - tf.math.log(1 - teacher)
+                    student = tf.math.log(student) - tf.math.log(1 - student)
+                else:
+                    teacher = tf.math.log(teacher)
+                    student = tf.math.log(student)
+            if logits.shape[-1] == 1:
+                teacher = tf.sigmoid(teacher / temperature)
+                student = tf.sigmoid(student / temperature)
+                sample_loss = tf.keras.losses.binary_crossentropy(teacher, student)
+            else:
+                teacher = tf.nn.softmax(teacher / temperature, axis=-1)
+                student = tf.nn.softmax(student / temperature, axis=-1)
+                sample_loss = tf.keras.losses.kl_divergence(teacher, student)
+            if len(sample_loss.shape) > 1:
+                sample_loss = tf.reduce_mean(sample_loss, axis=list(range(1, len(sample_loss.shape))))
+
+            # Softened gradients shrink by 1 / temperature^2 so the loss is scaled back up
+            return tf.reduce_mean(sample_loss * class_weights) * temperature * temperature
+
+        @tf.function(input_signature=distill_input_signature)
+        def distill(
+                self,
+                y,
+                class_weights,
+                soft_targets,
+                temperature,
+                alpha,
+                *inputs
+        ):
+            ys, inputs = self._split_labels(y, inputs)
+            self._global_step.assign_add(1)
+            learning_rate = get_learning_rate(self._optimizer)
+            with tf.GradientTape() as tape:
+                logits = self._call_model(inputs, True)
+                ground_truth_loss, output_losses = self._loss(ys, logits, class_weights)
+                distillation_loss = self._distillation_loss(soft_targets, logits[0], temperature, class_weights)
+                loss = alpha * distillation_loss + (1 - alpha) * ground_truth_loss
 
-            self._optimizer.minimize(loss, self._model.trainable_variables, tape=tape)
-            return [
-                loss,
-                logits
-            ]
+            self._minimize(loss, tape)
+            return self._results(loss, logits, output_losses, [learning_rate])
 
         @tf.function(input_signature=evaluate_input_signature)
         def evaluate(
@@ -152,20 +720,134 @@ def save_model(dir):
                 class_weights,
                 *inputs
         ):
-            logits = self._model(list(inputs), training=False)
-            loss = self._loss(y, logits, class_weights)
+            ys, inputs = self._split_labels(y, inputs)
+            logits = self._call_model(inputs, False)
+            loss, output_losses = self._loss(ys, logits, class_weights)
 
-            return [
-                loss,
-                logits
-            ]
+            return self._results(loss, logits, output_losses)
 
         @tf.function(input_signature=predict_input_signature)
         def predict(
                 self,
                 *inputs,
         ):
-            return [self._model(list(inputs), training=False)]
+            return self._call_model(list(inputs), False)
+
+        @tf.function(input_signature=predict_input_signature)
+        def predict_layers(
+                self,
+                *inputs,
+        ):
+            return tf.nest.flatten(self._layer_model(list(inputs), training=False))
+
+        @tf.function(input_signature=[])
+        def get_predict_layer_names(
+                self,
+        ):
+            return [tf.constant(self._predict_layer_names, dtype=tf.string)]
+
+        @tf.function(
+            input_signature=[
+                tf.TensorSpec(shape=[], dtype=tf.int32),
+                tf.TensorSpec(shape=[], dtype=tf.float32),
+            ] + predict_input_signature + get_explain_baseline_signature(predict_input_signature)
+        )
+        def explain(
+                self,
+                class_index,
+                alpha,
+                *inputs,
+        ):
+            # The inputs are followed by a baseline for each input
+            inputs = list(inputs)
+            return self._explainer.explain(
+                class_index,
+                alpha,
+                inputs[:len(predict_input_signature)],
+                inputs[len(predict_input_signature):],
+            )
+
+        @tf.function(input_signature=[])
+        def get_learning_rate(
+                self,
+        ):
+            return [get_learning_rate(self._optimizer)]
+
+        @tf.function(input_signature=[tf.TensorSpec(shape=[], dtype=tf.float32)])
+        def set_learning_rate(
+                self,
+                learning_rate,
+        ):
+            if isinstance(self._optimizer.learning_rate, ScaledSchedule):
+                unscaled = tf.cast(self._optimizer.learning_rate.schedule(self._optimizer.iterations), tf.float32)
+                self._learning_rate_scale.assign(learning_rate / unscaled)
+            else:
+                self._optimizer.learning_rate.assign(learning_rate)
+
+            return [get_learning_rate(self._optimizer)]
+
+        @tf.function(input_signature=[
+            tf.TensorSpec(shape=[], dtype=tf.string),
+            tf.TensorSpec(shape=[], dtype=tf.bool),
+        ])
+        def set_layer_trainable(
+                self,
+                layer_name,
+                trainable,
+        ):
+            found = tf.constant(0, dtype=tf.int32)
+            for layer_offset, name in enumerate(self._layer_names):
+                is_layer = tf.equal(layer_name, name)
+                self._layer_trainable[layer_offset].assign(
+                    tf.logical_or(
+                        tf.logical_and(is_layer, trainable),
+                        tf.logical_and(tf.logical_not(is_layer), self._layer_trainable[layer_offset])
+                    )
+                )
+                found += tf.cast(is_layer, tf.int32)
+
+            return [found]
+
+        @tf.function(input_signature=[])
+        def get_global_step(
+                self,
+        ):
+            return [self._global_step.read_value()]
+
+        @tf.function(input_signature=[])
+        def get_layer_trainable(
+                self,
+        ):
+            return [
+                tf.constant(self._layer_names, dtype=tf.string),
+                tf.stack(self._layer_trainable),
+            ]
+
+        @tf.function(input_signature=[tf.TensorSpec(shape=[], dtype=tf.float32)])
+        def update_averaged_weights(
+                self,
+                decay,
+        ):
+            # A decay of 0 copies the weights into the average
+            for weight, averaged_weight in zip(self._model.weights, self._averaged_weights):
+                if weight.dtype.is_floating:
+                    weight_decay = tf.cast(decay, weight.dtype)
+                    averaged_weight.assign(weight_decay * averaged_weight + (1 - weight_decay) * weight)
+                else:
+                    averaged_weight.assign(weight)
+
+            return [tf.constant(len(self._averaged_weights) > 0)]
+
+        @tf.function(input_signature=[])
+        def swap_averaged_weights(
+                self,
+        ):
+            for weight, averaged_weight in zip(self._model.weights, self._averaged_weights):
+                value = weight.read_value()
+                weight.assign(averaged_weight)
+                averaged_weight.assign(value)
+
+            return [tf.constant(len(self._averaged_weights) > 0)]
 
         @tf.function(input_signature=[])
         def get_weights(
@@ -188,26 +870,36 @@ def save_model(dir):
 
     gm = GolangModel()
 
-    output_shape = [config["batch_size"]]
-    for dim in y_shape[1:]:
-        output_shape.append(dim)
-
-    y_zeros = tf.zeros(shape=output_shape, dtype=y_dtype)
     class_weights_ones = tf.ones(shape=config["batch_size"], dtype=tf.float32)
 
     print("Tracing learn")
 
     gm.learn(
-        y_zeros,
+        y_zeros[0],
+        class_weights_ones,
+        *y_zeros[1:],
+        *zero_inputs,
+    )
+
+    print("Tracing distill")
+
+    # Only traced so compiling does not take an extra training step
+    gm.distill.get_concrete_function(
+        y_zeros[0],
         class_weights_ones,
+        tf.zeros(shape=[config["batch_size"]] + soft_targets_shape[1:], dtype=tf.float32),
+        tf.constant(1.0, dtype=tf.float32),
+        tf.constant(0.5, dtype=tf.float32),
+        *y_zeros[1:],
         *zero_inputs,
     )
 
     print("Tracing evaluate")
 
     gm.evaluate(
-        y_zeros,
+        y_zeros[0],
         class_weights_ones,
+        *y_zeros[1:],
         *zero_inputs,
     )
 
@@ -215,11 +907,43 @@ def save_model(dir):
 
     gm.predict(*zero_inputs)
 
+    print("Tracing predict_layers")
+
+    gm.predict_layers(*zero_inputs)
+    gm.get_predict_layer_names()
+
+    print("Tracing explain")
+
+    gm.explain(
+        tf.constant(0, dtype=tf.int32),
+        tf.constant(1.0, dtype=tf.float32),
+        *zero_inputs,
+        *[tf.zeros([1], dtype=spec.dtype) for spec in get_explain_baseline_signature(predict_input_signature)],
+    )
+
     print("Tracing set_weights")
 
     ws = gm.get_weights()
     gm.set_weights(*ws)
 
+    print("Tracing learning rate")
+
+    gm.set_learning_rate(gm.get_learning_rate()[0])
+
+    print("Tracing set_layer_trainable")
+
+    gm.set_layer_trainable(tf.constant(""), tf.constant(True))
+    gm.get_layer_trainable()
+
+    print("Tracing get_global_step")
+
+    gm.get_global_step()
+
+    print("Tracing weight averaging")
+
+    gm.update_averaged_weights.get_concrete_function(tf.constant(0.0, dtype=tf.float32))
+    gm.swap_averaged_weights.get_concrete_function()
+
     print("Saving model")
 
     tf.saved_model.save(
@@ -227,9 +951,20 @@ def save_model(dir):
         dir,
         signatures={
             "learn": gm.learn,
+            "distill": gm.distill,
             "evaluate": gm.evaluate,
             "predict": gm.predict,
+            "predict_layers": gm.predict_layers,
+            "get_predict_layer_names": gm.get_predict_layer_names,
+            "explain": gm.explain,
+            "get_learning_rate": gm.get_learning_rate,
+            "set_learning_rate": gm.set_learning_rate,
+            "set_layer_trainable": gm.set_layer_trainable,
+            "get_layer_trainable": gm.get_layer_trainable,
+            "get_global_step": gm.get_global_step,
             "set_weights": gm.set_weights,
+            "update_averaged_weights": gm.update_averaged_weights,
+            "swap_averaged_weights": gm.swap_averaged_weights,
         },
     )
 
@@ -244,29 +979,23 @@ if config["cpu_inference"]:
 `, "# tfkg-custom-definitions", strings.Join(customDefinitions, "\n"))
 }
 
-func GetVanillaPythonCode() string {
-	return `import json
-import os
-import logging
-import sys
-
-import tensorflow as tf
-import numpy as np
+func GetVanillaPythonCode(customDefinitions []string) string {
+	return strings.ReplaceAll(pythonCommonCode+"\n\n"+`# Run after common_model.py, which loads the config and defines the schedules and losses
 
-os.environ['TF_CPP_MIN_LOG_LEVEL'] = '2'  # ERROR
 os.environ["CUDA_VISIBLE_DEVICES"] = "-1"
-logging.getLogger('tensorflow').setLevel(logging.ERROR)
-logging.disable(logging.WARNING)
-
-with open(sys.argv[1], "r") as f:
-    config = json.load(f)
 
 print("Loading Vanilla model")
 
-model = tf.keras.models.load_model(config["model_dir"])
+model = tf.keras.models.load_model(config["model_dir"], custom_objects=custom_objects)
+
+y_signature = tf.TensorSpec(shape=(None, 1), dtype=tf.int32)
+if len(model.outputs[0].shape) > 2:
+    y_signature = tf.TensorSpec(shape=model.outputs[0].shape[:-1], dtype=tf.int32)
+if config["loss"]["class_name"] not in sparse_label_losses:
+    y_signature = tf.TensorSpec(shape=model.outputs[0].shape, dtype=model.outputs[0].dtype)
 
 learn_input_signature = [
-    tf.TensorSpec(shape=(None, 1), dtype=tf.int32),
+    y_signature,
     tf.TensorSpec(shape=None, dtype=tf.float32),
 ]
 predict_input_signature = []
@@ -298,23 +1027,22 @@ class GolangModel(tf.Module):
 
         self._model = model
 
+        self._predict_layer_names, predict_layer_outputs = get_predict_layers(self._model)
+        self._layer_model = tf.keras.Model(inputs=self._model.inputs, outputs=predict_layer_outputs)
+
+        self._explainer = Explainer(self._model)
+
         self._global_step = tf.Variable(0, dtype=tf.int32, trainable=False)
         opt = tf.keras.optimizers.get(config["optimizer"]["class_name"])
-        self._optimizer = opt.from_config(config["optimizer"]["config"])
-        loss_func = None
-        if config["loss"] == "binary_crossentropy":
-            loss_func = tf.keras.losses.BinaryCrossentropy(reduction="none")
-        elif config["loss"] == "sparse_categorical_crossentropy":
-            loss_func = tf.keras.losses.SparseCategoricalCrossentropy(reduction="none")
-
-        def loss(y_true, y_pred, class_weights):
-            weighted_loss = tf.multiply(
-                loss_func(y_true, y_pred),
-                class_weights
+        self._optimizer = opt.from_config(config["optimizer"]["config"], custom_objects=custom_objects)
+        # Schedules are scaled so set_learning_rate can change the learning rate without losing the schedule
+        self._learning_rate_scale = tf.Variable(1.0, dtype=tf.float32, trainable=False)
+        if isinstance(self._optimizer.learning_rate, tf.keras.optimizers.schedules.LearningRateSchedule):
+            self._optimizer.learning_rate = ScaledSchedule(
+                self._optimizer.learning_rate,
+                self._learning_rate_scale
             )
-            return tf.reduce_mean(weighted_loss)
-
-        self._loss = loss
+        self._loss = get_loss(config["loss"])
 
     @tf.function(input_signature=learn_input_signature)
     def learn(
@@ -324,6 +1052,7 @@ class GolangModel(tf.Module):
             *inputs
     ):
         self._global_step.assign_add(1)
+        learning_rate = get_learning_rate(self._optimizer)
         with tf.GradientTape() as tape:
             logits = self._model(list(inputs), training=True)
             loss = self._loss(y, logits, class_weights)
@@ -337,7 +1066,8 @@ class GolangModel(tf.Module):
         )
         return [
             loss,
-            logits
+            logits,
+            learning_rate
         ]
 
     @tf.function(input_signature=evaluate_input_signature)
@@ -362,6 +1092,59 @@ class GolangModel(tf.Module):
     ):
         return [self._model(list(inputs), training=False)]
 
+    @tf.function(input_signature=predict_input_signature)
+    def predict_layers(
+            self,
+            *inputs,
+    ):
+        return tf.nest.flatten(self._layer_model(list(inputs), training=False))
+
+    @tf.function(input_signature=[])
+    def get_predict_layer_names(
+            self,
+    ):
+        return [tf.constant(self._predict_layer_names, dtype=tf.string)]
+
+    @tf.function(
+        input_signature=[
+            tf.TensorSpec(shape=[], dtype=tf.int32),
+            tf.TensorSpec(shape=[], dtype=tf.float32),
+        ] + predict_input_signature + get_explain_baseline_signature(predict_input_signature)
+    )
+    def explain(
+            self,
+            class_index,
+            alpha,
+            *inputs,
+    ):
+        # The inputs are followed by a baseline for each input
+        inputs = list(inputs)
+        return self._explainer.explain(
+            class_index,
+            alpha,
+            inputs[:len(predict_input_signature)],
+            inputs[len(predict_input_signature):],
+        )
+
+    @tf.function(input_signature=[])
+    def get_learning_rate(
+            self,
+    ):
+        return [get_learning_rate(self._optimizer)]
+
+    @tf.function(input_signature=[tf.TensorSpec(shape=[], dtype=tf.float32)])
+    def set_learning_rate(
+            self,
+            learning_rate,
+    ):
+        if isinstance(self._optimizer.learning_rate, ScaledSchedule):
+            unscaled = tf.cast(self._optimizer.learning_rate.schedule(self._optimizer.iterations), tf.float32)
+            self._learning_rate_scale.assign(learning_rate / unscaled)
+        else:
+            self._optimizer.learning_rate.assign(learning_rate)
+
+        return [get_learning_rate(self._optimizer)]
+
     @tf.function(input_signature=[])
     def get_weights(
             self,
@@ -371,7 +1154,10 @@ class GolangModel(tf.Module):
 
 gm = GolangModel()
 
-y_zeros = tf.zeros(shape=[1, 1], dtype=tf.int32)
+y_zeros_shape = [1]
+for dim in y_signature.shape[1:]:
+    y_zeros_shape.append(dim)
+y_zeros = tf.zeros(shape=y_zeros_shape, dtype=y_signature.dtype)
 class_weights_ones = tf.ones(shape=1, dtype=tf.float32)
 
 print("Tracing learn")
@@ -394,10 +1180,28 @@ print("Tracing predict")
 
 gm.predict(*zero_inputs)
 
+print("Tracing predict_layers")
+
+gm.predict_layers(*zero_inputs)
+gm.get_predict_layer_names()
+
+print("Tracing explain")
+
+gm.explain(
+    tf.constant(0, dtype=tf.int32),
+    tf.constant(1.0, dtype=tf.float32),
+    *zero_inputs,
+    *[tf.zeros([1], dtype=spec.dtype) for spec in get_explain_baseline_signature(predict_input_signature)],
+)
+
 print("Tracing get_weights")
 
 gm.get_weights()
 
+print("Tracing learning rate")
+
+gm.set_learning_rate(gm.get_learning_rate()[0])
+
 print("Saving model")
 
 tf.saved_model.save(
@@ -407,9 +1211,14 @@ tf.saved_model.save(
         "learn": gm.learn,
         "evaluate": gm.evaluate,
         "predict": gm.predict,
+        "predict_layers": gm.predict_layers,
+        "get_predict_layer_names": gm.get_predict_layer_names,
+        "explain": gm.explain,
+        "get_learning_rate": gm.get_learning_rate,
+        "set_learning_rate": gm.set_learning_rate,
         "get_weights": gm.get_weights,
     },
 )
 
-print("Completed model base")`
+print("Completed model base")`, "# tfkg-custom-definitions", strings.Join(customDefinitions, "\n"))
 }
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"github.com/codingbeard/cblog"
+	"github.com/codingbeard/tfkg/callback"
+	"github.com/codingbeard/tfkg/data"
+	"github.com/galeone/tensorflow/tensorflow/go/core/protobuf/for_core_protos_go_proto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const trainingStateFileName = "training-state.json"
+
+type fitState struct {
+	config    FitConfig
+	averaging *weightAveraging
+}
+
+// TrainingState is saved alongside the model whenever a callback saves it during Fit. The optimizer slots and step
+// count are part of the saved model variables
+type TrainingState struct {
+	Epoch int `json:"epoch"`
+	// Batch is the number of training batches of Epoch which had been trained when the checkpoint was saved
+	Batch int `json:"batch"`
+	// EpochComplete is true when every training batch of Epoch had been trained when the checkpoint was saved
+	EpochComplete bool               `json:"epoch_complete"`
+	Epochs        int                `json:"epochs"`
+	BatchSize     int                `json:"batch_size"`
+	GlobalStep    int                `json:"global_step"`
+	ShuffleSeed   int64              `json:"shuffle_seed"`
+	BestMetrics   map[string]float64 `json:"best_metrics"`
+	// AveragedWeights is the number of weights in the average of FitConfig.WeightAveraging
+	AveragedWeights int `json:"averaged_weights"`
+	// AveragedWeightsSwapped is true when the checkpoint was saved while validating with the averaged weights, so the
+	// trained weights are in the averaged weights of the saved model
+	AveragedWeightsSwapped bool `json:"averaged_weights_swapped"`
+}
+
+func (m *TfkgModel) saveTrainingState(
+	dir string,
+	callbacks []callback.Callback,
+	event callback.Event,
+	mode callback.Mode,
+	epoch int,
+	batch int,
+) error {
+	config := m.fitState.config
+	if epoch == config.InitialEpoch+1 {
+		batch += config.InitialBatch
+	}
+
+	state := TrainingState{
+		Epoch:         epoch,
+		Batch:         batch,
+		EpochComplete: mode == callback.ModeVal || event == callback.EventEnd,
+		Epochs:        config.Epochs,
+		BatchSize:     config.BatchSize,
+		ShuffleSeed:   config.ShuffleSeed,
+		BestMetrics:   make(map[string]float64),
+	}
+
+	if m.fitState.averaging != nil {
+		state.AveragedWeights = m.fitState.averaging.averaged
+		state.AveragedWeightsSwapped = m.fitState.averaging.swapped
+	}
+
+	if _, ok := m.model.Signatures["get_global_step"]; ok {
+		globalStep, e := m.GetGlobalStep()
+		if e != nil {
+			return e
+		}
+		state.GlobalStep = globalStep
+	}
+
+	for _, call := range callbacks {
+		bestMetricsGetter, ok := call.(callback.HasBestMetrics)
+		if ok {
+			for name, value := range bestMetricsGetter.GetBestMetrics() {
+				state.BestMetrics[name] = value
+			}
+		}
+	}
+
+	jsonBytes, e := json.MarshalIndent(state, "", "  ")
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	e = ioutil.WriteFile(filepath.Join(dir, trainingStateFileName), jsonBytes, os.ModePerm)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
+
+// LoadTrainingState reads the training state saved alongside a model checkpoint during Fit
+func LoadTrainingState(dir string) (TrainingState, error) {
+	var state TrainingState
+	jsonBytes, e := ioutil.ReadFile(filepath.Join(dir, trainingStateFileName))
+	if e != nil {
+		return state, e
+	}
+
+	e = json.Unmarshal(jsonBytes, &state)
+	if e != nil {
+		return state, e
+	}
+
+	return state, nil
+}
+
+// ResumeTraining loads a checkpoint saved during Fit and continues training from the epoch and batch it was saved at.
+// The dataset should be created the same way as for the interrupted run, without shuffling it. The InitialEpoch,
+// InitialBatch, ShuffleSeed and InitialAveragedWeights of the config are taken from the checkpoint, as are Epochs and
+// BatchSize if they are not set. The best metric values are restored to callbacks such as callback.Checkpoint
+func ResumeTraining(
+	errorHandler *cberrors.ErrorsContainer,
+	logger *cblog.Logger,
+	dir string,
+	dataset data.Dataset,
+	config FitConfig,
+	sessionOptions ...*for_core_protos_go_proto.ConfigProto,
+) (*TfkgModel, error) {
+	state, e := LoadTrainingState(dir)
+	if e != nil {
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	m, e := LoadModel(errorHandler, logger, dir, sessionOptions...)
+	if e != nil {
+		return nil, e
+	}
+
+	if config.Epochs == 0 {
+		config.Epochs = state.Epochs
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = state.BatchSize
+	}
+	if config.BatchSize != state.BatchSize {
+		e = fmt.Errorf("the checkpoint was trained with a batch size of %d, got: %d", state.BatchSize, config.BatchSize)
+		errorHandler.Error(e)
+		return nil, e
+	}
+
+	config.ShuffleSeed = state.ShuffleSeed
+	config.InitialAveragedWeights = state.AveragedWeights
+	if state.AveragedWeightsSwapped {
+		e = m.SwapAveragedWeights()
+		if e != nil {
+			return nil, e
+		}
+	}
+	if state.EpochComplete {
+		config.InitialEpoch = state.Epoch
+		config.InitialBatch = 0
+	} else {
+		config.InitialEpoch = state.Epoch - 1
+		config.InitialBatch = state.Batch
+	}
+
+	for _, call := range config.Callbacks {
+		bestMetricsSetter, ok := call.(callback.HasBestMetrics)
+		if ok {
+			bestMetricsSetter.SetBestMetrics(state.BestMetrics)
+		}
+	}
+
+	logger.InfoF(
+		"model",
+		"Resuming training from epoch %d batch %d at step %d",
+		config.InitialEpoch+1,
+		config.InitialBatch+1,
+		state.GlobalStep,
+	)
+
+	_, e = m.FitContext(context.Background(), dataset, config)
+	if e != nil {
+		return m, e
+	}
+
+	return m, nil
+}
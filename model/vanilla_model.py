@@ -1,25 +1,19 @@
-import json
-import os
-import logging
-import sys
+# Run after common_model.py, which loads the config and defines the schedules and losses
 
-import tensorflow as tf
-import numpy as np
-
-os.environ['TF_CPP_MIN_LOG_LEVEL'] = '2'  # ERROR
 os.environ["CUDA_VISIBLE_DEVICES"] = "-1"
-logging.getLogger('tensorflow').setLevel(logging.ERROR)
-logging.disable(logging.WARNING)
-
-with open(sys.argv[1], "r") as f:
-    config = json.load(f)
 
 print("Loading Vanilla model")
 
-model = tf.keras.models.load_model(config["model_dir"])
+model = tf.keras.models.load_model(config["model_dir"], custom_objects=custom_objects)
+
+y_signature = tf.TensorSpec(shape=(None, 1), dtype=tf.int32)
+if len(model.outputs[0].shape) > 2:
+    y_signature = tf.TensorSpec(shape=model.outputs[0].shape[:-1], dtype=tf.int32)
+if config["loss"]["class_name"] not in sparse_label_losses:
+    y_signature = tf.TensorSpec(shape=model.outputs[0].shape, dtype=model.outputs[0].dtype)
 
 learn_input_signature = [
-    tf.TensorSpec(shape=(None, 1), dtype=tf.int32),
+    y_signature,
     tf.TensorSpec(shape=None, dtype=tf.float32),
 ]
 predict_input_signature = []
@@ -51,23 +45,22 @@ class GolangModel(tf.Module):
 
         self._model = model
 
+        self._predict_layer_names, predict_layer_outputs = get_predict_layers(self._model)
+        self._layer_model = tf.keras.Model(inputs=self._model.inputs, outputs=predict_layer_outputs)
+
+        self._explainer = Explainer(self._model)
+
         self._global_step = tf.Variable(0, dtype=tf.int32, trainable=False)
         opt = tf.keras.optimizers.get(config["optimizer"]["class_name"])
-        self._optimizer = opt.from_config(config["optimizer"]["config"])
-        loss_func = None
-        if config["loss"] == "binary_crossentropy":
-            loss_func = tf.keras.losses.BinaryCrossentropy(reduction="none")
-        elif config["loss"] == "sparse_categorical_crossentropy":
-            loss_func = tf.keras.losses.SparseCategoricalCrossentropy(reduction="none")
-
-        def loss(y_true, y_pred, class_weights):
-            weighted_loss = tf.multiply(
-                loss_func(y_true, y_pred),
-                class_weights
+        self._optimizer = opt.from_config(config["optimizer"]["config"], custom_objects=custom_objects)
+        # Schedules are scaled so set_learning_rate can change the learning rate without losing the schedule
+        self._learning_rate_scale = tf.Variable(1.0, dtype=tf.float32, trainable=False)
+        if isinstance(self._optimizer.learning_rate, tf.keras.optimizers.schedules.LearningRateSchedule):
+            self._optimizer.learning_rate = ScaledSchedule(
+                self._optimizer.learning_rate,
+                self._learning_rate_scale
             )
-            return tf.reduce_mean(weighted_loss)
-
-        self._loss = loss
+        self._loss = get_loss(config["loss"])
 
     @tf.function(input_signature=learn_input_signature)
     def learn(
@@ -77,6 +70,7 @@ class GolangModel(tf.Module):
             *inputs
     ):
         self._global_step.assign_add(1)
+        learning_rate = get_learning_rate(self._optimizer)
         with tf.GradientTape() as tape:
             logits = self._model(list(inputs), training=True)
             loss = self._loss(y, logits, class_weights)
@@ -90,7 +84,8 @@ class GolangModel(tf.Module):
         )
         return [
             loss,
-            logits
+            logits,
+            learning_rate
         ]
 
     @tf.function(input_signature=evaluate_input_signature)
@@ -115,6 +110,59 @@ class GolangModel(tf.Module):
     ):
         return [self._model(list(inputs), training=False)]
 
+    @tf.function(input_signature=predict_input_signature)
+    def predict_layers(
+            self,
+            *inputs,
+    ):
+        return tf.nest.flatten(self._layer_model(list(inputs), training=False))
+
+    @tf.function(input_signature=[])
+    def get_predict_layer_names(
+            self,
+    ):
+        return [tf.constant(self._predict_layer_names, dtype=tf.string)]
+
+    @tf.function(
+        input_signature=[
+            tf.TensorSpec(shape=[], dtype=tf.int32),
+            tf.TensorSpec(shape=[], dtype=tf.float32),
+        ] + predict_input_signature + get_explain_baseline_signature(predict_input_signature)
+    )
+    def explain(
+            self,
+            class_index,
+            alpha,
+            *inputs,
+    ):
+        # The inputs are followed by a baseline for each input
+        inputs = list(inputs)
+        return self._explainer.explain(
+            class_index,
+            alpha,
+            inputs[:len(predict_input_signature)],
+            inputs[len(predict_input_signature):],
+        )
+
+    @tf.function(input_signature=[])
+    def get_learning_rate(
+            self,
+    ):
+        return [get_learning_rate(self._optimizer)]
+
+    @tf.function(input_signature=[tf.TensorSpec(shape=[], dtype=tf.float32)])
+    def set_learning_rate(
+            self,
+            learning_rate,
+    ):
+        if isinstance(self._optimizer.learning_rate, ScaledSchedule):
+            unscaled = tf.cast(self._optimizer.learning_rate.schedule(self._optimizer.iterations), tf.float32)
+            self._learning_rate_scale.assign(learning_rate / unscaled)
+        else:
+            self._optimizer.learning_rate.assign(learning_rate)
+
+        return [get_learning_rate(self._optimizer)]
+
     @tf.function(input_signature=[])
     def get_weights(
             self,
@@ -124,7 +172,10 @@ class GolangModel(tf.Module):
 
 gm = GolangModel()
 
-y_zeros = tf.zeros(shape=[1, 1], dtype=tf.int32)
+y_zeros_shape = [1]
+for dim in y_signature.shape[1:]:
+    y_zeros_shape.append(dim)
+y_zeros = tf.zeros(shape=y_zeros_shape, dtype=y_signature.dtype)
 class_weights_ones = tf.ones(shape=1, dtype=tf.float32)
 
 print("Tracing learn")
@@ -147,10 +198,28 @@ print("Tracing predict")
 
 gm.predict(*zero_inputs)
 
+print("Tracing predict_layers")
+
+gm.predict_layers(*zero_inputs)
+gm.get_predict_layer_names()
+
+print("Tracing explain")
+
+gm.explain(
+    tf.constant(0, dtype=tf.int32),
+    tf.constant(1.0, dtype=tf.float32),
+    *zero_inputs,
+    *[tf.zeros([1], dtype=spec.dtype) for spec in get_explain_baseline_signature(predict_input_signature)],
+)
+
 print("Tracing get_weights")
 
 gm.get_weights()
 
+print("Tracing learning rate")
+
+gm.set_learning_rate(gm.get_learning_rate()[0])
+
 print("Saving model")
 
 tf.saved_model.save(
@@ -160,6 +229,11 @@ tf.saved_model.save(
         "learn": gm.learn,
         "evaluate": gm.evaluate,
         "predict": gm.predict,
+        "predict_layers": gm.predict_layers,
+        "get_predict_layer_names": gm.get_predict_layer_names,
+        "explain": gm.explain,
+        "get_learning_rate": gm.get_learning_rate,
+        "set_learning_rate": gm.set_learning_rate,
         "get_weights": gm.get_weights,
     },
 )
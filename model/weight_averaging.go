@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	tf "github.com/galeone/tensorflow/tensorflow/go"
+)
+
+type WeightAveragingMode string
+
+var (
+	// WeightAveragingEMA keeps an exponential moving average of the weights, updated after every batch
+	WeightAveragingEMA WeightAveragingMode = "ema"
+	// WeightAveragingSWA keeps an equal average of the weights at the end of each epoch from StartEpoch
+	WeightAveragingSWA WeightAveragingMode = "swa"
+)
+
+// WeightAveragingConfig keeps averaged shadow weights during Fit. The model must be compiled with
+// CompileConfig.WeightAveraging
+type WeightAveragingConfig struct {
+	Mode WeightAveragingMode
+	// Decay is the weight of the previous average for each EMA update, defaults to 0.999
+	Decay float32
+	// StartEpoch is the first epoch averaged by SWA, defaults to 1
+	StartEpoch int
+	// Validate runs validation with the averaged weights, so callbacks such as checkpoints at the end of validation
+	// save the averaged weights. The trained weights are restored before the next epoch, and by ResumeTraining
+	Validate bool
+	// ApplyAtEnd leaves the averaged weights in the model when Fit ends, so Evaluate, Predict and Save use them
+	ApplyAtEnd bool
+}
+
+type weightAveraging struct {
+	config   WeightAveragingConfig
+	averaged int
+	// swapped is true while the averaged weights are in the model
+	swapped bool
+}
+
+func (m *TfkgModel) newWeightAveraging(config WeightAveragingConfig, averaged int) (*weightAveraging, error) {
+	if config.Mode != WeightAveragingEMA && config.Mode != WeightAveragingSWA {
+		e := fmt.Errorf("unknown weight averaging mode: %s", config.Mode)
+		m.errorHandler.Error(e)
+		return nil, e
+	}
+	if config.Decay == 0 {
+		config.Decay = 0.999
+	}
+	if config.StartEpoch == 0 {
+		config.StartEpoch = 1
+	}
+
+	averaging := &weightAveraging{
+		config:   config,
+		averaged: averaged,
+	}
+	if config.Mode == WeightAveragingEMA && averaged == 0 {
+		// The average starts from the current weights
+		e := m.UpdateAveragedWeights(0)
+		if e != nil {
+			return nil, e
+		}
+		averaging.averaged = 1
+	}
+
+	return averaging, nil
+}
+
+// afterBatch updates the EMA after each training step, it runs on the same goroutine as the session so the weights
+// are not read mid step
+func (w *weightAveraging) afterBatch(m *TfkgModel) error {
+	if w.config.Mode != WeightAveragingEMA {
+		return nil
+	}
+
+	return m.UpdateAveragedWeights(w.config.Decay)
+}
+
+func (w *weightAveraging) afterEpoch(m *TfkgModel, epoch int) error {
+	if w.config.Mode != WeightAveragingSWA || epoch < w.config.StartEpoch {
+		return nil
+	}
+
+	e := m.UpdateAveragedWeights(float32(w.averaged) / float32(w.averaged+1))
+	if e != nil {
+		return e
+	}
+	w.averaged++
+
+	return nil
+}
+
+// swap swaps the weights with the averaged weights, saveTrainingState records which are in the model
+func (w *weightAveraging) swap(m *TfkgModel) error {
+	e := m.SwapAveragedWeights()
+	if e != nil {
+		return e
+	}
+	w.swapped = !w.swapped
+
+	return nil
+}
+
+// UpdateAveragedWeights sets the averaged weights to decay * averaged + (1 - decay) * weights. A decay of 0 copies the
+// weights into the average
+func (m *TfkgModel) UpdateAveragedWeights(decay float32) error {
+	decayTensor, e := tf.NewTensor(decay)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return m.runWeightAveragingSignature("update_averaged_weights", map[tf.Output]*tf.Tensor{
+		m.model.Graph.Operation("update_averaged_weights_decay").Output(0): decayTensor,
+	})
+}
+
+// SwapAveragedWeights swaps the weights of the model with the averaged weights. Swap again to restore them
+func (m *TfkgModel) SwapAveragedWeights() error {
+	return m.runWeightAveragingSignature("swap_averaged_weights", nil)
+}
+
+func (m *TfkgModel) runWeightAveragingSignature(signatureName string, feeds map[tf.Output]*tf.Tensor) error {
+	outputs, e := m.getSignatureOutputs(signatureName)
+	if e != nil {
+		return e
+	}
+
+	results, e := m.model.Session.Run(
+		feeds,
+		outputs,
+		nil,
+	)
+	if e != nil {
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	if !results[0].Value().(bool) {
+		e = fmt.Errorf("the model has no averaged weights, compile it with CompileConfig.WeightAveraging")
+		m.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
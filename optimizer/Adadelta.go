@@ -1,11 +1,15 @@
 package optimizer
 
 type OAdadelta struct {
-	decay        float64
-	epsilon      float64
-	learningRate float64
-	name         string
-	rho          float64
+	decay                float64
+	epsilon              float64
+	learningRate         float64
+	name                 string
+	rho                  float64
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func Adadelta() *OAdadelta {
@@ -43,6 +47,26 @@ func (o *OAdadelta) SetRho(rho float64) *OAdadelta {
 	return o
 }
 
+func (o *OAdadelta) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OAdadelta {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OAdadelta) SetClipnorm(clipnorm float64) *OAdadelta {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OAdadelta) SetClipvalue(clipvalue float64) *OAdadelta {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OAdadelta) SetGlobalClipnorm(globalClipnorm float64) *OAdadelta {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOAdadelta struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -50,17 +74,21 @@ type jsonConfigOAdadelta struct {
 }
 
 func (o *OAdadelta) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOAdadelta{
 		ClassName: "Adadelta",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"decay":         o.decay,
 			"epsilon":       o.epsilon,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"name":          o.name,
 			"rho":           o.rho,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
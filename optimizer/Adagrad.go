@@ -6,6 +6,10 @@ type OAdagrad struct {
 	initialAccumulatorValue float64
 	learningRate            float64
 	name                    string
+	learningRateSchedule    LearningRateSchedule
+	clipnorm                float64
+	clipvalue               float64
+	globalClipnorm          float64
 }
 
 func Adagrad() *OAdagrad {
@@ -43,6 +47,26 @@ func (o *OAdagrad) SetName(name string) *OAdagrad {
 	return o
 }
 
+func (o *OAdagrad) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OAdagrad {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OAdagrad) SetClipnorm(clipnorm float64) *OAdagrad {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OAdagrad) SetClipvalue(clipvalue float64) *OAdagrad {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OAdagrad) SetGlobalClipnorm(globalClipnorm float64) *OAdagrad {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOAdagrad struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -50,17 +74,21 @@ type jsonConfigOAdagrad struct {
 }
 
 func (o *OAdagrad) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOAdagrad{
 		ClassName: "Adagrad",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"decay":                     o.decay,
 			"epsilon":                   o.epsilon,
 			"initial_accumulator_value": o.initialAccumulatorValue,
-			"learning_rate":             o.learningRate,
+			"learning_rate":             learningRate,
 			"name":                      o.name,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
@@ -1,13 +1,17 @@
 package optimizer
 
 type OAdam struct {
-	amsgrad      bool
-	beta1        float64
-	beta2        float64
-	decay        float64
-	epsilon      float64
-	learningRate float64
-	name         string
+	amsgrad              bool
+	beta1                float64
+	beta2                float64
+	decay                float64
+	epsilon              float64
+	learningRate         float64
+	name                 string
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func Adam() *OAdam {
@@ -57,6 +61,26 @@ func (o *OAdam) SetName(name string) *OAdam {
 	return o
 }
 
+func (o *OAdam) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OAdam {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OAdam) SetClipnorm(clipnorm float64) *OAdam {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OAdam) SetClipvalue(clipvalue float64) *OAdam {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OAdam) SetGlobalClipnorm(globalClipnorm float64) *OAdam {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOAdam struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -64,19 +88,23 @@ type jsonConfigOAdam struct {
 }
 
 func (o *OAdam) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOAdam{
 		ClassName: "Adam",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"amsgrad":       o.amsgrad,
 			"beta_1":        o.beta1,
 			"beta_2":        o.beta2,
 			"decay":         o.decay,
 			"epsilon":       o.epsilon,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"name":          o.name,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
@@ -1,12 +1,16 @@
 package optimizer
 
 type OAdamax struct {
-	beta1        float64
-	beta2        float64
-	decay        float64
-	epsilon      float64
-	learningRate float64
-	name         string
+	beta1                float64
+	beta2                float64
+	decay                float64
+	epsilon              float64
+	learningRate         float64
+	name                 string
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func Adamax() *OAdamax {
@@ -50,6 +54,26 @@ func (o *OAdamax) SetName(name string) *OAdamax {
 	return o
 }
 
+func (o *OAdamax) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OAdamax {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OAdamax) SetClipnorm(clipnorm float64) *OAdamax {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OAdamax) SetClipvalue(clipvalue float64) *OAdamax {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OAdamax) SetGlobalClipnorm(globalClipnorm float64) *OAdamax {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOAdamax struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -57,18 +81,22 @@ type jsonConfigOAdamax struct {
 }
 
 func (o *OAdamax) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOAdamax{
 		ClassName: "Adamax",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"beta_1":        o.beta1,
 			"beta_2":        o.beta2,
 			"decay":         o.decay,
 			"epsilon":       o.epsilon,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"name":          o.name,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
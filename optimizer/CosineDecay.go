@@ -0,0 +1,46 @@
+package optimizer
+
+type SCosineDecay struct {
+	alpha               float64
+	decaySteps          float64
+	initialLearningRate float64
+	name                string
+}
+
+func CosineDecay(initialLearningRate float64, decaySteps float64) *SCosineDecay {
+	return &SCosineDecay{
+		alpha:               0,
+		decaySteps:          decaySteps,
+		initialLearningRate: initialLearningRate,
+		name:                UniqueName("CosineDecay"),
+	}
+}
+
+func (s *SCosineDecay) SetAlpha(alpha float64) *SCosineDecay {
+	s.alpha = alpha
+	return s
+}
+
+func (s *SCosineDecay) SetName(name string) *SCosineDecay {
+	s.name = name
+	return s
+}
+
+type jsonConfigSCosineDecay struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SCosineDecay) GetKerasLayerConfig() interface{} {
+	return jsonConfigSCosineDecay{
+		ClassName: "CosineDecay",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"alpha":                 s.alpha,
+			"decay_steps":           s.decaySteps,
+			"initial_learning_rate": s.initialLearningRate,
+			"name":                  s.name,
+		},
+	}
+}
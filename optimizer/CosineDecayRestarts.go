@@ -0,0 +1,62 @@
+package optimizer
+
+type SCosineDecayRestarts struct {
+	alpha               float64
+	firstDecaySteps     float64
+	initialLearningRate float64
+	mMul                float64
+	name                string
+	tMul                float64
+}
+
+func CosineDecayRestarts(initialLearningRate float64, firstDecaySteps float64) *SCosineDecayRestarts {
+	return &SCosineDecayRestarts{
+		alpha:               0,
+		firstDecaySteps:     firstDecaySteps,
+		initialLearningRate: initialLearningRate,
+		mMul:                1,
+		name:                UniqueName("CosineDecayRestarts"),
+		tMul:                2,
+	}
+}
+
+func (s *SCosineDecayRestarts) SetAlpha(alpha float64) *SCosineDecayRestarts {
+	s.alpha = alpha
+	return s
+}
+
+func (s *SCosineDecayRestarts) SetMMul(mMul float64) *SCosineDecayRestarts {
+	s.mMul = mMul
+	return s
+}
+
+func (s *SCosineDecayRestarts) SetName(name string) *SCosineDecayRestarts {
+	s.name = name
+	return s
+}
+
+func (s *SCosineDecayRestarts) SetTMul(tMul float64) *SCosineDecayRestarts {
+	s.tMul = tMul
+	return s
+}
+
+type jsonConfigSCosineDecayRestarts struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SCosineDecayRestarts) GetKerasLayerConfig() interface{} {
+	return jsonConfigSCosineDecayRestarts{
+		ClassName: "CosineDecayRestarts",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"alpha":                 s.alpha,
+			"first_decay_steps":     s.firstDecaySteps,
+			"initial_learning_rate": s.initialLearningRate,
+			"m_mul":                 s.mMul,
+			"name":                  s.name,
+			"t_mul":                 s.tMul,
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package optimizer
+
+type SExponentialDecay struct {
+	decayRate           float64
+	decaySteps          float64
+	initialLearningRate float64
+	name                string
+	staircase           bool
+}
+
+func ExponentialDecay(initialLearningRate float64, decaySteps float64, decayRate float64) *SExponentialDecay {
+	return &SExponentialDecay{
+		decayRate:           decayRate,
+		decaySteps:          decaySteps,
+		initialLearningRate: initialLearningRate,
+		name:                UniqueName("ExponentialDecay"),
+		staircase:           false,
+	}
+}
+
+func (s *SExponentialDecay) SetName(name string) *SExponentialDecay {
+	s.name = name
+	return s
+}
+
+func (s *SExponentialDecay) SetStaircase(staircase bool) *SExponentialDecay {
+	s.staircase = staircase
+	return s
+}
+
+type jsonConfigSExponentialDecay struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SExponentialDecay) GetKerasLayerConfig() interface{} {
+	return jsonConfigSExponentialDecay{
+		ClassName: "ExponentialDecay",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"decay_rate":            s.decayRate,
+			"decay_steps":           s.decaySteps,
+			"initial_learning_rate": s.initialLearningRate,
+			"name":                  s.name,
+			"staircase":             s.staircase,
+		},
+	}
+}
@@ -10,6 +10,10 @@ type OFtrl struct {
 	learningRate                      float64
 	learningRatePower                 float64
 	name                              string
+	learningRateSchedule              LearningRateSchedule
+	clipnorm                          float64
+	clipvalue                         float64
+	globalClipnorm                    float64
 }
 
 func Ftrl() *OFtrl {
@@ -71,6 +75,26 @@ func (o *OFtrl) SetName(name string) *OFtrl {
 	return o
 }
 
+func (o *OFtrl) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OFtrl {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OFtrl) SetClipnorm(clipnorm float64) *OFtrl {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OFtrl) SetClipvalue(clipvalue float64) *OFtrl {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OFtrl) SetGlobalClipnorm(globalClipnorm float64) *OFtrl {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOFtrl struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -78,21 +102,25 @@ type jsonConfigOFtrl struct {
 }
 
 func (o *OFtrl) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOFtrl{
 		ClassName: "Ftrl",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"beta":                                 o.beta,
 			"decay":                                o.decay,
 			"initial_accumulator_value":            o.initialAccumulatorValue,
 			"l1_regularization_strength":           o.l1RegularizationStrength,
 			"l2_regularization_strength":           o.l2RegularizationStrength,
 			"l2_shrinkage_regularization_strength": o.l2ShrinkageRegularizationStrength,
-			"learning_rate":                        o.learningRate,
+			"learning_rate":                        learningRate,
 			"learning_rate_power":                  o.learningRatePower,
 			"name":                                 o.name,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
@@ -0,0 +1,48 @@
+package optimizer
+
+type SLinearWarmup struct {
+	afterWarmup         LearningRateSchedule
+	initialLearningRate float64
+	name                string
+	warmupSteps         float64
+}
+
+// LinearWarmup linearly increases the learning rate from the initial learning rate (default 0) to the starting learning
+// rate of afterWarmup over warmupSteps, then follows afterWarmup
+func LinearWarmup(warmupSteps float64, afterWarmup LearningRateSchedule) *SLinearWarmup {
+	return &SLinearWarmup{
+		afterWarmup:         afterWarmup,
+		initialLearningRate: 0,
+		name:                UniqueName("LinearWarmup"),
+		warmupSteps:         warmupSteps,
+	}
+}
+
+func (s *SLinearWarmup) SetInitialLearningRate(initialLearningRate float64) *SLinearWarmup {
+	s.initialLearningRate = initialLearningRate
+	return s
+}
+
+func (s *SLinearWarmup) SetName(name string) *SLinearWarmup {
+	s.name = name
+	return s
+}
+
+type jsonConfigSLinearWarmup struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SLinearWarmup) GetKerasLayerConfig() interface{} {
+	return jsonConfigSLinearWarmup{
+		ClassName: "LinearWarmup",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"after_warmup":          s.afterWarmup.GetKerasLayerConfig(),
+			"initial_learning_rate": s.initialLearningRate,
+			"name":                  s.name,
+			"warmup_steps":          s.warmupSteps,
+		},
+	}
+}
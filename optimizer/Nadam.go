@@ -1,12 +1,16 @@
 package optimizer
 
 type ONadam struct {
-	beta1        float64
-	beta2        float64
-	decay        float64
-	epsilon      float64
-	learningRate float64
-	name         string
+	beta1                float64
+	beta2                float64
+	decay                float64
+	epsilon              float64
+	learningRate         float64
+	name                 string
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func Nadam() *ONadam {
@@ -50,6 +54,26 @@ func (o *ONadam) SetName(name string) *ONadam {
 	return o
 }
 
+func (o *ONadam) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *ONadam {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *ONadam) SetClipnorm(clipnorm float64) *ONadam {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *ONadam) SetClipvalue(clipvalue float64) *ONadam {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *ONadam) SetGlobalClipnorm(globalClipnorm float64) *ONadam {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigONadam struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -57,18 +81,22 @@ type jsonConfigONadam struct {
 }
 
 func (o *ONadam) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigONadam{
 		ClassName: "Nadam",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"beta_1":        o.beta1,
 			"beta_2":        o.beta2,
 			"decay":         o.decay,
 			"epsilon":       o.epsilon,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"name":          o.name,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
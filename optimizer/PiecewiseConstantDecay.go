@@ -0,0 +1,38 @@
+package optimizer
+
+type SPiecewiseConstantDecay struct {
+	boundaries []float64
+	name       string
+	values     []float64
+}
+
+func PiecewiseConstantDecay(boundaries []float64, values []float64) *SPiecewiseConstantDecay {
+	return &SPiecewiseConstantDecay{
+		boundaries: boundaries,
+		name:       UniqueName("PiecewiseConstantDecay"),
+		values:     values,
+	}
+}
+
+func (s *SPiecewiseConstantDecay) SetName(name string) *SPiecewiseConstantDecay {
+	s.name = name
+	return s
+}
+
+type jsonConfigSPiecewiseConstantDecay struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SPiecewiseConstantDecay) GetKerasLayerConfig() interface{} {
+	return jsonConfigSPiecewiseConstantDecay{
+		ClassName: "PiecewiseConstantDecay",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"boundaries": s.boundaries,
+			"name":       s.name,
+			"values":     s.values,
+		},
+	}
+}
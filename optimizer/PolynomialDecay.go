@@ -0,0 +1,62 @@
+package optimizer
+
+type SPolynomialDecay struct {
+	cycle               bool
+	decaySteps          float64
+	endLearningRate     float64
+	initialLearningRate float64
+	name                string
+	power               float64
+}
+
+func PolynomialDecay(initialLearningRate float64, decaySteps float64) *SPolynomialDecay {
+	return &SPolynomialDecay{
+		cycle:               false,
+		decaySteps:          decaySteps,
+		endLearningRate:     0.0001,
+		initialLearningRate: initialLearningRate,
+		name:                UniqueName("PolynomialDecay"),
+		power:               1,
+	}
+}
+
+func (s *SPolynomialDecay) SetCycle(cycle bool) *SPolynomialDecay {
+	s.cycle = cycle
+	return s
+}
+
+func (s *SPolynomialDecay) SetEndLearningRate(endLearningRate float64) *SPolynomialDecay {
+	s.endLearningRate = endLearningRate
+	return s
+}
+
+func (s *SPolynomialDecay) SetName(name string) *SPolynomialDecay {
+	s.name = name
+	return s
+}
+
+func (s *SPolynomialDecay) SetPower(power float64) *SPolynomialDecay {
+	s.power = power
+	return s
+}
+
+type jsonConfigSPolynomialDecay struct {
+	ClassName string                 `json:"class_name"`
+	Name      string                 `json:"name"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+func (s *SPolynomialDecay) GetKerasLayerConfig() interface{} {
+	return jsonConfigSPolynomialDecay{
+		ClassName: "PolynomialDecay",
+		Name:      s.name,
+		Config: map[string]interface{}{
+			"cycle":                 s.cycle,
+			"decay_steps":           s.decaySteps,
+			"end_learning_rate":     s.endLearningRate,
+			"initial_learning_rate": s.initialLearningRate,
+			"name":                  s.name,
+			"power":                 s.power,
+		},
+	}
+}
@@ -1,13 +1,17 @@
 package optimizer
 
 type ORMSprop struct {
-	centered     bool
-	decay        float64
-	epsilon      float64
-	learningRate float64
-	momentum     float64
-	name         string
-	rho          float64
+	centered             bool
+	decay                float64
+	epsilon              float64
+	learningRate         float64
+	momentum             float64
+	name                 string
+	rho                  float64
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func RMSprop() *ORMSprop {
@@ -57,6 +61,26 @@ func (o *ORMSprop) SetRho(rho float64) *ORMSprop {
 	return o
 }
 
+func (o *ORMSprop) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *ORMSprop {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *ORMSprop) SetClipnorm(clipnorm float64) *ORMSprop {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *ORMSprop) SetClipvalue(clipvalue float64) *ORMSprop {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *ORMSprop) SetGlobalClipnorm(globalClipnorm float64) *ORMSprop {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigORMSprop struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -64,19 +88,23 @@ type jsonConfigORMSprop struct {
 }
 
 func (o *ORMSprop) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigORMSprop{
 		ClassName: "RMSprop",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"centered":      o.centered,
 			"decay":         o.decay,
 			"epsilon":       o.epsilon,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"momentum":      o.momentum,
 			"name":          o.name,
 			"rho":           o.rho,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
@@ -1,11 +1,15 @@
 package optimizer
 
 type OSGD struct {
-	decay        float64
-	learningRate float64
-	momentum     float64
-	name         string
-	nesterov     bool
+	decay                float64
+	learningRate         float64
+	momentum             float64
+	name                 string
+	nesterov             bool
+	learningRateSchedule LearningRateSchedule
+	clipnorm             float64
+	clipvalue            float64
+	globalClipnorm       float64
 }
 
 func SGD() *OSGD {
@@ -43,6 +47,26 @@ func (o *OSGD) SetNesterov(nesterov bool) *OSGD {
 	return o
 }
 
+func (o *OSGD) SetLearningRateSchedule(learningRateSchedule LearningRateSchedule) *OSGD {
+	o.learningRateSchedule = learningRateSchedule
+	return o
+}
+
+func (o *OSGD) SetClipnorm(clipnorm float64) *OSGD {
+	o.clipnorm = clipnorm
+	return o
+}
+
+func (o *OSGD) SetClipvalue(clipvalue float64) *OSGD {
+	o.clipvalue = clipvalue
+	return o
+}
+
+func (o *OSGD) SetGlobalClipnorm(globalClipnorm float64) *OSGD {
+	o.globalClipnorm = globalClipnorm
+	return o
+}
+
 type jsonConfigOSGD struct {
 	ClassName string                 `json:"class_name"`
 	Name      string                 `json:"name"`
@@ -50,17 +74,21 @@ type jsonConfigOSGD struct {
 }
 
 func (o *OSGD) GetKerasLayerConfig() interface{} {
+	var learningRate interface{} = o.learningRate
+	if o.learningRateSchedule != nil {
+		learningRate = o.learningRateSchedule.GetKerasLayerConfig()
+	}
 
 	return jsonConfigOSGD{
 		ClassName: "SGD",
 		Name:      o.name,
-		Config: map[string]interface{}{
+		Config: addClippingConfig(map[string]interface{}{
 			"decay":         o.decay,
-			"learning_rate": o.learningRate,
+			"learning_rate": learningRate,
 			"momentum":      o.momentum,
 			"name":          o.name,
 			"nesterov":      o.nesterov,
-		},
+		}, o.clipnorm, o.clipvalue, o.globalClipnorm),
 	}
 }
 
@@ -6,6 +6,62 @@ type Optimizer interface {
 	GetKerasLayerConfig() interface{}
 }
 
+// LearningRateSchedule is serialised in place of the learning rate of an optimizer set with SetLearningRateSchedule
+type LearningRateSchedule interface {
+	GetKerasLayerConfig() interface{}
+}
+
+// addClippingConfig only adds the gradient clipping options which have been set, as keras does not allow clipnorm
+// and global_clipnorm together
+func addClippingConfig(config map[string]interface{}, clipnorm float64, clipvalue float64, globalClipnorm float64) map[string]interface{} {
+	if clipnorm > 0 {
+		config["clipnorm"] = clipnorm
+	}
+	if clipvalue > 0 {
+		config["clipvalue"] = clipvalue
+	}
+	if globalClipnorm > 0 {
+		config["global_clipnorm"] = globalClipnorm
+	}
+
+	return config
+}
+
+// Constructor builds an optimizer from its keras class name and config in the same way as layer.Constructor
+type Constructor struct {
+	Function interface{}
+	Required []string
+}
+
+// Schedules are the learning rate schedules keyed by class name, they are set with the SetLearningRateSchedule method
+// of an optimizer
+var Schedules = map[string]Constructor{
+	"CosineDecay": {
+		Function: CosineDecay,
+		Required: []string{"initial_learning_rate", "decay_steps"},
+	},
+	"CosineDecayRestarts": {
+		Function: CosineDecayRestarts,
+		Required: []string{"initial_learning_rate", "first_decay_steps"},
+	},
+	"ExponentialDecay": {
+		Function: ExponentialDecay,
+		Required: []string{"initial_learning_rate", "decay_steps", "decay_rate"},
+	},
+	"LinearWarmup": {
+		Function: LinearWarmup,
+		Required: []string{"warmup_steps", "after_warmup"},
+	},
+	"PiecewiseConstantDecay": {
+		Function: PiecewiseConstantDecay,
+		Required: []string{"boundaries", "values"},
+	},
+	"PolynomialDecay": {
+		Function: PolynomialDecay,
+		Required: []string{"initial_learning_rate", "decay_steps"},
+	},
+}
+
 var uniqueNameCounts = make(map[string]int)
 
 func UniqueName(name string) string {
@@ -0,0 +1,29 @@
+package optimizer
+
+// Constructors are the generated objects keyed by keras class name
+var Constructors = map[string]Constructor{
+	"Adadelta": {
+		Function: Adadelta,
+	},
+	"Adagrad": {
+		Function: Adagrad,
+	},
+	"Adam": {
+		Function: Adam,
+	},
+	"Adamax": {
+		Function: Adamax,
+	},
+	"Ftrl": {
+		Function: Ftrl,
+	},
+	"Nadam": {
+		Function: Nadam,
+	},
+	"RMSprop": {
+		Function: RMSprop,
+	},
+	"SGD": {
+		Function: SGD,
+	},
+}
@@ -11,6 +11,28 @@ import (
 	"strings"
 )
 
+// Readers are the readers of this file by function name, for processors described in config files. Use
+// ReadMultiLabelStrings directly as it takes a delimiter
+var Readers = map[string]func(columns []string) interface{}{
+	"ReadCsvFloat32s": ReadCsvFloat32s,
+	"ReadCsvInt32s":   ReadCsvInt32s,
+	"ReadJpg":         ReadJpg,
+	"ReadStringNop":   ReadStringNop,
+}
+
+// Converters are the converters of this file by function name, for processors described in config files
+var Converters = map[string]func(columns interface{}) (*tf.Tensor, error){
+	"ConvertImageToFloat32SliceTensor":     ConvertImageToFloat32SliceTensor,
+	"ConvertDivisorToFloat32SliceTensor":   ConvertDivisorToFloat32SliceTensor,
+	"ConvertInt32SliceToTensor":            ConvertInt32SliceToTensor,
+	"ConvertInterfaceToInt32SliceTensor":   ConvertInterfaceToInt32SliceTensor,
+	"ConvertInterfaceToFloat32SliceTensor": ConvertInterfaceToFloat32SliceTensor,
+	"ConvertInterfaceFloat32SliceToTensor": ConvertInterfaceFloat32SliceToTensor,
+	"ConvertInterfaceInt32SliceToTensor":   ConvertInterfaceInt32SliceToTensor,
+	"ConvertTokenizerToInt32SliceTensor":   ConvertTokenizerToInt32SliceTensor,
+	"ConvertTokenizerToFloat32SliceTensor": ConvertTokenizerToFloat32SliceTensor,
+}
+
 func ReadCsvFloat32s(columns []string) interface{} {
 	var floats2d [][]float32
 	for _, column := range columns {
@@ -213,6 +235,26 @@ func ReadStringNop(columns []string) interface{} {
 	return columns
 }
 
+// ReadMultiLabelStrings splits each column into tags on the delimiter and joins them with spaces for a multi label
+// tokenizer. Spaces within a tag are replaced with underscores
+func ReadMultiLabelStrings(delimiter string) func(columns []string) interface{} {
+	return func(columns []string) interface{} {
+		var joined []string
+		for _, column := range columns {
+			var tags []string
+			for _, tag := range strings.Split(column, delimiter) {
+				tag = strings.TrimSpace(tag)
+				if tag == "" {
+					continue
+				}
+				tags = append(tags, strings.ReplaceAll(tag, " ", "_"))
+			}
+			joined = append(joined, strings.Join(tags, " "))
+		}
+		return joined
+	}
+}
+
 func ConvertTokenizerToInt32SliceTensor(columns interface{}) (*tf.Tensor, error) {
 	columnsInts, ok := columns.([][]int32)
 	if !ok {
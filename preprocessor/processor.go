@@ -18,6 +18,7 @@ type Processor struct {
 
 	cacheDir  string
 	divisor   *RegressionDivisor
+	scaler    *Scaler
 	tokenizer *Tokenizer
 	image     *Image
 	reader    func(column []string) interface{}
@@ -32,6 +33,7 @@ type ProcessorConfig struct {
 	DataLength  int
 	RequiresFit bool
 	Divisor     *RegressionDivisor
+	Scaler      *Scaler
 	Tokenizer   *Tokenizer
 	Image       *Image
 	Reader      func(column []string) interface{}
@@ -51,6 +53,7 @@ func NewProcessor(
 		DataLength:   config.DataLength,
 		RequiresFit:  config.RequiresFit,
 		divisor:      config.Divisor,
+		scaler:       config.Scaler,
 		tokenizer:    config.Tokenizer,
 		image:        config.Image,
 		reader:       config.Reader,
@@ -130,10 +133,82 @@ func NewBinaryYProcessor(
 	}
 }
 
+// NewMultiLabelTokenizingYProcessor reads a column of tags separated by delimiter E.G. "news|sport" into a k-hot
+// float32 vector with one element per tag seen while fitting. Use it with loss.MultiLabelCrossentropy
+func NewMultiLabelTokenizingYProcessor(
+	errorHandler *cberrors.ErrorsContainer,
+	cacheDir string,
+	lineOffset int,
+	delimiter string,
+) *Processor {
+	return &Processor{
+		errorHandler: errorHandler,
+		Name:         "y",
+		cacheDir:     cacheDir,
+		LineOffset:   lineOffset,
+		RequiresFit:  true,
+		tokenizer: NewTokenizer(errorHandler, 1, -1, TokenizerConfig{
+			IsMultiLabelTokenizer: true,
+			DisableFiltering:      true,
+		}),
+		reader:    ReadMultiLabelStrings(delimiter),
+		converter: ConvertTokenizerToFloat32SliceTensor,
+	}
+}
+
+// NewSequenceLabelTokenizingYProcessor reads a column of space separated labels, one per word of an input column, into
+// a maxLen int32 vector padded with 0 like the Tokenizer of the input. The input Tokenizer should use KeepUnknownWords
+// and DisableFiltering so each token stays aligned with its label. Label 0 is padding, so the output needs
+// Tokenizer().NumWords()+1 classes. Use it with loss.SequenceSparseCategoricalCrossentropy to mask the padding
+func NewSequenceLabelTokenizingYProcessor(
+	errorHandler *cberrors.ErrorsContainer,
+	cacheDir string,
+	lineOffset int,
+	maxLen int,
+) *Processor {
+	return &Processor{
+		errorHandler: errorHandler,
+		Name:         "y",
+		cacheDir:     cacheDir,
+		LineOffset:   lineOffset,
+		RequiresFit:  true,
+		tokenizer: NewTokenizer(errorHandler, maxLen, -1, TokenizerConfig{
+			DisableFiltering: true,
+		}),
+		reader:    ReadStringNop,
+		converter: ConvertTokenizerToInt32SliceTensor,
+	}
+}
+
+// NewScalingYProcessor reads a column of comma separated float32 regression targets and scales them with standard or
+// min-max scaling fit on the dataset. The scaler is saved with the other processors so data.Inference can map the
+// outputs of TfkgModel.Predict back to the original units
+func NewScalingYProcessor(
+	errorHandler *cberrors.ErrorsContainer,
+	cacheDir string,
+	lineOffset int,
+	mode ScalerMode,
+) *Processor {
+	return &Processor{
+		errorHandler: errorHandler,
+		Name:         "y",
+		cacheDir:     cacheDir,
+		LineOffset:   lineOffset,
+		RequiresFit:  true,
+		scaler:       NewScaler(errorHandler, mode),
+		reader:       ReadCsvFloat32s,
+		converter:    ConvertDivisorToFloat32SliceTensor,
+	}
+}
+
 func (p *Processor) Tokenizer() *Tokenizer {
 	return p.tokenizer
 }
 
+func (p *Processor) Scaler() *Scaler {
+	return p.scaler
+}
+
 func (p *Processor) FitString(column []string) error {
 	value := p.reader(column)
 	if p.divisor != nil {
@@ -146,6 +221,16 @@ func (p *Processor) FitString(column []string) error {
 		for _, floatValue := range floatValues {
 			p.divisor.Fit(floatValue)
 		}
+	} else if p.scaler != nil {
+		floatValues, ok := value.([][]float32)
+		if !ok {
+			e := fmt.Errorf("error casting read value to []float32 for preprocessor %s, value was: %#v", p.Name, value)
+			p.errorHandler.Error(e)
+			return e
+		}
+		for _, floatValue := range floatValues {
+			p.scaler.Fit(floatValue)
+		}
 	} else if p.tokenizer != nil {
 		stringValues, ok := value.([]string)
 		if !ok {
@@ -171,7 +256,26 @@ func (p *Processor) FitInterface(column interface{}) error {
 		for _, floatValue := range floatValues {
 			p.divisor.Fit(floatValue)
 		}
+	} else if p.scaler != nil {
+		// A single row of a ValuesDataset is a []float32
+		if floatValue, ok := column.([]float32); ok {
+			p.scaler.Fit(floatValue)
+			return nil
+		}
+		floatValues, ok := column.([][]float32)
+		if !ok {
+			e := fmt.Errorf("error casting read value to []float32 for preprocessor %s, value was: %#v", p.Name, column)
+			p.errorHandler.Error(e)
+			return e
+		}
+		for _, floatValue := range floatValues {
+			p.scaler.Fit(floatValue)
+		}
 	} else if p.tokenizer != nil {
+		if stringValue, ok := column.(string); ok {
+			p.tokenizer.Fit(stringValue)
+			return nil
+		}
 		stringValues, ok := column.([]string)
 		if !ok {
 			e := fmt.Errorf("error casting read value to string for preprocessor %s, value was: %#v", p.Name, column)
@@ -191,10 +295,13 @@ func (p *Processor) SetLoadDir(dir string) {
 
 func (p *Processor) Load() error {
 	divisorConfigPath := filepath.Join(p.cacheDir, fmt.Sprintf("%s-divisor.json", p.Name))
+	scalerConfigPath := filepath.Join(p.cacheDir, fmt.Sprintf("%s-scaler.json", p.Name))
 	tokenizerConfigPath := filepath.Join(p.cacheDir, fmt.Sprintf("%s-tokenizer.json", p.Name))
 	_, e := os.Stat(divisorConfigPath)
 	if e == nil {
 		p.divisor = NewDivisor(p.errorHandler)
+	} else if _, e := os.Stat(scalerConfigPath); e == nil {
+		p.scaler = NewScaler(p.errorHandler, ScalerModeStandard)
 	} else {
 		_, e := os.Stat(tokenizerConfigPath)
 		if e == nil {
@@ -206,6 +313,11 @@ func (p *Processor) Load() error {
 		if e != nil {
 			return e
 		}
+	} else if p.scaler != nil {
+		e := p.scaler.Load(scalerConfigPath)
+		if e != nil {
+			return e
+		}
 	} else if p.tokenizer != nil {
 		e := p.tokenizer.Load(tokenizerConfigPath)
 		if e != nil {
@@ -229,6 +341,12 @@ func (p *Processor) Save(saveDir string) error {
 			p.errorHandler.Error(e)
 			return e
 		}
+	} else if p.scaler != nil {
+		e := p.scaler.Save(filepath.Join(saveDir, fmt.Sprintf("%s-scaler.json", p.Name)))
+		if e != nil {
+			p.errorHandler.Error(e)
+			return e
+		}
 	} else if p.tokenizer != nil {
 		e := p.tokenizer.Save(filepath.Join(saveDir, fmt.Sprintf("%s-tokenizer.json", p.Name)))
 		if e != nil {
@@ -253,6 +371,17 @@ func (p *Processor) ProcessString(columnRows []string) (*tf.Tensor, error) {
 			dividedRows = append(dividedRows, divided)
 		}
 		return p.converter(dividedRows)
+	} else if p.scaler != nil {
+		var scaledRows [][]float32
+		for _, columnRow := range read.([][]float32) {
+			scaled, e := p.scaler.Transform(columnRow)
+			if e != nil {
+				p.errorHandler.Error(e)
+				return nil, e
+			}
+			scaledRows = append(scaledRows, scaled)
+		}
+		return p.converter(scaledRows)
 	} else if p.tokenizer != nil {
 		var tokenizedStrings [][]int32
 		for _, columnRow := range read.([]string) {
@@ -303,6 +432,29 @@ func (p *Processor) ProcessInterface(columnRows interface{}) (*tf.Tensor, error)
 			}
 		}
 		return p.converter(dividedRows)
+	} else if p.scaler != nil {
+		var scaledRows [][]float32
+		literalType, ok := columnRows.([][]float32)
+		if ok {
+			for _, columnRow := range literalType {
+				scaled, e := p.scaler.Transform(columnRow)
+				if e != nil {
+					p.errorHandler.Error(e)
+					return nil, e
+				}
+				scaledRows = append(scaledRows, scaled)
+			}
+		} else {
+			for _, columnRow := range columnRows.([]interface{}) {
+				scaled, e := p.scaler.Transform(columnRow.([]float32))
+				if e != nil {
+					p.errorHandler.Error(e)
+					return nil, e
+				}
+				scaledRows = append(scaledRows, scaled)
+			}
+		}
+		return p.converter(scaledRows)
 	} else if p.tokenizer != nil {
 		var tokenizedStrings [][]int32
 		literalType, ok := columnRows.([]string)
@@ -345,3 +497,23 @@ func (p *Processor) ProcessInterface(columnRows interface{}) (*tf.Tensor, error)
 
 	return p.converter(columnRows)
 }
+
+// InverseTransform maps scaled rows, E.G. the outputs of TfkgModel.Predict, back to the original units. Rows are
+// returned unchanged when the processor has no Scaler
+func (p *Processor) InverseTransform(rows [][]float32) ([][]float32, error) {
+	if p.scaler == nil {
+		return rows, nil
+	}
+
+	var unscaledRows [][]float32
+	for _, row := range rows {
+		unscaled, e := p.scaler.InverseTransform(row)
+		if e != nil {
+			p.errorHandler.Error(e)
+			return nil, e
+		}
+		unscaledRows = append(unscaledRows, unscaled)
+	}
+
+	return unscaledRows, nil
+}
@@ -0,0 +1,205 @@
+package preprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/codingbeard/cberrors"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+)
+
+type ScalerMode string
+
+var (
+	// ScalerModeStandard scales each value to (value - mean) / standard deviation
+	ScalerModeStandard ScalerMode = "standard"
+	// ScalerModeMinMax scales each value to between 0 and 1 using the min and max seen while fitting
+	ScalerModeMinMax ScalerMode = "min_max"
+)
+
+// Scaler fits standard or min-max scaling on each element of a float32 column so regression targets can be scaled
+// for training and predictions mapped back to the original units with InverseTransform
+type Scaler struct {
+	mode  ScalerMode
+	count float64
+	means []float64
+	m2s   []float64
+	mins  []float32
+	maxs  []float32
+	lock  *sync.Mutex
+
+	errorHandler *cberrors.ErrorsContainer
+}
+
+type scalerConfig struct {
+	Mode               ScalerMode `json:"mode"`
+	Count              float64    `json:"count"`
+	Means              []float64  `json:"means"`
+	StandardDeviations []float64  `json:"standard_deviations"`
+	Mins               []float32  `json:"mins"`
+	Maxs               []float32  `json:"maxs"`
+}
+
+func NewScaler(
+	errorHandler *cberrors.ErrorsContainer,
+	mode ScalerMode,
+) *Scaler {
+	return &Scaler{
+		mode:         mode,
+		lock:         &sync.Mutex{},
+		errorHandler: errorHandler,
+	}
+}
+
+func (s *Scaler) Load(configFile string) error {
+	contents, e := ioutil.ReadFile(configFile)
+	if e != nil {
+		return e
+	}
+
+	var config scalerConfig
+
+	e = json.Unmarshal(contents, &config)
+	if e != nil {
+		return e
+	}
+
+	if len(config.Means) != len(config.StandardDeviations) ||
+		len(config.Means) != len(config.Mins) ||
+		len(config.Means) != len(config.Maxs) {
+		return fmt.Errorf("mismatched number of scales in configFile: %s", configFile)
+	}
+
+	s.mode = config.Mode
+	s.count = config.Count
+	s.means = config.Means
+	s.m2s = nil
+	for _, standardDeviation := range config.StandardDeviations {
+		s.m2s = append(s.m2s, standardDeviation*standardDeviation*config.Count)
+	}
+	s.mins = config.Mins
+	s.maxs = config.Maxs
+
+	return nil
+}
+
+func (s *Scaler) Save(configFile string) error {
+	s.lock.Lock()
+	config := scalerConfig{
+		Mode:  s.mode,
+		Count: s.count,
+		Means: s.means,
+		Mins:  s.mins,
+		Maxs:  s.maxs,
+	}
+	for offset := range s.means {
+		config.StandardDeviations = append(config.StandardDeviations, s.standardDeviation(offset))
+	}
+	s.lock.Unlock()
+
+	jsonBytes, e := json.Marshal(config)
+	if e != nil {
+		s.errorHandler.Error(e)
+		return e
+	}
+
+	e = ioutil.WriteFile(configFile, jsonBytes, os.ModePerm)
+	if e != nil {
+		s.errorHandler.Error(e)
+		return e
+	}
+
+	return nil
+}
+
+func (s *Scaler) Mode() ScalerMode {
+	return s.mode
+}
+
+func (s *Scaler) Fit(input []float32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.count++
+	for i := 0; i < len(input); i++ {
+		value := float64(input[i])
+		if len(s.means) <= i {
+			s.means = append(s.means, 0)
+			s.m2s = append(s.m2s, 0)
+			s.mins = append(s.mins, input[i])
+			s.maxs = append(s.maxs, input[i])
+		}
+
+		// Welford's online algorithm so the variance can be fit in a single pass
+		delta := value - s.means[i]
+		s.means[i] += delta / s.count
+		s.m2s[i] += delta * (value - s.means[i])
+
+		if input[i] < s.mins[i] {
+			s.mins[i] = input[i]
+		}
+		if input[i] > s.maxs[i] {
+			s.maxs[i] = input[i]
+		}
+	}
+}
+
+func (s *Scaler) Transform(input []float32) ([]float32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var scaled []float32
+	for offset, value := range input {
+		if len(s.means) <= offset {
+			return nil, fmt.Errorf("missing scale %d, scales len: %d", offset, len(s.means))
+		}
+		if s.mode == ScalerModeMinMax {
+			scaled = append(scaled, (value-s.mins[offset])/s.valueRange(offset))
+		} else {
+			scaled = append(scaled, float32((float64(value)-s.means[offset])/s.standardDeviation(offset)))
+		}
+	}
+
+	return scaled, nil
+}
+
+// InverseTransform maps scaled values, E.G. the output of TfkgModel.Predict, back to the original units
+func (s *Scaler) InverseTransform(input []float32) ([]float32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var unscaled []float32
+	for offset, value := range input {
+		if len(s.means) <= offset {
+			return nil, fmt.Errorf("missing scale %d, scales len: %d", offset, len(s.means))
+		}
+		if s.mode == ScalerModeMinMax {
+			unscaled = append(unscaled, value*s.valueRange(offset)+s.mins[offset])
+		} else {
+			unscaled = append(unscaled, float32(float64(value)*s.standardDeviation(offset)+s.means[offset]))
+		}
+	}
+
+	return unscaled, nil
+}
+
+func (s *Scaler) standardDeviation(offset int) float64 {
+	if s.count == 0 {
+		return 1
+	}
+	standardDeviation := math.Sqrt(s.m2s[offset] / s.count)
+	if standardDeviation == 0 {
+		return 1
+	}
+	return standardDeviation
+}
+
+func (s *Scaler) valueRange(offset int) float32 {
+	valueRange := s.maxs[offset] - s.mins[offset]
+	if valueRange == 0 {
+		return 1
+	}
+	return valueRange
+}
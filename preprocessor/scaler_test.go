@@ -0,0 +1,113 @@
+package preprocessor
+
+import (
+	"github.com/codingbeard/cberrors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func assertFloat32s(t *testing.T, name string, expected []float32, actual []float32) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("%s: expected %v, got %v", name, expected, actual)
+	}
+	for offset := range expected {
+		if math.Abs(float64(expected[offset]-actual[offset])) > 1e-4 {
+			t.Errorf("%s: expected %v, got %v", name, expected, actual)
+			return
+		}
+	}
+}
+
+func TestScalerRoundTrip(t *testing.T) {
+	rows := [][]float32{
+		{1, 10, 5},
+		{2, 20, 5},
+		{3, 30, 5},
+		{4, 40, 5},
+	}
+
+	tests := []struct {
+		name string
+		mode ScalerMode
+		// scaled is the expected transform of the first row
+		scaled []float32
+	}{
+		{
+			name: "standard",
+			mode: ScalerModeStandard,
+			// The mean of the first column is 2.5 and the standard deviation is sqrt(1.25), a constant column is
+			// only shifted by its mean
+			scaled: []float32{-1.5 / float32(math.Sqrt(1.25)), -15 / float32(math.Sqrt(125)), 0},
+		},
+		{
+			name: "min max",
+			mode: ScalerModeMinMax,
+			// A constant column is only shifted by its min
+			scaled: []float32{0, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errorHandler := cberrors.NewErrorContainer()
+			scaler := NewScaler(errorHandler, test.mode)
+			for _, row := range rows {
+				scaler.Fit(row)
+			}
+
+			scaled, e := scaler.Transform(rows[0])
+			if e != nil {
+				t.Fatal(e)
+			}
+			assertFloat32s(t, "transform", test.scaled, scaled)
+
+			configFile := filepath.Join(t.TempDir(), "scaler.json")
+			e = scaler.Save(configFile)
+			if e != nil {
+				t.Fatal(e)
+			}
+			loaded := NewScaler(errorHandler, "")
+			e = loaded.Load(configFile)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if loaded.Mode() != test.mode {
+				t.Errorf("expected the loaded mode %s, got %s", test.mode, loaded.Mode())
+			}
+
+			for _, row := range rows {
+				scaled, e := scaler.Transform(row)
+				if e != nil {
+					t.Fatal(e)
+				}
+				loadedScaled, e := loaded.Transform(row)
+				if e != nil {
+					t.Fatal(e)
+				}
+				assertFloat32s(t, "loaded transform", scaled, loadedScaled)
+
+				unscaled, e := loaded.InverseTransform(scaled)
+				if e != nil {
+					t.Fatal(e)
+				}
+				assertFloat32s(t, "inverse transform", row, unscaled)
+			}
+		})
+	}
+}
+
+func TestScalerMissingScale(t *testing.T) {
+	scaler := NewScaler(cberrors.NewErrorContainer(), ScalerModeStandard)
+	scaler.Fit([]float32{1, 2})
+
+	_, e := scaler.Transform([]float32{1, 2, 3})
+	if e == nil {
+		t.Error("expected an error transforming more values than were fit")
+	}
+	_, e = scaler.InverseTransform([]float32{1, 2, 3})
+	if e == nil {
+		t.Error("expected an error inverse transforming more values than were fit")
+	}
+}
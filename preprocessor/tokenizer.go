@@ -11,15 +11,18 @@ import (
 )
 
 type Tokenizer struct {
-	isCategoryTokenizer bool
-	dictionary          map[string]int
-	wordCounts          map[string]*wordCount
-	uniqueWordCount     int
-	maxLen              int
-	numWords            int
-	filter              string
-	disableFiltering    bool
-	lock                *sync.Mutex
+	isCategoryTokenizer   bool
+	isMultiLabelTokenizer bool
+	dictionary            map[string]int
+	words                 map[int32]string
+	wordCounts            map[string]*wordCount
+	uniqueWordCount       int
+	maxLen                int
+	numWords              int
+	filter                string
+	disableFiltering      bool
+	keepUnknownWords      bool
+	lock                  *sync.Mutex
 
 	errorHandler *cberrors.ErrorsContainer
 }
@@ -34,12 +37,20 @@ type tokenizerConfig struct {
 	WordIndex        map[string]int `json:"word_index"`
 	Filter           string         `json:"filter"`
 	DisableFiltering bool           `json:"disable_filtering"`
+	MultiLabel       bool           `json:"multi_label,omitempty"`
+	KeepUnknownWords bool           `json:"keep_unknown_words,omitempty"`
 }
 
 type TokenizerConfig struct {
 	IsCategoryTokenizer bool
-	Filters             string
-	DisableFiltering    bool
+	// IsMultiLabelTokenizer is a category tokenizer which tokenizes each word of a sentence into a k-hot vector with
+	// one element per category
+	IsMultiLabelTokenizer bool
+	Filters               string
+	DisableFiltering      bool
+	// KeepUnknownWords tokenizes words which are not in the dictionary as NumWords()+1 instead of dropping them, so
+	// each token stays aligned with its word for sequence labelling. Embeddings need NumWords()+2 inputs
+	KeepUnknownWords bool
 }
 
 func NewTokenizer(
@@ -52,6 +63,9 @@ func NewTokenizer(
 	if len(configs) > 0 {
 		config = configs[0]
 	}
+	if config.IsMultiLabelTokenizer {
+		config.IsCategoryTokenizer = true
+	}
 	if config.IsCategoryTokenizer {
 		numWords = -1
 	}
@@ -59,15 +73,17 @@ func NewTokenizer(
 		config.Filters = "!\"#$%&()*+,-./:;<=>?@[\\]^_`{|}~\t\n"
 	}
 	return &Tokenizer{
-		isCategoryTokenizer: config.IsCategoryTokenizer,
-		dictionary:          make(map[string]int),
-		wordCounts:          make(map[string]*wordCount),
-		maxLen:              maxLen,
-		numWords:            numWords,
-		filter:              config.Filters,
-		disableFiltering:    config.DisableFiltering,
-		lock:                &sync.Mutex{},
-		errorHandler:        errorHandler,
+		isCategoryTokenizer:   config.IsCategoryTokenizer,
+		isMultiLabelTokenizer: config.IsMultiLabelTokenizer,
+		dictionary:            make(map[string]int),
+		wordCounts:            make(map[string]*wordCount),
+		maxLen:                maxLen,
+		numWords:              numWords,
+		filter:                config.Filters,
+		disableFiltering:      config.DisableFiltering,
+		keepUnknownWords:      config.KeepUnknownWords,
+		lock:                  &sync.Mutex{},
+		errorHandler:          errorHandler,
 	}
 }
 
@@ -85,10 +101,16 @@ func (t *Tokenizer) Load(configFile string) error {
 	}
 
 	t.dictionary = config.WordIndex
+	t.words = nil
 	t.maxLen = config.MaxLen
 	t.numWords = len(config.WordIndex)
 	t.filter = config.Filter
 	t.disableFiltering = config.DisableFiltering
+	t.keepUnknownWords = config.KeepUnknownWords
+	if config.MultiLabel {
+		t.isCategoryTokenizer = true
+		t.isMultiLabelTokenizer = true
+	}
 
 	return nil
 }
@@ -99,6 +121,8 @@ func (t *Tokenizer) Save(configFile string) error {
 		WordIndex:        t.dictionary,
 		Filter:           t.filter,
 		DisableFiltering: t.disableFiltering,
+		MultiLabel:       t.isMultiLabelTokenizer,
+		KeepUnknownWords: t.keepUnknownWords,
 	})
 	if e != nil {
 		t.errorHandler.Error(e)
@@ -197,6 +221,7 @@ func (t *Tokenizer) Fit(sentence string) {
 func (t *Tokenizer) FinishFit() {
 	t.lock.Lock()
 	defer t.lock.Unlock()
+	t.words = nil
 	type kv struct {
 		k string
 		v int
@@ -244,10 +269,23 @@ func (t *Tokenizer) FinishFit() {
 }
 
 func (t *Tokenizer) Tokenize(sentence string) []int32 {
-	tokenized := make([]int32, t.maxLen)
-
 	words := t.split(t.clean(sentence))
 
+	if t.isMultiLabelTokenizer {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		kHot := make([]int32, len(t.dictionary))
+		for _, word := range words {
+			dictionaryIndex, ok := t.dictionary[strings.TrimSpace(word)]
+			if ok {
+				kHot[dictionaryIndex] = 1
+			}
+		}
+		return kHot
+	}
+
+	tokenized := make([]int32, t.maxLen)
+
 	position := 0
 	for _, word := range words {
 		if position >= t.maxLen {
@@ -262,8 +300,60 @@ func (t *Tokenizer) Tokenize(sentence string) []int32 {
 		if ok {
 			tokenized[position] = int32(dictionaryIndex)
 			position++
+		} else if t.keepUnknownWords && word != "" {
+			tokenized[position] = int32(len(t.dictionary) + 1)
+			position++
 		}
 	}
 
 	return tokenized
 }
+
+// Words maps tokens produced by Tokenize back to the words of the dictionary. Padding is returned as "" and unknown
+// words kept by KeepUnknownWords as "<unknown>"
+func (t *Tokenizer) Words(tokens []int32) []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.words == nil {
+		t.words = make(map[int32]string)
+		for word, index := range t.dictionary {
+			t.words[int32(index)] = word
+		}
+	}
+
+	words := make([]string, len(tokens))
+	for position, token := range tokens {
+		word, ok := t.words[token]
+		if ok {
+			words[position] = word
+		} else if token != 0 {
+			words[position] = "<unknown>"
+		}
+	}
+
+	return words
+}
+
+type WordAttribution struct {
+	Position    int
+	Word        string
+	Attribution float32
+}
+
+// AttributeWords pairs the per token attributions of TfkgModel.Saliency or TfkgModel.IntegratedGradients for one row
+// of tokens with their words, skipping padding
+func (t *Tokenizer) AttributeWords(tokens []int32, attributions []float32) []WordAttribution {
+	var wordAttributions []WordAttribution
+	for position, word := range t.Words(tokens) {
+		if tokens[position] == 0 || position >= len(attributions) {
+			continue
+		}
+		wordAttributions = append(wordAttributions, WordAttribution{
+			Position:    position,
+			Word:        word,
+			Attribution: attributions[position],
+		})
+	}
+
+	return wordAttributions
+}
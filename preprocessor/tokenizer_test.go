@@ -0,0 +1,169 @@
+package preprocessor
+
+import (
+	"encoding/json"
+	"github.com/codingbeard/cberrors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadTestTokenizer(t *testing.T, config tokenizerConfig) *Tokenizer {
+	t.Helper()
+	jsonBytes, e := json.Marshal(config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	configFile := filepath.Join(t.TempDir(), "tokenizer.json")
+	e = ioutil.WriteFile(configFile, jsonBytes, os.ModePerm)
+	if e != nil {
+		t.Fatal(e)
+	}
+	tokenizer := NewTokenizer(cberrors.NewErrorContainer(), 0, 0)
+	e = tokenizer.Load(configFile)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return tokenizer
+}
+
+func TestTokenizerWords(t *testing.T) {
+	wordIndex := map[string]int{"the": 1, "cat": 2, "sat": 3}
+
+	tests := []struct {
+		name             string
+		keepUnknownWords bool
+		sentence         string
+		tokens           []int32
+		words            []string
+	}{
+		{
+			name:     "known words and padding",
+			sentence: "The cat sat",
+			tokens:   []int32{1, 2, 3, 0, 0},
+			words:    []string{"the", "cat", "sat", "", ""},
+		},
+		{
+			name:     "unknown words are dropped",
+			sentence: "the dog sat",
+			tokens:   []int32{1, 3, 0, 0, 0},
+			words:    []string{"the", "sat", "", "", ""},
+		},
+		{
+			name:             "unknown words are kept",
+			keepUnknownWords: true,
+			sentence:         "the dog sat",
+			tokens:           []int32{1, 4, 3, 0, 0},
+			words:            []string{"the", "<unknown>", "sat", "", ""},
+		},
+		{
+			name:     "truncated to max len",
+			sentence: "the cat sat the cat sat",
+			tokens:   []int32{1, 2, 3, 1, 2},
+			words:    []string{"the", "cat", "sat", "the", "cat"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokenizer := loadTestTokenizer(t, tokenizerConfig{
+				MaxLen:           5,
+				WordIndex:        wordIndex,
+				Filter:           "!,.",
+				KeepUnknownWords: test.keepUnknownWords,
+			})
+
+			tokens := tokenizer.Tokenize(test.sentence)
+			if !reflect.DeepEqual(tokens, test.tokens) {
+				t.Errorf("expected the tokens %v, got %v", test.tokens, tokens)
+			}
+			words := tokenizer.Words(tokens)
+			if !reflect.DeepEqual(words, test.words) {
+				t.Errorf("expected the words %q, got %q", test.words, words)
+			}
+		})
+	}
+}
+
+func TestTokenizerWordsAfterFit(t *testing.T) {
+	tokenizer := NewTokenizer(cberrors.NewErrorContainer(), 4, 10)
+	tokenizer.Fit("The cat sat.")
+	tokenizer.FinishFit()
+
+	expected := []string{"the", "cat", "sat", ""}
+	words := tokenizer.Words(tokenizer.Tokenize("the cat, sat!"))
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("expected %q, got %q", expected, words)
+	}
+
+	// The cached words are rebuilt when the dictionary changes
+	tokenizer.Fit("dog dog dog dog")
+	tokenizer.FinishFit()
+	expected = []string{"dog", "the", ""}
+	words = tokenizer.Words([]int32{int32(tokenizer.dictionary["dog"]), int32(tokenizer.dictionary["the"]), 0})
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("expected %q after fitting again, got %q", expected, words)
+	}
+	if words := tokenizer.Words([]int32{99}); words[0] != "<unknown>" {
+		t.Errorf("expected a token outside the dictionary to be <unknown>, got %q", words[0])
+	}
+}
+
+func TestTokenizerAttributeWords(t *testing.T) {
+	tokenizer := loadTestTokenizer(t, tokenizerConfig{
+		MaxLen:    4,
+		WordIndex: map[string]int{"the": 1, "cat": 2, "sat": 3},
+	})
+
+	tests := []struct {
+		name         string
+		tokens       []int32
+		attributions []float32
+		expected     []WordAttribution
+	}{
+		{
+			name:         "padding is skipped",
+			tokens:       []int32{1, 2, 0, 0},
+			attributions: []float32{0.5, 0.25, 0.1, 0.1},
+			expected: []WordAttribution{
+				{Position: 0, Word: "the", Attribution: 0.5},
+				{Position: 1, Word: "cat", Attribution: 0.25},
+			},
+		},
+		{
+			name:         "unknown words are kept",
+			tokens:       []int32{3, 4, 1, 0},
+			attributions: []float32{0.1, 0.2, 0.3, 0.4},
+			expected: []WordAttribution{
+				{Position: 0, Word: "sat", Attribution: 0.1},
+				{Position: 1, Word: "<unknown>", Attribution: 0.2},
+				{Position: 2, Word: "the", Attribution: 0.3},
+			},
+		},
+		{
+			name:         "fewer attributions than tokens",
+			tokens:       []int32{1, 2, 3, 0},
+			attributions: []float32{0.1},
+			expected: []WordAttribution{
+				{Position: 0, Word: "the", Attribution: 0.1},
+			},
+		},
+		{
+			name:         "only padding",
+			tokens:       []int32{0, 0, 0, 0},
+			attributions: []float32{0.1, 0.2, 0.3, 0.4},
+			expected:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wordAttributions := tokenizer.AttributeWords(test.tokens, test.attributions)
+			if !reflect.DeepEqual(wordAttributions, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, wordAttributions)
+			}
+		})
+	}
+}
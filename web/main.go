@@ -7,7 +7,9 @@ import (
 	"github.com/codingbeard/cblog"
 	"github.com/codingbeard/cbweb"
 	"github.com/codingbeard/cbweb/module/cbwebcommon"
+	"github.com/codingbeard/tfkg/jobs"
 	"github.com/codingbeard/tfkg/web/module/home"
+	webjobs "github.com/codingbeard/tfkg/web/module/jobs"
 	"github.com/codingbeard/tfkg/web/module/metrics"
 	"github.com/valyala/fasthttp"
 	"html/template"
@@ -62,6 +64,16 @@ func main() {
 
 	cbWebCommonModule.SetDefaults()
 
+	// The job queue is shared with the `tfkg worker` processes
+	jobsDir := os.Getenv("JOBS_DIR")
+	if jobsDir == "" {
+		jobsDir = "/go/src/tfkg/job-queue"
+	}
+	jobQueue, e := jobs.NewQueue(jobsDir)
+	if e != nil {
+		panic(e)
+	}
+
 	navItems := func(ctx *fasthttp.RequestCtx) []cbweb.NavItem {
 		navItems := cbweb.NavItemCollection{
 			{Title: "Home", SubNavItems: []cbweb.NavItem{
@@ -97,6 +109,11 @@ func main() {
 			Logger:       logger,
 			NavItems:     navItems,
 		},
+		&webjobs.Module{
+			ErrorHandler: errorHandler,
+			Logger:       logger,
+			Queue:        jobQueue,
+		},
 	}
 
 	logger.InfoF("root", "Initialising http server")
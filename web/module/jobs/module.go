@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/codingbeard/tfkg/jobs"
+	"github.com/valyala/fasthttp"
+)
+
+// Module is the JSON api of the job queue, the model configs of the jobs are trained by `tfkg worker` processes sharing
+// the queue dir
+type Module struct {
+	ErrorHandler ErrorHandler
+	Logger       Logger
+	Queue        *jobs.Queue
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (m *Module) GetErrorHandler() ErrorHandler {
+	if m.ErrorHandler == nil {
+		m.ErrorHandler = &defaultErrorHandler{}
+	}
+
+	return m.ErrorHandler
+}
+
+func (m *Module) GetLogger() Logger {
+	if m.Logger == nil {
+		m.Logger = &defaultLogger{}
+	}
+
+	return m.Logger
+}
+
+func (m *Module) List(ctx *fasthttp.RequestCtx) {
+	queuedJobs, e := m.Queue.List()
+	if e != nil {
+		m.writeError(ctx, e)
+		return
+	}
+	if queuedJobs == nil {
+		queuedJobs = []jobs.Job{}
+	}
+
+	m.writeJson(ctx, fasthttp.StatusOK, queuedJobs)
+}
+
+func (m *Module) Submit(ctx *fasthttp.RequestCtx) {
+	var request jobs.SubmitRequest
+	decoder := json.NewDecoder(bytes.NewReader(ctx.PostBody()))
+	decoder.DisallowUnknownFields()
+	e := decoder.Decode(&request)
+	if e != nil {
+		m.writeJson(ctx, fasthttp.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid job: %s", e.Error())})
+		return
+	}
+
+	job, e := m.Queue.Submit(request)
+	if e != nil {
+		m.writeJson(ctx, fasthttp.StatusBadRequest, errorResponse{Error: e.Error()})
+		return
+	}
+	m.GetLogger().InfoF("jobs", "Queued job %s (%s)", job.Id, job.Name)
+
+	m.writeJson(ctx, fasthttp.StatusCreated, job)
+}
+
+func (m *Module) Get(ctx *fasthttp.RequestCtx) {
+	job, e := m.Queue.Get(fmt.Sprint(ctx.UserValue("id")))
+	if e != nil {
+		m.writeError(ctx, e)
+		return
+	}
+
+	m.writeJson(ctx, fasthttp.StatusOK, job)
+}
+
+func (m *Module) Cancel(ctx *fasthttp.RequestCtx) {
+	job, e := m.Queue.Cancel(fmt.Sprint(ctx.UserValue("id")))
+	if e != nil {
+		m.writeError(ctx, e)
+		return
+	}
+	m.GetLogger().InfoF("jobs", "Cancelling job %s (%s)", job.Id, job.Name)
+
+	m.writeJson(ctx, fasthttp.StatusOK, job)
+}
+
+func (m *Module) writeError(ctx *fasthttp.RequestCtx, e error) {
+	if errors.Is(e, jobs.ErrJobNotFound) {
+		m.writeJson(ctx, fasthttp.StatusNotFound, errorResponse{Error: e.Error()})
+		return
+	}
+	if errors.Is(e, jobs.ErrJobDone) {
+		m.writeJson(ctx, fasthttp.StatusConflict, errorResponse{Error: e.Error()})
+		return
+	}
+	m.GetErrorHandler().Error(e)
+	m.writeJson(ctx, fasthttp.StatusInternalServerError, errorResponse{Error: e.Error()})
+}
+
+func (m *Module) writeJson(ctx *fasthttp.RequestCtx, statusCode int, response interface{}) {
+	jsonBytes, e := json.Marshal(response)
+	if e != nil {
+		m.GetErrorHandler().Error(e)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(statusCode)
+	ctx.SetBody(jsonBytes)
+}
+
+func (m *Module) GetGlobalTemplates() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (m *Module) SetGlobalTemplates(templates map[string][]byte) {
+
+}
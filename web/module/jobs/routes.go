@@ -0,0 +1,16 @@
+package jobs
+
+import (
+	"github.com/fasthttp/router"
+)
+
+var JobsRoute = "/api/jobs"
+var JobRoute = "/api/jobs/{id}"
+var CancelJobRoute = "/api/jobs/{id}/cancel"
+
+func (m *Module) SetRoutes(router *router.Router) {
+	router.GET(JobsRoute, m.List)
+	router.POST(JobsRoute, m.Submit)
+	router.GET(JobRoute, m.Get)
+	router.POST(CancelJobRoute, m.Cancel)
+}